@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+
+	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/internal/backup"
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/bookimport"
+	"um6p.ma/final_project/internal/config"
+	"um6p.ma/final_project/internal/customer"
+	"um6p.ma/final_project/internal/genre"
+	"um6p.ma/final_project/internal/inventory"
+	"um6p.ma/final_project/internal/lifecycle"
+	"um6p.ma/final_project/internal/middleware"
+	"um6p.ma/final_project/internal/migrate"
+	"um6p.ma/final_project/internal/order"
+	"um6p.ma/final_project/internal/publisher"
+	"um6p.ma/final_project/internal/review"
+	"um6p.ma/final_project/internal/router"
+	"um6p.ma/final_project/internal/sales"
+	"um6p.ma/final_project/internal/series"
+	"um6p.ma/final_project/pkg/blob"
+	"um6p.ma/final_project/pkg/journal"
+)
+
+type stores struct {
+	authors    author.AuthorStore
+	genres     genre.GenreStore
+	books      book.BookStore
+	series     series.SeriesStore
+	publishers publisher.PublisherStore
+	customers  customer.CustomerStore
+	orders     order.OrderStore
+	reviews    review.ReviewStore
+	sales      sales.SalesStore
+	movements  inventory.MovementStore
+	blobs      blob.Store
+
+	db *sql.DB
+}
+
+func (s stores) close() error {
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// shutdown flushes and closes every store; the server leaves this to the
+// lifecycle manager, offline commands call it directly.
+func (s stores) shutdown() error {
+	var errs []error
+	for _, ns := range s.named() {
+		if f, ok := ns.store.(lifecycle.Flusher); ok {
+			errs = append(errs, f.Flush())
+		}
+		if c, ok := ns.store.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	errs = append(errs, s.close())
+	return errors.Join(errs...)
+}
+
+func newInMemoryStores() stores {
+	return stores{
+		authors:    author.NewStore(),
+		genres:     genre.NewStore(),
+		books:      book.NewStore(),
+		series:     series.NewStore(),
+		publishers: publisher.NewStore(),
+		customers:  customer.NewCustomerStore(),
+		orders:     order.NewOrderStore(),
+		reviews:    review.NewStore(),
+		sales:      sales.NewSalesStore(),
+		movements:  inventory.NewStore(),
+	}
+}
+
+func (s stores) named() []namedStore {
+	return []namedStore{
+		{"authors", s.authors},
+		{"genres", s.genres},
+		{"books", s.books},
+		{"series", s.series},
+		{"publishers", s.publishers},
+		{"customers", s.customers},
+		{"orders", s.orders},
+		{"reviews", s.reviews},
+		{"sales", s.sales},
+		{"movements", s.movements},
+	}
+}
+
+type namedStore struct {
+	name  string
+	store any
+}
+
+// openStores opens the configured backend, converts any books written
+// before the genre tree or editions existed and records opening balances for
+// stock the ledger does not explain.
+func openStores(cfg config.Config) (stores, error) {
+	s, err := openBackend(cfg)
+	if err != nil {
+		return stores{}, err
+	}
+	s.blobs = openBlobs(cfg)
+	n, err := book.MigrateLegacyBooks(context.Background(), s.books, s.genres, s.publishers)
+	if err != nil {
+		return stores{}, errors.Join(err, s.shutdown())
+	}
+	if n > 0 {
+		slog.Info("migrated legacy books", "books", n)
+	}
+	n, err = inventory.Reconcile(context.Background(), s.books, s.movements)
+	if err != nil {
+		return stores{}, errors.Join(err, s.shutdown())
+	}
+	if n > 0 {
+		slog.Info("reconciled stock ledger", "movements", n)
+	}
+	return s, nil
+}
+
+// openBlobs returns the store for uploaded files: blob_dir if set, memory
+// for the memory backend and otherwise a blobs directory in data_dir.
+func openBlobs(cfg config.Config) blob.Store {
+	switch {
+	case cfg.BlobDir != "":
+		return blob.NewFileStore(cfg.BlobDir)
+	case cfg.Store == "memory":
+		return blob.NewMemoryStore()
+	default:
+		return blob.NewFileStore(filepath.Join(cfg.DataDir, "blobs"))
+	}
+}
+
+func openBackend(cfg config.Config) (stores, error) {
+	switch cfg.Store {
+	case "memory":
+		return newInMemoryStores(), nil
+	case "file":
+		return openFileStores(cfg.DataDir)
+	case "journal":
+		return openJournaledStores(cfg.DataDir, cfg.JournalOptions())
+	case "sql":
+		return openSQLStores(cfg)
+	default:
+		return stores{}, fmt.Errorf("unknown store backend %q", cfg.Store)
+	}
+}
+
+func openFileStores(dir string) (stores, error) {
+	authors, err := author.NewFileStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	genres, err := genre.NewFileStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	books, err := book.NewFileStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	seriesStore, err := series.NewFileStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	publishers, err := publisher.NewFileStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	customers, err := customer.NewFileCustomerStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	orders, err := order.NewFileOrderStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	reviews, err := review.NewFileStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	movements, err := inventory.NewFileStore(dir)
+	if err != nil {
+		return stores{}, err
+	}
+	return stores{
+		authors:    authors,
+		genres:     genres,
+		books:      books,
+		series:     seriesStore,
+		publishers: publishers,
+		customers:  customers,
+		orders:     orders,
+		reviews:    reviews,
+		sales:      sales.NewSalesStore(),
+		movements:  movements,
+	}, nil
+}
+
+func openJournaledStores(dir string, opts journal.Options) (stores, error) {
+	authors, err := author.NewJournaledStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	genres, err := genre.NewJournaledStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	books, err := book.NewJournaledStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	seriesStore, err := series.NewJournaledStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	publishers, err := publisher.NewJournaledStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	customers, err := customer.NewJournaledCustomerStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	orders, err := order.NewJournaledOrderStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	reviews, err := review.NewJournaledStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	movements, err := inventory.NewJournaledStore(dir, opts)
+	if err != nil {
+		return stores{}, err
+	}
+	return stores{
+		authors:    authors,
+		genres:     genres,
+		books:      books,
+		series:     seriesStore,
+		publishers: publishers,
+		customers:  customers,
+		orders:     orders,
+		reviews:    reviews,
+		sales:      sales.NewSalesStore(),
+		movements:  movements,
+	}, nil
+}
+
+func openSQLStores(cfg config.Config) (stores, error) {
+	db, err := openDB(cfg)
+	if err != nil {
+		return stores{}, err
+	}
+	pending, err := migrate.Pending(context.Background(), db)
+	if err != nil {
+		db.Close()
+		return stores{}, err
+	}
+	if len(pending) > 0 {
+		db.Close()
+		return stores{}, fmt.Errorf("database schema is %d migration(s) behind; run \"bookstore migrate up\"", len(pending))
+	}
+	return stores{
+		authors:    author.NewSQLStore(db),
+		genres:     genre.NewSQLStore(db),
+		books:      book.NewSQLStore(db),
+		series:     series.NewSQLStore(db),
+		publishers: publisher.NewSQLStore(db),
+		customers:  customer.NewSQLCustomerStore(db),
+		orders:     order.NewSQLOrderStore(db),
+		reviews:    review.NewSQLStore(db),
+		sales:      sales.NewSQLSalesStore(db),
+		movements:  inventory.NewSQLStore(db),
+		db:         db,
+	}, nil
+}
+
+type app struct {
+	cfg    config.Config
+	stores stores
+
+	authorService    author.Service
+	bookService      book.Service
+	genreService     genre.Service
+	seriesService    series.Service
+	publisherService publisher.Service
+	reviewService    review.Service
+	inventoryService inventory.Service
+	orderService     order.Service
+	salesService     sales.Service
+	backupService    *backup.Service
+	importService    *bookimport.Service
+
+	authorHandler    *author.Handler
+	bookHandler      *book.Handler
+	genreHandler     *genre.Handler
+	seriesHandler    *series.Handler
+	publisherHandler *publisher.Handler
+	reviewHandler    *review.Handler
+	inventoryHandler *inventory.Handler
+	customerHandler  *customer.Handler
+	orderHandler     *order.Handler
+	salesHandler     *sales.Handler
+	backupHandler    *backup.Handler
+	importHandler    *bookimport.Handler
+}
+
+func newApp(ctx context.Context, cfg config.Config, s stores) (*app, error) {
+	index := book.NewSearchIndex()
+	if err := index.Rebuild(ctx, s.authors, s.genres, s.books); err != nil {
+		return nil, fmt.Errorf("failed to build search index: %w", err)
+	}
+	s.authors = book.NewIndexedAuthorStore(s.authors, index)
+	s.genres = book.NewIndexedGenreStore(s.genres, index)
+	s.books = book.NewIndexedStore(book.NewAuthorLinkedStore(s.books, s.authors), index)
+
+	a := &app{cfg: cfg, stores: s}
+
+	tracked := inventory.NewTrackedBookStore(s.books, s.movements)
+
+	a.reviewService = review.NewService(s.reviews, s.books, s.customers, s.orders)
+	a.bookService = book.NewService(tracked, s.genres, s.series, s.publishers, a.reviewService, index, s.blobs)
+	a.seriesService = series.NewService(s.series, s.authors, a.bookService)
+	a.authorService = author.NewService(s.authors, a.bookService, a.seriesService)
+	a.genreService = genre.NewService(s.genres, a.bookService)
+	a.publisherService = publisher.NewService(s.publishers, a.bookService)
+	a.inventoryService = inventory.NewService(s.books, s.movements, tracked, inventory.LogNotifier{}, cfg.AlertInterval)
+	a.orderService = order.NewService(s.orders, s.customers, s.books, tracked)
+	a.salesService = sales.NewService(s.orders, s.sales, s.books, s.publishers, cfg.ReportInterval)
+	a.backupService = backup.NewService(s.authors, s.genres, s.series, s.publishers, s.books, s.customers, s.orders, s.reviews, s.movements)
+	a.importService = bookimport.NewService(s.authors, s.publishers, a.bookService)
+
+	a.authorHandler = author.NewHandler(a.authorService)
+	a.bookHandler = book.NewHandler(a.bookService, book.ExportOptions{Currency: cfg.Currency, Sender: "bookstore"})
+	a.genreHandler = genre.NewHandler(a.genreService)
+	a.seriesHandler = series.NewHandler(a.seriesService)
+	a.publisherHandler = publisher.NewHandler(a.publisherService)
+	a.reviewHandler = review.NewHandler(a.reviewService)
+	a.inventoryHandler = inventory.NewHandler(a.inventoryService)
+	a.customerHandler = customer.NewHandler(s.customers)
+	a.orderHandler = order.NewHandler(a.orderService, cfg.OrderTimeout)
+	a.salesHandler = sales.NewHandler(a.salesService)
+	a.backupHandler = backup.NewHandler(a.backupService)
+	a.importHandler = bookimport.NewHandler(a.importService)
+
+	return a, nil
+}
+
+func (a *app) routes() http.Handler {
+	r := router.New("/api/v1", true)
+
+	a.authorHandler.RegisterRoutes(r)
+	a.bookHandler.RegisterRoutes(r)
+	a.genreHandler.RegisterRoutes(r)
+	a.seriesHandler.RegisterRoutes(r)
+	a.publisherHandler.RegisterRoutes(r)
+	a.reviewHandler.RegisterRoutes(r)
+	a.inventoryHandler.RegisterRoutes(r)
+	a.customerHandler.RegisterRoutes(r)
+	a.orderHandler.RegisterRoutes(r)
+	a.salesHandler.RegisterRoutes(r)
+	a.backupHandler.RegisterRoutes(r)
+	a.importHandler.RegisterRoutes(r)
+
+	return middleware.Chain(r,
+		middleware.RequestID,
+		middleware.AccessLog,
+		middleware.Recover,
+	)
+}
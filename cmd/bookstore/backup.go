@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"um6p.ma/final_project/internal/backup"
+	"um6p.ma/final_project/internal/config"
+)
+
+// openPersistentStores opens the configured stores for an offline command.
+// The in-memory backend is rejected since its data would vanish on exit.
+func openPersistentStores(cfg config.Config) (stores, error) {
+	if cfg.Store == "memory" {
+		return stores{}, fmt.Errorf("the %q store keeps no data between runs; pick file, journal or sql", cfg.Store)
+	}
+	return openStores(cfg)
+}
+
+// runBackup implements "bookstore backup [-o file] [flags]".
+func runBackup(args []string) error {
+	var output string
+	cfg, _, err := config.LoadWithFlags("bookstore backup", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&output, "o", "", "write the archive to this file instead of stdout")
+	})
+	if err != nil {
+		return err
+	}
+	s, err := openPersistentStores(cfg)
+	if err != nil {
+		return err
+	}
+	defer s.shutdown()
+
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	svc := backup.NewService(s.authors, s.genres, s.series, s.publishers, s.books, s.customers, s.orders, s.reviews, s.movements)
+	return svc.Export(context.Background(), w)
+}
+
+// runRestore implements "bookstore restore [-dry-run] [-conflict policy] file [flags]".
+func runRestore(args []string) error {
+	var dryRun bool
+	var conflict string
+	cfg, rest, err := config.LoadWithFlags("bookstore restore", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&dryRun, "dry-run", false, "validate the archive without writing anything")
+		fs.StringVar(&conflict, "conflict", string(backup.ConflictFail), "how to handle existing IDs (skip, overwrite, fail)")
+	})
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: bookstore restore [-dry-run] [-conflict policy] [flags] archive.tar.gz")
+	}
+	policy, err := backup.ParseConflictPolicy(conflict)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(rest[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s, err := openPersistentStores(cfg)
+	if err != nil {
+		return err
+	}
+	svc := backup.NewService(s.authors, s.genres, s.series, s.publishers, s.books, s.customers, s.orders, s.reviews, s.movements)
+	report, importErr := svc.Import(context.Background(), f, backup.ImportOptions{DryRun: dryRun, Conflict: policy})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+
+	return errors.Join(importErr, s.shutdown())
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"um6p.ma/final_project/internal/bookimport"
+	"um6p.ma/final_project/internal/config"
+)
+
+// runImportBooks implements "bookstore import-books [-dry-run] [-mode mode] [-map Header=field,...] file.csv [flags]".
+func runImportBooks(args []string) error {
+	var dryRun bool
+	var mode, columnMap string
+	cfg, rest, err := config.LoadWithFlags("bookstore import-books", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&dryRun, "dry-run", false, "validate the file without writing anything")
+		fs.StringVar(&mode, "mode", string(bookimport.ModeAllOrNothing), "what to do with valid rows when some are invalid (all-or-nothing, best-effort)")
+		fs.StringVar(&columnMap, "map", "", "comma-separated Header=field mappings for columns that do not name a book field")
+	})
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: bookstore import-books [-dry-run] [-mode mode] [-map Header=field,...] [flags] file.csv")
+	}
+	m, err := bookimport.ParseMode(mode)
+	if err != nil {
+		return err
+	}
+	columns, err := bookimport.ParseColumnMap(columnMap)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(rest[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s, err := openPersistentStores(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	a, err := newApp(ctx, cfg, s)
+	if err != nil {
+		return errors.Join(err, s.shutdown())
+	}
+	report, importErr := a.importService.Import(ctx, f, bookimport.Options{DryRun: dryRun, Mode: m, Columns: columns})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+
+	return errors.Join(importErr, s.shutdown())
+}
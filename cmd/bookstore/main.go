@@ -1,31 +1,100 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"um6p.ma/final_project/internal/author"
-	"um6p.ma/final_project/internal/book"
-	"um6p.ma/final_project/internal/customer"
-	"um6p.ma/final_project/internal/order"
-	"um6p.ma/final_project/internal/sales"
+	"um6p.ma/final_project/internal/config"
+	"um6p.ma/final_project/internal/lifecycle"
+	"um6p.ma/final_project/pkg/logging"
 )
 
+var commands = map[string]func(args []string) error{
+	"migrate":      runMigrate,
+	"backup":       runBackup,
+	"restore":      runRestore,
+	"import-books": runImportBooks,
+}
+
 func main() {
-	http.HandleFunc("/authors", author.AuthorsHandler)
-	http.HandleFunc("/authors/", author.AuthorHandler)
-	http.HandleFunc("/books", book.BooksHandler)
-	http.HandleFunc("/books/", book.BookHandler)
-	http.HandleFunc("/customers", customer.CustomersHandler)
-	http.HandleFunc("/customers/", customer.CustomerHandler)
+	if err := run(os.Args); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	http.HandleFunc("/orders", order.OrdersHandler)
-	http.HandleFunc("/orders/", order.OrderHandler)
+// run starts the command or server named by args and returns once it is
+// done, so that main's single exit comes after every deferred cleanup.
+func run(args []string) error {
+	if len(args) > 1 {
+		if cmd, ok := commands[args[1]]; ok {
+			if err := cmd(args[2:]); err != nil {
+				return fmt.Errorf("%s: %w", args[1], err)
+			}
+			return nil
+		}
+	}
+
+	cfg, err := config.Load(args[0], args[1:])
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.PrintConfig {
+		fmt.Print(cfg.String())
+		return nil
+	}
 
-	http.HandleFunc("/sales/report", sales.SalesReportHandler)
+	level, _ := cfg.SlogLevel()
+	slog.SetDefault(slog.New(logging.NewHandler(
+		slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}),
+	)))
 
-	if err := http.ListenAndServe(":8085", nil); err != nil {
-		log.Fatalf("server failed to start: %v", err)
+	s, err := openStores(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open stores: %w", err)
+	}
+	a, err := newApp(context.Background(), cfg, s)
+	if err != nil {
+		return errors.Join(err, s.shutdown())
+	}
+
+	srv := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      a.routes(),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
 	}
 
+	m := lifecycle.NewManager(srv, cfg.ShutdownTimeout)
+	m.AddWorker(lifecycle.Worker{
+		Name:  "sales-report",
+		Start: a.salesService.StartPeriodicReportGeneration,
+		Stop:  a.salesService.Stop,
+	})
+	m.AddWorker(lifecycle.Worker{
+		Name:  "stock-alerts",
+		Start: a.inventoryService.StartPeriodicAlertChecks,
+		Stop:  a.inventoryService.Stop,
+	})
+	for _, ns := range s.named() {
+		if f, ok := ns.store.(lifecycle.Flusher); ok {
+			m.AddFlusher(ns.name, f)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The manager flushes and closes the stores; the database is left.
+	if err := errors.Join(m.Run(ctx), s.close()); err != nil {
+		return fmt.Errorf("server stopped with error: %w", err)
+	}
+	log.Println("server stopped")
+	return nil
 }
@@ -0,0 +1,84 @@
+package author
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID  int      `json:"next_id"`
+	Authors []Author `json:"authors"`
+}
+
+func (store *InMemoryAuthorStore) snapshot() storeSnapshot {
+	store.Lock()
+	defer store.Unlock()
+
+	snap := storeSnapshot{NextID: store.nextID, Authors: make([]Author, 0, len(store.authors))}
+	for _, a := range store.authors {
+		snap.Authors = append(snap.Authors, a)
+	}
+	return snap
+}
+
+func (store *InMemoryAuthorStore) restore(snap storeSnapshot) {
+	store.Lock()
+	defer store.Unlock()
+
+	store.authors = make(map[int]Author, len(snap.Authors))
+	store.nextID = max(snap.NextID, 1)
+	for _, a := range snap.Authors {
+		store.authors[a.ID] = a
+		store.nextID = max(store.nextID, a.ID+1)
+	}
+}
+
+type FileAuthorStore struct {
+	*InMemoryAuthorStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileStore(dir string) (*FileAuthorStore, error) {
+	mem := NewStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "authors.json"), "authors", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuthorStore{InMemoryAuthorStore: mem, file: file}, nil
+}
+
+func (store *FileAuthorStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileAuthorStore) CreateAuthor(ctx context.Context, author Author) (int, error) {
+	id, err := store.InMemoryAuthorStore.CreateAuthor(ctx, author)
+	if err != nil {
+		return 0, err
+	}
+	return id, store.file.Save()
+}
+
+func (store *FileAuthorStore) UpdateAuthor(ctx context.Context, id int, author Author) (Author, error) {
+	updated, err := store.InMemoryAuthorStore.UpdateAuthor(ctx, id, author)
+	if err != nil {
+		return Author{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FileAuthorStore) DeleteAuthor(ctx context.Context, id int) error {
+	if err := store.InMemoryAuthorStore.DeleteAuthor(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileAuthorStore) RestoreAuthor(ctx context.Context, author Author) error {
+	if err := store.InMemoryAuthorStore.RestoreAuthor(ctx, author); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
@@ -2,10 +2,12 @@ package author
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
-	"um6p.ma/final_project/pkg/error"
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
 )
 
 func NewStore() *InMemoryAuthorStore {
@@ -15,88 +17,129 @@ func NewStore() *InMemoryAuthorStore {
 	}
 }
 
-var store = NewStore()
-
-func AuthorsHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+type Handler struct {
+	svc Service
+}
 
-	switch r.Method {
-	case http.MethodPost:
-		var author Author
-		if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
-			error.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
 
-		createdAuthor, err := store.CreateAuthor(ctx, author)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/authors", h.ListAuthors)
+	r.HandleFunc(http.MethodPost, "/authors", h.CreateAuthor)
+	r.HandleFunc(http.MethodGet, "/authors/{id}", h.GetAuthor)
+	r.HandleFunc(http.MethodPut, "/authors/{id}", h.UpdateAuthor)
+	r.HandleFunc(http.MethodDelete, "/authors/{id}", h.DeleteAuthor)
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(createdAuthor)
+func (h *Handler) CreateAuthor(w http.ResponseWriter, r *http.Request) {
+	var author Author
+	if err := json.NewDecoder(r.Body).Decode(&author); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
 
-	case http.MethodGet:
-		authors, err := store.ListAuthors(ctx)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
+	createdAuthor, err := h.svc.CreateAuthor(r.Context(), author)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(authors)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdAuthor)
+}
 
-	default:
-		error.WriteJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+func (h *Handler) ListAuthors(w http.ResponseWriter, r *http.Request) {
+	authors, err := h.svc.ListAuthors(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(authors)
 }
 
-func AuthorHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+func (h *Handler) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid author ID", http.StatusBadRequest)
+		return
+	}
 
-	id, err := strconv.Atoi(r.URL.Path[len("/authors/"):])
+	author, err := h.svc.GetAuthor(r.Context(), id)
 	if err != nil {
-		error.WriteJSONError(w, "invalid author ID", http.StatusBadRequest)
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		author, err := store.GetAuthorByID(ctx, id)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(author)
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(author)
+func (h *Handler) UpdateAuthor(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid author ID", http.StatusBadRequest)
+		return
+	}
 
-	case http.MethodPut:
-		var updatedData Author
-		if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
-			error.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
+	var updatedData Author
+	if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
 
-		updatedAuthor := store.UpdateAuthor(ctx, id, updatedData)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
+	updatedAuthor, err := h.svc.UpdateAuthor(r.Context(), id, updatedData)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(updatedAuthor)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedAuthor)
+}
 
-	case http.MethodDelete:
-		if err := store.DeleteAuthor(ctx, id); err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
+func (h *Handler) DeleteAuthor(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid author ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	cascade, err := ParseCascade(q.Get("cascade"))
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := DeleteOptions{Cascade: cascade}
+	if cascade == CascadeReassign {
+		if opts.ReassignTo, err = strconv.Atoi(q.Get("to")); err != nil {
+			pkgError.WriteJSONError(w, "cascade=reassign requires 'to' to be the ID of the new author", http.StatusBadRequest)
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
+	}
 
-	default:
-		error.WriteJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+	err = h.svc.DeleteAuthor(r.Context(), id, opts)
+	var inUse *InUseError
+	if errors.As(err, &inUse) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(inUseResponse{Error: inUse.Error(), Books: inUse.Books, Series: inUse.Series})
+		return
+	}
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type inUseResponse struct {
+	Error  string      `json:"error"`
+	Books  []BookRef   `json:"books"`
+	Series []SeriesRef `json:"series,omitempty"`
 }
@@ -0,0 +1,94 @@
+package author
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryAuthorStore) apply(rec journal.Record) error {
+	store.Lock()
+	defer store.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var a Author
+		if err := json.Unmarshal(rec.Data, &a); err != nil {
+			return fmt.Errorf("invalid author record: %w", err)
+		}
+		store.authors[a.ID] = a
+		store.nextID = max(store.nextID, a.ID+1)
+	case journal.OpDelete:
+		delete(store.authors, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledAuthorStore struct {
+	*InMemoryAuthorStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledStore(dir string, opts journal.Options) (*JournaledAuthorStore, error) {
+	mem := NewStore()
+	j, err := journal.OpenStore(dir, "authors", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledAuthorStore{InMemoryAuthorStore: mem, journal: j}, nil
+}
+
+func (store *JournaledAuthorStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledAuthorStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledAuthorStore) CreateAuthor(ctx context.Context, author Author) (int, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	id, err := store.InMemoryAuthorStore.CreateAuthor(ctx, author)
+	if err != nil {
+		return 0, err
+	}
+	author.ID = id
+	return id, store.journal.Record(journal.OpCreate, id, author)
+}
+
+func (store *JournaledAuthorStore) UpdateAuthor(ctx context.Context, id int, author Author) (Author, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemoryAuthorStore.UpdateAuthor(ctx, id, author)
+	if err != nil {
+		return Author{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledAuthorStore) DeleteAuthor(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryAuthorStore.DeleteAuthor(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledAuthorStore) RestoreAuthor(ctx context.Context, author Author) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryAuthorStore.RestoreAuthor(ctx, author); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, author.ID, author)
+}
@@ -1,6 +1,14 @@
 package author
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrInUse is wrapped by the error returned when deleting an author who
+// still has books.
+var ErrInUse = errors.New("author has books")
 
 type Author struct {
 	ID        int    `json:"id"`
@@ -12,7 +20,80 @@ type Author struct {
 type AuthorStore interface {
 	CreateAuthor(ctx context.Context, author Author) (int, error)
 	GetAuthorByID(ctx context.Context, id int) (Author, error)
-	UpdateAuthor(ctx context.Context, author Author) (Author, error)
+	UpdateAuthor(ctx context.Context, id int, author Author) (Author, error)
 	DeleteAuthor(ctx context.Context, id int) error
 	ListAuthors(ctx context.Context) ([]Author, error)
+	RestoreAuthor(ctx context.Context, author Author) error
+}
+
+// BookRef identifies one of an author's books.
+type BookRef struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+// BookManager gives author deletion access to the author's books without
+// this package depending on package book; the book service implements it.
+type BookManager interface {
+	BookRefsByAuthor(ctx context.Context, authorID int) ([]BookRef, error)
+	ReassignBooks(ctx context.Context, fromAuthorID, toAuthorID int) error
+	DeleteBooksByAuthor(ctx context.Context, authorID int) error
+}
+
+// SeriesRef identifies a series credited to an author.
+type SeriesRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// SeriesManager gives author deletion access to the series credited to the
+// author; the series service implements it.
+type SeriesManager interface {
+	SeriesRefsByAuthor(ctx context.Context, authorID int) ([]SeriesRef, error)
+	// ReassignSeries credits the series of fromAuthorID to toAuthorID, or to
+	// no one if toAuthorID is 0.
+	ReassignSeries(ctx context.Context, fromAuthorID, toAuthorID int) error
+}
+
+// InUseError lists the books and series that keep an author from being
+// deleted.
+type InUseError struct {
+	AuthorID int
+	Books    []BookRef
+	Series   []SeriesRef
+}
+
+func (e *InUseError) Error() string {
+	return fmt.Sprintf("author %d has %d book(s) and %d series; delete with cascade=reassign or cascade=delete",
+		e.AuthorID, len(e.Books), len(e.Series))
+}
+
+func (e *InUseError) Unwrap() error {
+	return ErrInUse
+}
+
+// Cascade says what happens to an author's books when the author is
+// deleted. Series credited to the author are reassigned with the books, and
+// left without an author when the books are deleted.
+type Cascade string
+
+const (
+	CascadeNone     Cascade = ""
+	CascadeReassign Cascade = "reassign"
+	CascadeDelete   Cascade = "delete"
+)
+
+func ParseCascade(s string) (Cascade, error) {
+	switch c := Cascade(s); c {
+	case CascadeNone, CascadeReassign, CascadeDelete:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid cascade %q: must be reassign or delete", s)
+}
+
+// DeleteOptions control DeleteAuthor. ReassignTo is the author who takes
+// over the books with CascadeReassign.
+type DeleteOptions struct {
+	Cascade    Cascade
+	ReassignTo int
 }
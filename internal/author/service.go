@@ -3,8 +3,9 @@ package author
 import (
 	"context"
 	"fmt"
-	"log"
 	"sync"
+
+	"um6p.ma/final_project/pkg/logging"
 )
 
 type InMemoryAuthorStore struct {
@@ -23,7 +24,7 @@ func (store *InMemoryAuthorStore) CreateAuthor(ctx context.Context, author Autho
 	default:
 		for _, existingAuthor := range store.authors {
 			if existingAuthor.FirstName == author.FirstName && existingAuthor.LastName == author.LastName {
-				log.Printf("author with name %s already exists", author.FirstName)
+				logging.Printf(ctx, "author with name %s already exists", author.FirstName)
 				return 0, fmt.Errorf("author with name %s already exists", author.FirstName)
 			}
 		}
@@ -45,7 +46,7 @@ func (store *InMemoryAuthorStore) GetAuthorByID(ctx context.Context, id int) (Au
 	default:
 		author, found := store.authors[id]
 		if !found {
-			log.Printf("author with ID %d not found", id)
+			logging.Printf(ctx, "author with ID %d not found", id)
 			return Author{}, fmt.Errorf("author with ID %d not found", id)
 		}
 		return author, nil
@@ -80,25 +81,123 @@ func (store *InMemoryAuthorStore) DeleteAuthor(ctx context.Context, id int) erro
 			delete(store.authors, id)
 			return nil
 		}
-		log.Printf("author with ID %d not found", id)
+		logging.Printf(ctx, "author with ID %d not found", id)
 		return fmt.Errorf("author with ID %d not found", id)
 	}
 }
 
-func (store *InMemoryAuthorStore) UpdateAuthor(ctx context.Context, id int, author Author) error {
+// RestoreAuthor stores author under its own ID, as needed when importing a
+// backup, and moves nextID past it.
+func (store *InMemoryAuthorStore) RestoreAuthor(ctx context.Context, author Author) error {
+	store.Lock()
+	defer store.Unlock()
+
+	if author.ID <= 0 {
+		return fmt.Errorf("invalid author ID %d", author.ID)
+	}
+	for id, existingAuthor := range store.authors {
+		if id != author.ID && existingAuthor.FirstName == author.FirstName && existingAuthor.LastName == author.LastName {
+			return fmt.Errorf("author with name %s already exists", author.FirstName)
+		}
+	}
+	store.authors[author.ID] = author
+	store.nextID = max(store.nextID, author.ID+1)
+	return nil
+}
+
+func (store *InMemoryAuthorStore) UpdateAuthor(ctx context.Context, id int, author Author) (Author, error) {
 	store.Lock()
 	defer store.Unlock()
 
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return Author{}, ctx.Err()
 	default:
 		if _, found := store.authors[id]; found {
 			author.ID = id
 			store.authors[id] = author
-			return nil
+			return author, nil
+		}
+		logging.Printf(ctx, "author with ID %d not found", id)
+		return Author{}, fmt.Errorf("author with ID %d not found", id)
+	}
+}
+
+type Service interface {
+	CreateAuthor(ctx context.Context, a Author) (int, error)
+	GetAuthor(ctx context.Context, id int) (Author, error)
+	UpdateAuthor(ctx context.Context, id int, a Author) (Author, error)
+	DeleteAuthor(ctx context.Context, id int, opts DeleteOptions) error
+	ListAuthors(ctx context.Context) ([]Author, error)
+}
+
+type service struct {
+	store  AuthorStore
+	books  BookManager
+	series SeriesManager
+}
+
+func NewService(store AuthorStore, books BookManager, series SeriesManager) Service {
+	return &service{store: store, books: books, series: series}
+}
+
+func (s *service) CreateAuthor(ctx context.Context, a Author) (int, error) {
+	return s.store.CreateAuthor(ctx, a)
+}
+
+func (s *service) GetAuthor(ctx context.Context, id int) (Author, error) {
+	return s.store.GetAuthorByID(ctx, id)
+}
+
+func (s *service) UpdateAuthor(ctx context.Context, id int, a Author) (Author, error) {
+	return s.store.UpdateAuthor(ctx, id, a)
+}
+
+func (s *service) ListAuthors(ctx context.Context) ([]Author, error) {
+	return s.store.ListAuthors(ctx)
+}
+
+// DeleteAuthor refuses to delete an author who still has books or series,
+// unless opts says to move them to another author or delete the books too.
+func (s *service) DeleteAuthor(ctx context.Context, id int, opts DeleteOptions) error {
+	if _, err := s.store.GetAuthorByID(ctx, id); err != nil {
+		return err
+	}
+	if opts.Cascade == CascadeReassign {
+		if opts.ReassignTo == 0 || opts.ReassignTo == id {
+			return fmt.Errorf("cascade=reassign needs another author to reassign the books to")
+		}
+		if _, err := s.store.GetAuthorByID(ctx, opts.ReassignTo); err != nil {
+			return err
+		}
+	}
+
+	books, err := s.books.BookRefsByAuthor(ctx, id)
+	if err != nil {
+		return err
+	}
+	series, err := s.series.SeriesRefsByAuthor(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(books) > 0 || len(series) > 0 {
+		switch opts.Cascade {
+		case CascadeReassign:
+			err = s.books.ReassignBooks(ctx, id, opts.ReassignTo)
+			if err == nil {
+				err = s.series.ReassignSeries(ctx, id, opts.ReassignTo)
+			}
+		case CascadeDelete:
+			err = s.books.DeleteBooksByAuthor(ctx, id)
+			if err == nil {
+				err = s.series.ReassignSeries(ctx, id, 0)
+			}
+		default:
+			return &InUseError{AuthorID: id, Books: books, Series: series}
+		}
+		if err != nil {
+			return err
 		}
-		log.Printf("author with ID %d not found", id)
-		return fmt.Errorf("author with ID %d not found", id)
 	}
+	return s.store.DeleteAuthor(ctx, id)
 }
@@ -0,0 +1,105 @@
+package author
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+type SQLAuthorStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLAuthorStore {
+	return &SQLAuthorStore{db: db}
+}
+
+func (store *SQLAuthorStore) CreateAuthor(ctx context.Context, author Author) (int, error) {
+	var id int
+	err := store.db.QueryRowContext(ctx,
+		`INSERT INTO authors (first_name, last_name, bio) VALUES ($1, $2, $3)
+		 ON CONFLICT (first_name, last_name) DO NOTHING
+		 RETURNING id`,
+		author.FirstName, author.LastName, author.Bio,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("author with name %s already exists", author.FirstName)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to create author: %w", err)
+	}
+	return id, nil
+}
+
+func (store *SQLAuthorStore) GetAuthorByID(ctx context.Context, id int) (Author, error) {
+	var a Author
+	err := store.db.QueryRowContext(ctx,
+		`SELECT id, first_name, last_name, bio FROM authors WHERE id = $1`, id,
+	).Scan(&a.ID, &a.FirstName, &a.LastName, &a.Bio)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Author{}, fmt.Errorf("author with ID %d not found", id)
+	}
+	if err != nil {
+		return Author{}, fmt.Errorf("failed to get author %d: %w", id, err)
+	}
+	return a, nil
+}
+
+func (store *SQLAuthorStore) UpdateAuthor(ctx context.Context, id int, author Author) (Author, error) {
+	res, err := store.db.ExecContext(ctx,
+		`UPDATE authors SET first_name = $2, last_name = $3, bio = $4 WHERE id = $1`,
+		id, author.FirstName, author.LastName, author.Bio,
+	)
+	if err != nil {
+		return Author{}, fmt.Errorf("failed to update author %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Author{}, fmt.Errorf("author with ID %d not found", id)
+	}
+	author.ID = id
+	return author, nil
+}
+
+func (store *SQLAuthorStore) DeleteAuthor(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM authors WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete author %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("author with ID %d not found", id)
+	}
+	return nil
+}
+
+func (store *SQLAuthorStore) ListAuthors(ctx context.Context) ([]Author, error) {
+	rows, err := store.db.QueryContext(ctx, `SELECT id, first_name, last_name, bio FROM authors ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list authors: %w", err)
+	}
+	defer rows.Close()
+
+	all := make([]Author, 0)
+	for rows.Next() {
+		var a Author
+		if err := rows.Scan(&a.ID, &a.FirstName, &a.LastName, &a.Bio); err != nil {
+			return nil, err
+		}
+		all = append(all, a)
+	}
+	return all, rows.Err()
+}
+
+func (store *SQLAuthorStore) RestoreAuthor(ctx context.Context, author Author) error {
+	err := sqlutil.RestoreRow(ctx, store.db, "authors",
+		`INSERT INTO authors (id, first_name, last_name, bio) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (id) DO UPDATE
+		 SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name, bio = EXCLUDED.bio`,
+		author.ID, author.FirstName, author.LastName, author.Bio)
+	if err != nil {
+		return fmt.Errorf("failed to restore author %d: %w", author.ID, err)
+	}
+	return nil
+}
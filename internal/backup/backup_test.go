@@ -0,0 +1,251 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/customer"
+	"um6p.ma/final_project/internal/genre"
+	"um6p.ma/final_project/internal/inventory"
+	"um6p.ma/final_project/internal/order"
+	"um6p.ma/final_project/internal/publisher"
+	"um6p.ma/final_project/internal/review"
+	"um6p.ma/final_project/internal/series"
+)
+
+type stores struct {
+	authors    *author.InMemoryAuthorStore
+	genres     *genre.InMemoryGenreStore
+	publishers *publisher.InMemoryPublisherStore
+	books      *book.InMemoryBookStore
+	customers  *customer.InMemoryCustomerStore
+	orders     *order.InMemoryOrderStore
+	reviews    *review.InMemoryReviewStore
+	movements  *inventory.InMemoryMovementStore
+}
+
+func newService() (*Service, stores) {
+	s := stores{
+		authors:    author.NewStore(),
+		genres:     genre.NewStore(),
+		publishers: publisher.NewStore(),
+		books:      book.NewStore(),
+		customers:  customer.NewCustomerStore(),
+		orders:     order.NewOrderStore(),
+		reviews:    review.NewStore(),
+		movements:  inventory.NewStore(),
+	}
+	return NewService(s.authors, s.genres, series.NewStore(), s.publishers, s.books,
+		s.customers, s.orders, s.reviews, s.movements), s
+}
+
+// newCatalog returns a service holding one of each entity, with bookAuthor
+// as the author of its book.
+func newCatalog(t *testing.T, bookAuthor int) (*Service, stores) {
+	t.Helper()
+	ctx := context.Background()
+	svc, s := newService()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := s.authors.CreateAuthor(ctx, author.Author{FirstName: "Frank", LastName: "Herbert"})
+	must(err)
+	g, err := s.genres.CreateGenre(ctx, genre.Genre{Name: "Science Fiction"})
+	must(err)
+	p, err := s.publishers.CreatePublisher(ctx, publisher.Publisher{Name: "Chilton"})
+	must(err)
+	b, err := s.books.CreateBook(ctx, book.Book{
+		Title:        "Dune",
+		Author:       author.Author{ID: bookAuthor},
+		Contributors: []book.Contributor{{Author: author.Author{ID: bookAuthor}, Role: book.RoleAuthor}},
+		Genres:       []int{g.ID},
+		PublisherID:  p.ID,
+		Price:        12.5,
+		Stock:        3,
+	})
+	must(err)
+	c, err := s.customers.CreateCustomer(ctx, &customer.Customer{Name: "Reader", Email: "reader@example.com"})
+	must(err)
+	o, err := s.orders.Create(ctx, order.Order{
+		CustomerID: c.ID,
+		Items:      []order.OrderItem{{BookID: b.ID, EditionID: b.Editions[0].ID, Title: b.Title, Quantity: 1, UnitPrice: 12.5}},
+		Status:     "Pending",
+		CreatedAt:  time.Now(),
+	})
+	must(err)
+	_, err = s.reviews.CreateReview(ctx, review.Review{BookID: b.ID, CustomerID: c.ID, Rating: 5, Status: review.StatusApproved})
+	must(err)
+	edition := b.Editions[0].ID
+	_, err = s.movements.RecordMovement(ctx, inventory.Movement{BookID: b.ID, EditionID: edition, Reason: inventory.ReasonOpening, Delta: 4, StockAfter: 4})
+	must(err)
+	_, err = s.movements.RecordMovement(ctx, inventory.Movement{BookID: b.ID, EditionID: edition, Reason: inventory.ReasonSale, Delta: -1, StockAfter: 3, OrderID: o.ID})
+	must(err)
+	return svc, s
+}
+
+func export(t *testing.T, svc *Service) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := svc.Export(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// contents encodes every entity in svc the way Export does, keyed by file.
+func contents(t *testing.T, svc *Service) map[string]string {
+	t.Helper()
+	a, err := svc.snapshot(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]string)
+	for name, items := range map[string]any{
+		"authors": a.authors, "genres": a.genres, "series": a.series, "publishers": a.publishers,
+		"books": a.books, "customers": a.customers, "orders": a.orders, "reviews": a.reviews,
+		"stock_movements": a.movements,
+	} {
+		body, err := encodeLines(items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[name] = string(body)
+	}
+	return out
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    ConflictPolicy
+		wantErr bool
+	}{
+		{"skip", ConflictSkip, false},
+		{"overwrite", ConflictOverwrite, false},
+		{"fail", ConflictFail, false},
+		{"", ConflictFail, false},
+		{"merge", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseConflictPolicy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseConflictPolicy(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	source, _ := newCatalog(t, 1)
+	archive := export(t, source)
+
+	target, _ := newService()
+	report, err := target.Import(context.Background(), bytes.NewReader(archive), ImportOptions{Conflict: ConflictFail})
+	if err != nil {
+		t.Fatalf("Import: %v (%v)", err, report.Errors)
+	}
+	if report.Version != FormatVersion {
+		t.Errorf("report version = %d, want %d", report.Version, FormatVersion)
+	}
+	for name, rep := range map[string]EntityReport{
+		"authors": report.Authors, "books": report.Books, "orders": report.Orders,
+		"reviews": report.Reviews, "stock movements": report.Movements,
+	} {
+		if rep.Created != rep.Total || rep.Total == 0 {
+			t.Errorf("%s: %+v, want every record created", name, rep)
+		}
+	}
+	if report.Reconciled != 0 {
+		t.Errorf("%d movements reconciled, want the restored ledger to explain the stock", report.Reconciled)
+	}
+
+	want, got := contents(t, source), contents(t, target)
+	for name := range want {
+		if got[name] != want[name] {
+			t.Errorf("%s after the round trip:\n%s\nwant:\n%s", name, got[name], want[name])
+		}
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	source, _ := newCatalog(t, 1)
+	archive := export(t, source)
+	target, s := newService()
+
+	report, err := target.Import(context.Background(), bytes.NewReader(archive), ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Books.Created != 1 {
+		t.Errorf("dry run reports %+v for books, want 1 created", report.Books)
+	}
+	if _, err := s.books.GetAllBooks(context.Background()); !errors.Is(err, book.ErrNoBooks) {
+		t.Errorf("GetAllBooks after a dry run = %v, want %v", err, book.ErrNoBooks)
+	}
+}
+
+// TestImportConflicts imports an archive over the catalog it was exported
+// from after renaming its author.
+func TestImportConflicts(t *testing.T) {
+	tests := []struct {
+		policy   ConflictPolicy
+		wantErr  error
+		want     EntityReport
+		wantName string
+	}{
+		{ConflictFail, ErrConflict, EntityReport{Total: 1}, "Renamed"},
+		{ConflictSkip, nil, EntityReport{Total: 1, Skipped: 1}, "Renamed"},
+		{ConflictOverwrite, nil, EntityReport{Total: 1, Overwritten: 1}, "Frank"},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		svc, s := newCatalog(t, 1)
+		archive := export(t, svc)
+		if _, err := s.authors.UpdateAuthor(ctx, 1, author.Author{FirstName: "Renamed", LastName: "Herbert"}); err != nil {
+			t.Fatal(err)
+		}
+
+		report, err := svc.Import(ctx, bytes.NewReader(archive), ImportOptions{Conflict: tt.policy})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Import = %v, want %v", tt.policy, err, tt.wantErr)
+		}
+		if tt.wantErr != nil && len(report.Errors) == 0 {
+			t.Errorf("%s: no conflicts listed", tt.policy)
+		}
+		if report.Authors != tt.want {
+			t.Errorf("%s: authors %+v, want %+v", tt.policy, report.Authors, tt.want)
+		}
+		a, err := s.authors.GetAuthorByID(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.FirstName != tt.wantName {
+			t.Errorf("%s: author is named %q, want %q", tt.policy, a.FirstName, tt.wantName)
+		}
+	}
+}
+
+func TestImportBrokenReference(t *testing.T) {
+	source, _ := newCatalog(t, 99)
+	archive := export(t, source)
+	target, s := newService()
+
+	report, err := target.Import(context.Background(), bytes.NewReader(archive), ImportOptions{})
+	if !errors.Is(err, ErrInvalidArchive) {
+		t.Fatalf("Import = %v, want %v", err, ErrInvalidArchive)
+	}
+	if len(report.Errors) != 1 {
+		t.Errorf("errors = %q, want the unknown author", report.Errors)
+	}
+	if authors, _ := s.authors.ListAuthors(context.Background()); len(authors) != 0 {
+		t.Errorf("authors = %+v, want nothing imported from a broken archive", authors)
+	}
+}
@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+	"um6p.ma/final_project/pkg/logging"
+)
+
+const maxImportSize = 512 << 20
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/admin/export", h.Export)
+	r.HandleFunc(http.MethodPost, "/admin/import", h.Import)
+}
+
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := h.svc.Export(r.Context(), &buf); err != nil {
+		logging.Printf(r.Context(), "export failed: %v", err)
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("bookstore-%s.tar.gz", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(w)
+}
+
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	policy, err := ParseConflictPolicy(q.Get("conflict"))
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun := false
+	if v := q.Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			pkgError.WriteJSONError(w, "invalid 'dry_run' value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	report, err := h.svc.Import(r.Context(), body, ImportOptions{DryRun: dryRun, Conflict: policy})
+
+	status := http.StatusOK
+	switch {
+	case errors.Is(err, ErrInvalidArchive):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case err != nil:
+		status = http.StatusInternalServerError
+	}
+	if err != nil && len(report.Errors) == 0 {
+		pkgError.WriteJSONError(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,75 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+)
+
+// FormatVersion 2 stores orders by customer and book reference; version 1
+// archives with embedded customers and books are converted on import.
+// Version 3 adds genres.jsonl; books in older archives carry a genre string
+// that is mapped onto top-level genres on import. Version 4 adds book
+// contributors; books in older archives credit their author as sole
+// contributor. Version 5 adds book editions; books in older archives get a
+// single paperback edition from their price, stock and ISBNs. Version 6
+// adds series.jsonl, version 7 publishers.jsonl and version 8 reviews.jsonl.
+// Version 9 adds stock_movements.jsonl; stock that the restored ledger does
+// not explain, as for older archives, gets opening or correction movements.
+const FormatVersion = 9
+
+type Manifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Counts    map[string]int `json:"counts"`
+}
+
+type ConflictPolicy string
+
+const (
+	ConflictSkip      ConflictPolicy = "skip"
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	ConflictFail      ConflictPolicy = "fail"
+)
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return p, nil
+	case "":
+		return ConflictFail, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q (want skip, overwrite or fail)", s)
+	}
+}
+
+type ImportOptions struct {
+	DryRun   bool
+	Conflict ConflictPolicy
+}
+
+type EntityReport struct {
+	Total       int `json:"total"`
+	Created     int `json:"created"`
+	Overwritten int `json:"overwritten"`
+	Skipped     int `json:"skipped"`
+	Failed      int `json:"failed"`
+}
+
+type ImportReport struct {
+	Version    int            `json:"version"`
+	DryRun     bool           `json:"dry_run"`
+	Conflict   ConflictPolicy `json:"conflict"`
+	Authors    EntityReport   `json:"authors"`
+	Genres     EntityReport   `json:"genres"`
+	Series     EntityReport   `json:"series"`
+	Publishers EntityReport   `json:"publishers"`
+	Books      EntityReport   `json:"books"`
+	Customers  EntityReport   `json:"customers"`
+	Orders     EntityReport   `json:"orders"`
+	Reviews    EntityReport   `json:"reviews"`
+	Movements  EntityReport   `json:"stock_movements"`
+	// Reconciled counts the movements recorded after the import for stock
+	// the ledger did not explain.
+	Reconciled int      `json:"reconciled"`
+	Errors     []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,183 @@
+package book
+
+import (
+	"context"
+	"fmt"
+
+	"um6p.ma/final_project/internal/author"
+)
+
+// AuthorLinkedStore ties book contributors to the authors in an
+// AuthorStore. Writes reject unknown author IDs and store each contributor's
+// current record; reads replace the stored records with the current ones, so
+// author updates show up in every book read.
+type AuthorLinkedStore struct {
+	BookStore
+	authors author.AuthorStore
+}
+
+func NewAuthorLinkedStore(store BookStore, authors author.AuthorStore) *AuthorLinkedStore {
+	return &AuthorLinkedStore{BookStore: store, authors: authors}
+}
+
+func (s *AuthorLinkedStore) resolve(ctx context.Context, b Book) (Book, error) {
+	b = b.withContributors()
+	for i, c := range b.Contributors {
+		if c.ID == 0 {
+			return Book{}, fmt.Errorf("contributors must be given by author ID")
+		}
+		a, err := s.authors.GetAuthorByID(ctx, c.ID)
+		if err != nil {
+			return Book{}, err
+		}
+		b.Contributors[i].Author = a
+	}
+	return b.withContributors(), nil
+}
+
+// authorLookup loads every author once, for linking many books.
+func (s *AuthorLinkedStore) authorLookup(ctx context.Context) (func(id int) (author.Author, bool), error) {
+	all, err := s.authors.ListAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]author.Author, len(all))
+	for _, a := range all {
+		byID[a.ID] = a
+	}
+	return func(id int) (author.Author, bool) {
+		a, ok := byID[id]
+		return a, ok
+	}, nil
+}
+
+func (s *AuthorLinkedStore) link(ctx context.Context, books []Book) ([]Book, error) {
+	lookup, err := s.authorLookup(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range books {
+		books[i] = linkAuthors(b, lookup)
+	}
+	return books, nil
+}
+
+func (s *AuthorLinkedStore) linkOne(ctx context.Context, b Book) Book {
+	return linkAuthors(b, func(id int) (author.Author, bool) {
+		a, err := s.authors.GetAuthorByID(ctx, id)
+		return a, err == nil
+	})
+}
+
+// linkAuthors replaces b's contributors with their current records, keeping
+// the stored copy of any that lookup no longer finds.
+func linkAuthors(b Book, lookup func(id int) (author.Author, bool)) Book {
+	b = b.withContributors()
+	for i, c := range b.Contributors {
+		if a, ok := lookup(c.ID); ok {
+			b.Contributors[i].Author = a
+		}
+	}
+	return b.withContributors()
+}
+
+func (s *AuthorLinkedStore) CreateBook(ctx context.Context, b Book) (Book, error) {
+	b, err := s.resolve(ctx, b)
+	if err != nil {
+		return Book{}, err
+	}
+	return s.BookStore.CreateBook(ctx, b)
+}
+
+func (s *AuthorLinkedStore) UpdateBook(ctx context.Context, id int, b Book) (Book, error) {
+	b, err := s.resolve(ctx, b)
+	if err != nil {
+		return Book{}, err
+	}
+	return s.BookStore.UpdateBook(ctx, id, b)
+}
+
+// ModifyBook passes fn the book with its current authors and checks the
+// contributors of the book it returns.
+func (s *AuthorLinkedStore) ModifyBook(ctx context.Context, id int, fn func(Book) (Book, error)) (Book, error) {
+	return ModifyBook(ctx, s.BookStore, id, func(b Book) (Book, error) {
+		b, err := fn(s.linkOne(ctx, b))
+		if err != nil {
+			return Book{}, err
+		}
+		return s.resolve(ctx, b)
+	})
+}
+
+func (s *AuthorLinkedStore) RestoreBook(ctx context.Context, b Book) error {
+	b, err := s.resolve(ctx, b)
+	if err != nil {
+		return err
+	}
+	return s.BookStore.RestoreBook(ctx, b)
+}
+
+func (s *AuthorLinkedStore) GetBook(ctx context.Context, id int) (Book, error) {
+	b, err := s.BookStore.GetBook(ctx, id)
+	if err != nil {
+		return Book{}, err
+	}
+	return s.linkOne(ctx, b), nil
+}
+
+func (s *AuthorLinkedStore) GetBookByISBN(ctx context.Context, isbn13 string) (Book, error) {
+	b, err := s.BookStore.GetBookByISBN(ctx, isbn13)
+	if err != nil {
+		return Book{}, err
+	}
+	return s.linkOne(ctx, b), nil
+}
+
+func (s *AuthorLinkedStore) GetAllBooks(ctx context.Context) ([]Book, error) {
+	books, err := s.BookStore.GetAllBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.link(ctx, books)
+}
+
+// SearchBooks matches author names against the current author records, not
+// the ones stored with the books.
+func (s *AuthorLinkedStore) SearchBooks(ctx context.Context, criteria SearchCriteria) ([]Book, error) {
+	byName := criteria.Author
+	criteria.Author = ""
+	books, err := s.BookStore.SearchBooks(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	books, err = s.link(ctx, books)
+	if err != nil || byName == "" {
+		return books, err
+	}
+	criteria.Author = byName
+	matched := make([]Book, 0, len(books))
+	for _, b := range books {
+		if criteria.Matches(b) {
+			matched = append(matched, b)
+		}
+	}
+	return matched, nil
+}
+
+// ScanBooks links each book as it goes and, like SearchBooks, matches author
+// names against the current author records.
+func (s *AuthorLinkedStore) ScanBooks(ctx context.Context, criteria SearchCriteria, fn func(Book) error) error {
+	lookup, err := s.authorLookup(ctx)
+	if err != nil {
+		return err
+	}
+	byName := SearchCriteria{Author: criteria.Author}
+	criteria.Author = ""
+	return s.BookStore.ScanBooks(ctx, criteria, func(b Book) error {
+		b = linkAuthors(b, lookup)
+		if !byName.Matches(b) {
+			return nil
+		}
+		return fn(b)
+	})
+}
@@ -0,0 +1,200 @@
+package book
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"time"
+
+	"um6p.ma/final_project/pkg/logging"
+	"um6p.ma/final_project/pkg/thumbnail"
+)
+
+const (
+	MaxCoverSize   = 5 << 20
+	maxCoverPixels = 40_000_000
+
+	CoverOriginal = "original"
+)
+
+// CoverSizes maps each thumbnail size to the box, in pixels, it fits in.
+var CoverSizes = map[string]int{
+	"thumb":  160,
+	"medium": 480,
+}
+
+var coverTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+var (
+	ErrUnsupportedCover = errors.New("cover must be a JPEG, PNG or WebP image")
+	ErrCoverTooLarge    = errors.New("cover image is too large")
+)
+
+// Cover describes a book's cover image; the image and its thumbnails are
+// kept in blob storage. The standard library cannot decode WebP, so WebP
+// covers have no dimensions or thumbnails and are served as uploaded in
+// every size.
+type Cover struct {
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	Size        int       `json:"size"`
+	ETag        string    `json:"etag"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func coverKey(bookID int, size string) string {
+	return fmt.Sprintf("covers/%d/%s", bookID, size)
+}
+
+// checkCoverType returns the type of data, which must be a cover type and
+// match the declared type unless the client gave none or a generic one.
+func checkCoverType(declared string, data []byte) (string, error) {
+	sniffed := http.DetectContentType(data)
+	if !slices.Contains(coverTypes, sniffed) {
+		return "", ErrUnsupportedCover
+	}
+	if declared != "" && declared != "application/octet-stream" {
+		mediaType, _, err := mime.ParseMediaType(declared)
+		if err != nil || mediaType != sniffed {
+			return "", fmt.Errorf("%w: declared as %s but content is %s", ErrUnsupportedCover, declared, sniffed)
+		}
+	}
+	return sniffed, nil
+}
+
+// coverThumbnails decodes a JPEG or PNG cover, fills in its dimensions and
+// returns a thumbnail in the same format for every size in CoverSizes.
+func coverThumbnails(cover *Cover, data []byte) (map[string][]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cover image: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxCoverPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrCoverTooLarge, cfg.Width, cfg.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cover image: %w", err)
+	}
+	cover.Width, cover.Height = cfg.Width, cfg.Height
+
+	thumbs := make(map[string][]byte, len(CoverSizes))
+	for size, px := range CoverSizes {
+		var buf bytes.Buffer
+		thumb := thumbnail.Fit(img, px)
+		if cover.ContentType == "image/png" {
+			err = png.Encode(&buf, thumb)
+		} else {
+			err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s thumbnail: %w", size, err)
+		}
+		thumbs[size] = buf.Bytes()
+	}
+	return thumbs, nil
+}
+
+// SetCover stores data as the cover of book id, replacing any previous one,
+// along with its thumbnails. declared is the content type the client gave,
+// which may be empty.
+func (s *service) SetCover(ctx context.Context, id int, declared string, data []byte) (Cover, error) {
+	if len(data) > MaxCoverSize {
+		return Cover{}, fmt.Errorf("%w: over %d bytes", ErrCoverTooLarge, MaxCoverSize)
+	}
+	contentType, err := checkCoverType(declared, data)
+	if err != nil {
+		return Cover{}, err
+	}
+	if _, err := s.store.GetBook(ctx, id); err != nil {
+		return Cover{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	cover := Cover{
+		ContentType: contentType,
+		Size:        len(data),
+		ETag:        hex.EncodeToString(sum[:8]),
+		UpdatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+	var thumbs map[string][]byte
+	if contentType != "image/webp" {
+		if thumbs, err = coverThumbnails(&cover, data); err != nil {
+			return Cover{}, err
+		}
+	}
+
+	if err := s.covers.Put(ctx, coverKey(id, CoverOriginal), bytes.NewReader(data)); err != nil {
+		return Cover{}, fmt.Errorf("failed to store cover of book with ID %d: %w", id, err)
+	}
+	for size := range CoverSizes {
+		if thumb, ok := thumbs[size]; ok {
+			err = s.covers.Put(ctx, coverKey(id, size), bytes.NewReader(thumb))
+		} else {
+			err = s.covers.Delete(ctx, coverKey(id, size))
+		}
+		if err != nil {
+			return Cover{}, fmt.Errorf("failed to store %s cover of book with ID %d: %w", size, id, err)
+		}
+	}
+
+	// Thumbnailing takes a while; set the cover on the book as it is now so
+	// that stock sold in the meantime is kept.
+	_, err = ModifyBook(ctx, s.store, id, func(b Book) (Book, error) {
+		b.Cover = &cover
+		return b, nil
+	})
+	if err != nil {
+		return Cover{}, err
+	}
+	return cover, nil
+}
+
+// GetCover returns the cover of book id in the given size, which is
+// CoverOriginal or a key of CoverSizes.
+func (s *service) GetCover(ctx context.Context, id int, size string) (Cover, []byte, error) {
+	b, err := s.store.GetBook(ctx, id)
+	if err != nil {
+		return Cover{}, nil, err
+	}
+	if b.Cover == nil {
+		return Cover{}, nil, fmt.Errorf("book with ID %d has no cover", id)
+	}
+	if b.Cover.ContentType == "image/webp" {
+		size = CoverOriginal
+	}
+
+	r, err := s.covers.Get(ctx, coverKey(id, size))
+	if err != nil {
+		return Cover{}, nil, err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Cover{}, nil, fmt.Errorf("failed to read cover of book with ID %d: %w", id, err)
+	}
+	return *b.Cover, data, nil
+}
+
+func (s *service) deleteCover(ctx context.Context, id int) {
+	sizes := []string{CoverOriginal}
+	for size := range CoverSizes {
+		sizes = append(sizes, size)
+	}
+	for _, size := range sizes {
+		if err := s.covers.Delete(ctx, coverKey(id, size)); err != nil {
+			logging.Printf(ctx, "failed to delete %s cover of book %d: %v", size, id, err)
+		}
+	}
+}
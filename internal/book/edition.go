@@ -0,0 +1,172 @@
+package book
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Format is the physical or digital form an edition is sold in.
+type Format string
+
+const (
+	FormatHardcover Format = "hardcover"
+	FormatPaperback Format = "paperback"
+	FormatEbook     Format = "ebook"
+	FormatAudiobook Format = "audiobook"
+)
+
+var Formats = []Format{FormatHardcover, FormatPaperback, FormatEbook, FormatAudiobook}
+
+func ParseFormat(s string) (Format, error) {
+	if f := Format(s); slices.Contains(Formats, f) {
+		return f, nil
+	}
+	return "", fmt.Errorf("invalid format %q", s)
+}
+
+// Edition is one separately priced and stocked form of a book. Edition IDs
+// are unique across the catalog and serve as SKUs. PublisherID names the
+// publisher of the edition; editions without one are published by the
+// book's publisher.
+type Edition struct {
+	ID          int     `json:"id"`
+	Format      Format  `json:"format"`
+	ISBN13      string  `json:"isbn13,omitempty"`
+	ISBN10      string  `json:"isbn10,omitempty"`
+	PublisherID int     `json:"publisher_id,omitempty"`
+	PageCount   int     `json:"page_count,omitempty"`
+	Price       float64 `json:"price"`
+	Stock       int     `json:"stock"`
+
+	// legacyPublisher holds the publisher name editions were stored with
+	// before they referenced publishers, until it is resolved to
+	// PublisherID.
+	legacyPublisher string
+}
+
+// UnmarshalJSON reads the plain "publisher" name of older data into
+// legacyPublisher.
+func (e *Edition) UnmarshalJSON(data []byte) error {
+	type plain Edition
+	var raw struct {
+		plain
+		Publisher string `json:"publisher"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = Edition(raw.plain)
+	e.legacyPublisher = strings.TrimSpace(raw.Publisher)
+	return nil
+}
+
+// withEditions keeps the book-level price, stock and ISBNs in step with the
+// editions: the first edition is the default, whose price and ISBNs the book
+// shows, and the book's stock is the total over all editions. A book without
+// editions, as written before editions existed, gets a single paperback
+// edition built from its own fields.
+func (b Book) withEditions() Book {
+	if len(b.Editions) == 0 {
+		b.Editions = []Edition{{
+			Format: FormatPaperback,
+			ISBN13: b.ISBN13,
+			ISBN10: b.ISBN10,
+			Price:  b.Price,
+			Stock:  b.Stock,
+		}}
+	} else {
+		b.Editions = slices.Clone(b.Editions)
+	}
+
+	b.Stock = 0
+	for i, e := range b.Editions {
+		if e.Format == "" {
+			b.Editions[i].Format = FormatPaperback
+		}
+		b.Stock += e.Stock
+	}
+	def := b.Editions[0]
+	b.Price, b.ISBN13, b.ISBN10 = def.Price, def.ISBN13, def.ISBN10
+	return b
+}
+
+// withStockOf gives each edition of b the stock of the edition of current
+// with the same ID.
+func (b Book) withStockOf(current Book) Book {
+	current = current.withEditions()
+	b = b.withEditions()
+	for i, e := range b.Editions {
+		if j := current.EditionIndex(e.ID); j >= 0 {
+			b.Editions[i].Stock = current.Editions[j].Stock
+		}
+	}
+	return b.withEditions()
+}
+
+// EditionIndex returns the position of edition id in b.Editions, or of the
+// default edition when id is 0, and -1 if there is no such edition.
+func (b Book) EditionIndex(id int) int {
+	if id == 0 {
+		if len(b.Editions) == 0 {
+			return -1
+		}
+		return 0
+	}
+	return slices.IndexFunc(b.Editions, func(e Edition) bool { return e.ID == id })
+}
+
+// AdjustStock changes the stock of edition editionID (the default edition
+// when 0) by delta, refusing to take it below zero.
+func (b Book) AdjustStock(editionID, delta int) (Book, error) {
+	b = b.withEditions()
+	i := b.EditionIndex(editionID)
+	if i < 0 {
+		return Book{}, fmt.Errorf("edition %d not found for book with ID %d", editionID, b.ID)
+	}
+	if e := b.Editions[i]; e.Stock+delta < 0 {
+		return Book{}, fmt.Errorf("insufficient stock for edition %d of book with ID %d; available: %d, requested: %d", e.ID, b.ID, e.Stock, -delta)
+	}
+	b.Editions[i].Stock += delta
+	return b.withEditions(), nil
+}
+
+// EditionPublisher returns the publisher of edition editionID, the default
+// edition when 0: its own, or the book's if it has none.
+func (b Book) EditionPublisher(editionID int) int {
+	if i := b.EditionIndex(editionID); i >= 0 && b.Editions[i].PublisherID != 0 {
+		return b.Editions[i].PublisherID
+	}
+	return b.PublisherID
+}
+
+// publishedBy reports whether publisherID publishes b or any of its
+// editions.
+func (b Book) publishedBy(publisherID int) bool {
+	return b.PublisherID == publisherID ||
+		slices.ContainsFunc(b.Editions, func(e Edition) bool { return e.PublisherID == publisherID })
+}
+
+// NeedsReorder reports whether b's stock has fallen below its reorder point.
+func (b Book) NeedsReorder() bool {
+	return b.ReorderPoint > 0 && b.Stock < b.ReorderPoint
+}
+
+func checkEditions(b Book) error {
+	for _, e := range b.Editions {
+		if _, err := ParseFormat(string(e.Format)); err != nil {
+			return err
+		}
+		if e.Price < 0 {
+			return fmt.Errorf("edition price must not be negative")
+		}
+		if e.Stock < 0 {
+			return fmt.Errorf("edition stock must not be negative")
+		}
+		if e.PageCount < 0 {
+			return fmt.Errorf("edition page count must not be negative")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package book
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat is a catalog feed format.
+type ExportFormat string
+
+const (
+	ExportCSV  ExportFormat = "csv"
+	ExportONIX ExportFormat = "onix"
+)
+
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch f := ExportFormat(s); f {
+	case ExportCSV, ExportONIX:
+		return f, nil
+	case "":
+		return ExportCSV, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (want csv or onix)", s)
+	}
+}
+
+// ExportOptions sets up a catalog feed. Currency is the ISO 4217 code prices
+// are given in, and Sender names the bookstore in ONIX headers.
+type ExportOptions struct {
+	Format   ExportFormat
+	Currency string
+	Sender   string
+	Criteria SearchCriteria
+}
+
+// exportFlushEvery is how many records are written between flushes, so a
+// feed reaches the client as it is produced.
+const exportFlushEvery = 100
+
+var exportCSVHeader = []string{
+	"book_id", "edition_id", "title", "author", "contributors",
+	"isbn13", "isbn10", "format", "publisher", "page_count", "genres",
+	"published_at", "price", "currency", "stock",
+}
+
+// ExportBooks writes the books matching opts.Criteria to w in opts.Format,
+// one record per edition, as they are read from the store.
+func (s *service) ExportBooks(ctx context.Context, w io.Writer, opts ExportOptions) error {
+	criteria, ok, err := s.resolveCriteria(ctx, opts.Criteria)
+	if err != nil {
+		return err
+	}
+	scan := func(fn func(Book) error) error {
+		if !ok {
+			return nil
+		}
+		return s.store.ScanBooks(ctx, criteria, fn)
+	}
+
+	publishers, err := s.publishers.ListPublishers(ctx)
+	if err != nil {
+		return err
+	}
+	publisherNames := make(map[int]string, len(publishers))
+	for _, p := range publishers {
+		publisherNames[p.ID] = p.Name
+	}
+
+	switch opts.Format {
+	case ExportCSV:
+		genres, err := s.genres.ListGenres(ctx)
+		if err != nil {
+			return err
+		}
+		names := make(map[int]string, len(genres))
+		for _, g := range genres {
+			names[g.ID] = g.Name
+		}
+		return writeCSV(w, opts, names, publisherNames, scan)
+	case ExportONIX:
+		return writeONIX(w, opts, time.Now(), publisherNames, scan)
+	default:
+		return fmt.Errorf("unknown export format %q", opts.Format)
+	}
+}
+
+func personName(first, last string) string {
+	return strings.TrimSpace(first + " " + last)
+}
+
+func formatPrice(p float64) string {
+	return strconv.FormatFloat(p, 'f', 2, 64)
+}
+
+func writeCSV(w io.Writer, opts ExportOptions, genreNames, publisherNames map[int]string, scan func(func(Book) error) error) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportCSVHeader); err != nil {
+		return err
+	}
+
+	n := 0
+	err := scan(func(b Book) error {
+		contributors := make([]string, len(b.Contributors))
+		for i, c := range b.Contributors {
+			contributors[i] = fmt.Sprintf("%s (%s)", personName(c.FirstName, c.LastName), c.Role)
+		}
+		genres := make([]string, 0, len(b.Genres))
+		for _, id := range b.Genres {
+			if name, ok := genreNames[id]; ok {
+				genres = append(genres, name)
+			}
+		}
+		published := ""
+		if !b.PublishedAt.IsZero() {
+			published = b.PublishedAt.Format(time.DateOnly)
+		}
+
+		for _, e := range b.Editions {
+			pages := ""
+			if e.PageCount > 0 {
+				pages = strconv.Itoa(e.PageCount)
+			}
+			err := cw.Write([]string{
+				strconv.Itoa(b.ID), strconv.Itoa(e.ID), b.Title,
+				personName(b.Author.FirstName, b.Author.LastName), strings.Join(contributors, "; "),
+				e.ISBN13, e.ISBN10, string(e.Format), publisherNames[b.EditionPublisher(e.ID)], pages, strings.Join(genres, "; "),
+				published, formatPrice(e.Price), opts.Currency, strconv.Itoa(e.Stock),
+			})
+			if err != nil {
+				return err
+			}
+			if n++; n%exportFlushEvery == 0 {
+				cw.Flush()
+				if err := cw.Error(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ONIX 3.0 reference-tag elements, in the order the schema requires. Code
+// values are from the ONIX code lists.
+
+const onixNamespace = "http://ns.editeur.org/onix/3.0/reference"
+
+var onixProductForms = map[Format]string{
+	FormatHardcover: "BB",
+	FormatPaperback: "BC",
+	FormatEbook:     "ED",
+	FormatAudiobook: "AJ",
+}
+
+var onixContributorRoles = map[Role]string{
+	RoleAuthor:      "A01",
+	RoleCoAuthor:    "A01",
+	RoleEditor:      "B01",
+	RoleTranslator:  "B06",
+	RoleIllustrator: "A12",
+}
+
+type onixHeader struct {
+	XMLName      xml.Name `xml:"Header"`
+	SenderName   string   `xml:"Sender>SenderName"`
+	SentDateTime string
+}
+
+type onixIdentifier struct {
+	ProductIDType string
+	IDTypeName    string `xml:",omitempty"`
+	IDValue       string
+}
+
+type onixContributor struct {
+	SequenceNumber  int
+	ContributorRole string
+	PersonName      string
+	NamesBeforeKey  string `xml:",omitempty"`
+	KeyNames        string `xml:",omitempty"`
+}
+
+type onixExtent struct {
+	ExtentType  string
+	ExtentValue int
+	ExtentUnit  string
+}
+
+type onixDescriptiveDetail struct {
+	ProductComposition string
+	ProductForm        string
+	TitleType          string            `xml:"TitleDetail>TitleType"`
+	TitleElementLevel  string            `xml:"TitleDetail>TitleElement>TitleElementLevel"`
+	TitleText          string            `xml:"TitleDetail>TitleElement>TitleText"`
+	Contributors       []onixContributor `xml:"Contributor"`
+	Extents            []onixExtent      `xml:"Extent"`
+}
+
+type onixPublisher struct {
+	PublishingRole string
+	PublisherName  string
+}
+
+type onixPublishingDate struct {
+	PublishingDateRole string
+	Date               string
+}
+
+type onixPublishingDetail struct {
+	Publishers      []onixPublisher      `xml:"Publisher"`
+	PublishingDates []onixPublishingDate `xml:"PublishingDate"`
+}
+
+type onixSupplyDetail struct {
+	SupplierRole        string `xml:"Supplier>SupplierRole"`
+	SupplierName        string `xml:"Supplier>SupplierName"`
+	ProductAvailability string
+	OnHand              int    `xml:"Stock>OnHand"`
+	PriceType           string `xml:"Price>PriceType"`
+	PriceAmount         string `xml:"Price>PriceAmount"`
+	CurrencyCode        string `xml:"Price>CurrencyCode"`
+}
+
+type onixProduct struct {
+	XMLName            xml.Name `xml:"Product"`
+	RecordReference    string
+	NotificationType   string
+	ProductIdentifiers []onixIdentifier `xml:"ProductIdentifier"`
+	DescriptiveDetail  onixDescriptiveDetail
+	PublishingDetail   *onixPublishingDetail `xml:",omitempty"`
+	SupplyDetail       onixSupplyDetail      `xml:"ProductSupply>SupplyDetail"`
+}
+
+// onixProductFor describes edition e of b, published by publisherName, as
+// an ONIX product, available if it is in stock.
+func onixProductFor(b Book, e Edition, publisherName string, opts ExportOptions) onixProduct {
+	p := onixProduct{
+		RecordReference:  fmt.Sprintf("%s-edition-%d", opts.Sender, e.ID),
+		NotificationType: "03",
+		ProductIdentifiers: []onixIdentifier{
+			{ProductIDType: "01", IDTypeName: "SKU", IDValue: strconv.Itoa(e.ID)},
+		},
+		DescriptiveDetail: onixDescriptiveDetail{
+			ProductComposition: "00",
+			ProductForm:        onixProductForms[e.Format],
+			TitleType:          "01",
+			TitleElementLevel:  "01",
+			TitleText:          b.Title,
+		},
+		SupplyDetail: onixSupplyDetail{
+			SupplierRole:        "00",
+			SupplierName:        opts.Sender,
+			ProductAvailability: "31",
+			OnHand:              e.Stock,
+			PriceType:           "01",
+			PriceAmount:         formatPrice(e.Price),
+			CurrencyCode:        opts.Currency,
+		},
+	}
+	if e.ISBN13 != "" {
+		p.ProductIdentifiers = append(p.ProductIdentifiers, onixIdentifier{ProductIDType: "15", IDValue: e.ISBN13})
+	}
+	for i, c := range b.Contributors {
+		p.DescriptiveDetail.Contributors = append(p.DescriptiveDetail.Contributors, onixContributor{
+			SequenceNumber:  i + 1,
+			ContributorRole: onixContributorRoles[c.Role],
+			PersonName:      personName(c.FirstName, c.LastName),
+			NamesBeforeKey:  c.FirstName,
+			KeyNames:        c.LastName,
+		})
+	}
+	if e.PageCount > 0 {
+		p.DescriptiveDetail.Extents = []onixExtent{{ExtentType: "00", ExtentValue: e.PageCount, ExtentUnit: "03"}}
+	}
+
+	var pub onixPublishingDetail
+	if publisherName != "" {
+		pub.Publishers = []onixPublisher{{PublishingRole: "01", PublisherName: publisherName}}
+	}
+	if !b.PublishedAt.IsZero() {
+		pub.PublishingDates = []onixPublishingDate{{PublishingDateRole: "01", Date: b.PublishedAt.Format("20060102")}}
+	}
+	if len(pub.Publishers) > 0 || len(pub.PublishingDates) > 0 {
+		p.PublishingDetail = &pub
+	}
+	if e.Stock > 0 {
+		p.SupplyDetail.ProductAvailability = "21"
+	}
+	return p
+}
+
+func writeONIX(w io.Writer, opts ExportOptions, now time.Time, publisherNames map[int]string, scan func(func(Book) error) error) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	message := xml.StartElement{
+		Name: xml.Name{Local: "ONIXMessage"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "release"}, Value: "3.0"},
+			{Name: xml.Name{Local: "xmlns"}, Value: onixNamespace},
+		},
+	}
+	if err := enc.EncodeToken(message); err != nil {
+		return err
+	}
+	header := onixHeader{SenderName: opts.Sender, SentDateTime: now.UTC().Format("20060102T1504Z")}
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+
+	err := scan(func(b Book) error {
+		for _, e := range b.Editions {
+			if err := enc.Encode(onixProductFor(b, e, publisherNames[b.EditionPublisher(e.ID)], opts)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(message.End()); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,89 @@
+package book
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID        int    `json:"next_id"`
+	NextEditionID int    `json:"next_edition_id,omitempty"`
+	Books         []Book `json:"books"`
+}
+
+func (store *InMemoryBookStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	snap := storeSnapshot{NextID: store.nextID, NextEditionID: store.nextEditionID, Books: make([]Book, 0, len(store.books))}
+	for _, b := range store.books {
+		snap.Books = append(snap.Books, b)
+	}
+	return snap
+}
+
+func (store *InMemoryBookStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.books = make(map[int]Book, len(snap.Books))
+	store.nextID = max(snap.NextID, 1)
+	store.nextEditionID = max(snap.NextEditionID, 1)
+	for _, b := range snap.Books {
+		store.books[b.ID] = b
+		store.nextID = max(store.nextID, b.ID+1)
+		for _, e := range b.Editions {
+			store.nextEditionID = max(store.nextEditionID, e.ID+1)
+		}
+	}
+}
+
+type FileBookStore struct {
+	*InMemoryBookStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileStore(dir string) (*FileBookStore, error) {
+	mem := NewStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "books.json"), "books", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileBookStore{InMemoryBookStore: mem, file: file}, nil
+}
+
+func (store *FileBookStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileBookStore) CreateBook(ctx context.Context, book Book) (Book, error) {
+	created, err := store.InMemoryBookStore.CreateBook(ctx, book)
+	if err != nil {
+		return Book{}, err
+	}
+	return created, store.file.Save()
+}
+
+func (store *FileBookStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
+	updated, err := store.InMemoryBookStore.UpdateBook(ctx, id, book)
+	if err != nil {
+		return Book{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FileBookStore) DeleteBook(ctx context.Context, id int) error {
+	if err := store.InMemoryBookStore.DeleteBook(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileBookStore) RestoreBook(ctx context.Context, book Book) error {
+	if err := store.InMemoryBookStore.RestoreBook(ctx, book); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
@@ -1,103 +1,442 @@
 package book
 
 import (
-	"context"
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
-	"um6p.ma/final_project/pkg/error"
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+	"um6p.ma/final_project/pkg/isbn"
+	"um6p.ma/final_project/pkg/logging"
 )
 
 func NewStore() *InMemoryBookStore {
 	return &InMemoryBookStore{
-		books:  make(map[int]Book),
-		nextID: 1,
+		books:         make(map[int]Book),
+		nextID:        1,
+		nextEditionID: 1,
 	}
 }
 
-var store = NewStore()
+type Handler struct {
+	svc  Service
+	feed ExportOptions
+}
 
-var svc = NewService(store)
+// NewHandler returns the book handler. feed gives the currency and sender
+// of catalog exports.
+func NewHandler(svc Service, feed ExportOptions) *Handler {
+	return &Handler{svc: svc, feed: feed}
+}
 
-func BooksHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	switch r.Method {
-	case http.MethodPost:
-		var book Book
-		if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
-			error.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/books", h.ListBooks)
+	r.HandleFunc(http.MethodGet, "/books/search", h.SearchBooks)
+	r.HandleFunc(http.MethodGet, "/books/export", h.ExportBooks)
+	r.HandleFunc(http.MethodPost, "/books", h.CreateBook)
+	r.HandleFunc(http.MethodGet, "/books/{id}", h.GetBook)
+	r.HandleFunc(http.MethodPut, "/books/{id}", h.UpdateBook)
+	r.HandleFunc(http.MethodDelete, "/books/{id}", h.DeleteBook)
+	r.HandleFunc(http.MethodPut, "/books/{id}/cover", h.PutCover)
+	// GET /books/isbn/{isbn} and GET /books/{id}/cover both match
+	// /books/isbn/cover, which ServeMux refuses to register, so one pattern
+	// serves the two and tells them apart by the literal isbn segment.
+	r.HandleFunc(http.MethodGet, "/books/{id}/{sub}", h.getBookSubresource)
+	r.HandleFunc(http.MethodGet, "/authors/{id}/books", h.ListAuthorBooks)
+}
 
-		createdBook, err := svc.CreateBook(ctx, book)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+func (h *Handler) CreateBook(w http.ResponseWriter, r *http.Request) {
+	var book Book
+	if err := json.NewDecoder(r.Body).Decode(&book); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(createdBook)
+	createdBook, err := h.svc.CreateBook(r.Context(), book)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	case http.MethodGet:
-		books, err := svc.GetAllBooks(ctx)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(books)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdBook)
+}
+
+func (h *Handler) ListBooks(w http.ResponseWriter, r *http.Request) {
+	books, err := h.svc.GetAllBooks(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(books)
+}
+
+func (h *Handler) GetBook(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	book, err := h.svc.GetBook(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
 
+func (h *Handler) getBookSubresource(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.PathValue("id") == "isbn":
+		r.SetPathValue("isbn", r.PathValue("sub"))
+		h.GetBookByISBN(w, r)
+	case r.PathValue("sub") == "cover":
+		h.GetCover(w, r)
 	default:
-		error.WriteJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+		pkgError.WriteJSONError(w, "not found", http.StatusNotFound)
+	}
+}
+
+// GetBookByISBN serves barcode scanner lookups; either form of ISBN is
+// accepted.
+func (h *Handler) GetBookByISBN(w http.ResponseWriter, r *http.Request) {
+	book, err := h.svc.GetBookByISBN(r.Context(), r.PathValue("isbn"))
+	if errors.Is(err, isbn.ErrInvalid) {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(book)
+}
+
+func (h *Handler) UpdateBook(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedData Book
+	if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	updatedData.ID = id
+	updatedBook, err := h.svc.UpdateBook(r.Context(), id, updatedData)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedBook)
+}
+
+func (h *Handler) DeleteBook(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteBook(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func BookHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	id, err := strconv.Atoi(r.URL.Path[len("/books/"):])
+// ListAuthorBooks returns the author's books keyed by the role they are
+// credited in.
+func (h *Handler) ListAuthorBooks(w http.ResponseWriter, r *http.Request) {
+	authorID, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid author ID", http.StatusBadRequest)
+		return
+	}
+
+	byRole, err := h.svc.GetBooksByContributor(r.Context(), authorID)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(byRole)
+}
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchBooks filters the catalog. With q it instead returns full-text
+// results ranked by relevance, each with highlighted snippets, still
+// narrowed by any other filters given.
+func (h *Handler) SearchBooks(w http.ResponseWriter, r *http.Request) {
+	criteria, err := parseSearchCriteria(r.URL.Query())
 	if err != nil {
-		error.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		book, err := svc.GetBook(ctx, id)
+	if q := r.URL.Query().Get("q"); q != "" {
+		limit := defaultSearchLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxSearchLimit {
+				pkgError.WriteJSONError(w, fmt.Sprintf("invalid limit %q: must be between 1 and %d", v, maxSearchLimit), http.StatusBadRequest)
+				return
+			}
+		}
+		results, err := h.svc.RankedSearch(r.Context(), q, criteria, limit)
 		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
+			pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(book)
+		json.NewEncoder(w).Encode(results)
+		return
+	}
 
-	case http.MethodPut:
-		var updatedData Book
-		if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
-			error.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
+	books, err := h.svc.SearchBooks(r.Context(), criteria)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-		updatedData.ID = id
-		updatedBook, err := svc.UpdateBook(ctx, id, updatedData)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(books)
+}
+
+// exportWriteTimeout bounds each write of a catalog export, which as a whole
+// may take longer than the server's write timeout.
+const exportWriteTimeout = time.Minute
+
+// deadlineWriter pushes the response's write deadline back on every write
+// and records whether anything was written.
+type deadlineWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	written bool
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	d.rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+	d.written = true
+	return d.w.Write(p)
+}
+
+// ExportBooks streams the catalog, filtered by the search parameters, as CSV
+// or ONIX 3.0 XML.
+func (h *Handler) ExportBooks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format, err := ParseExportFormat(q.Get("format"))
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	criteria, err := parseSearchCriteria(q)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts := h.feed
+	opts.Format, opts.Criteria = format, criteria
+
+	ext, contentType := "csv", "text/csv; charset=utf-8"
+	if format == ExportONIX {
+		ext, contentType = "xml", "application/xml; charset=utf-8"
+	}
+	filename := fmt.Sprintf("catalog-%s.%s", time.Now().UTC().Format("20060102"), ext)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	dw := &deadlineWriter{w: w, rc: http.NewResponseController(w)}
+	if err := h.svc.ExportBooks(r.Context(), dw, opts); err != nil {
+		logging.Printf(r.Context(), "catalog export failed: %v", err)
+		if !dw.written {
+			w.Header().Del("Content-Disposition")
+			pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// The feed is already partly sent; cut the connection so the client
+		// does not take it for the whole catalog.
+		panic(http.ErrAbortHandler)
+	}
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(updatedBook)
+func parseSearchCriteria(q url.Values) (SearchCriteria, error) {
+	c := SearchCriteria{
+		Title:  q.Get("title"),
+		Author: q.Get("author"),
+		Genre:  q.Get("genre"),
+	}
 
-	case http.MethodDelete:
-		if err := svc.DeleteBook(ctx, id); err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
+	var err error
+	if v := q.Get("author_id"); v != "" {
+		if c.AuthorID, err = strconv.Atoi(v); err != nil || c.AuthorID <= 0 {
+			return SearchCriteria{}, fmt.Errorf("invalid author_id %q", v)
+		}
+	}
+	if v := q.Get("role"); v != "" {
+		if c.Role, err = ParseRole(v); err != nil {
+			return SearchCriteria{}, err
+		}
+	}
+	if v := q.Get("min_price"); v != "" {
+		if c.MinPrice, err = strconv.ParseFloat(v, 64); err != nil || c.MinPrice < 0 {
+			return SearchCriteria{}, fmt.Errorf("invalid min_price %q", v)
+		}
+	}
+	if v := q.Get("max_price"); v != "" {
+		if c.MaxPrice, err = strconv.ParseFloat(v, 64); err != nil || c.MaxPrice < 0 {
+			return SearchCriteria{}, fmt.Errorf("invalid max_price %q", v)
+		}
+	}
+	if c.MaxPrice > 0 && c.MinPrice > c.MaxPrice {
+		return SearchCriteria{}, fmt.Errorf("min_price must not exceed max_price")
+	}
+	if v := q.Get("published_after"); v != "" {
+		if c.PublishedAfter, err = parseSearchDate(v, false); err != nil {
+			return SearchCriteria{}, fmt.Errorf("invalid published_after %q: use YYYY-MM-DD or RFC 3339", v)
+		}
+	}
+	if v := q.Get("published_before"); v != "" {
+		if c.PublishedBefore, err = parseSearchDate(v, true); err != nil {
+			return SearchCriteria{}, fmt.Errorf("invalid published_before %q: use YYYY-MM-DD or RFC 3339", v)
+		}
+	}
+	if v := q.Get("in_stock"); v != "" {
+		if c.InStock, err = strconv.ParseBool(v); err != nil {
+			return SearchCriteria{}, fmt.Errorf("invalid in_stock %q", v)
 		}
-		w.WriteHeader(http.StatusNoContent)
+	}
+	if v := q.Get("series_id"); v != "" {
+		if c.SeriesID, err = strconv.Atoi(v); err != nil || c.SeriesID <= 0 {
+			return SearchCriteria{}, fmt.Errorf("invalid series_id %q", v)
+		}
+	}
+	if v := q.Get("publisher_id"); v != "" {
+		if c.PublisherID, err = strconv.Atoi(v); err != nil || c.PublisherID <= 0 {
+			return SearchCriteria{}, fmt.Errorf("invalid publisher_id %q", v)
+		}
+	}
+	if c.Sort, err = ParseSortKey(q.Get("sort")); err != nil {
+		return SearchCriteria{}, err
+	}
+	return c, nil
+}
 
-	default:
-		error.WriteJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+// parseSearchDate accepts a full timestamp or a plain date. A plain date used
+// as an upper bound covers the whole day.
+func parseSearchDate(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// coverCacheControl lets clients reuse a cover for a few minutes before
+// revalidating it with its ETag.
+const coverCacheControl = "public, max-age=300"
+
+// PutCover takes the image from the "cover" field of a multipart form.
+func (h *Handler) PutCover(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.svc.GetBook(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxCoverSize+1<<20)
+	file, header, err := r.FormFile("cover")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		pkgError.WriteJSONError(w, ErrCoverTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		pkgError.WriteJSONError(w, "bad request: expected a multipart form with a cover file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, MaxCoverSize+1))
+	if err != nil {
+		pkgError.WriteJSONError(w, "bad request: failed to read cover", http.StatusBadRequest)
+		return
+	}
+
+	cover, err := h.svc.SetCover(r.Context(), id, header.Header.Get("Content-Type"), data)
+	switch {
+	case errors.Is(err, ErrCoverTooLarge):
+		pkgError.WriteJSONError(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, ErrUnsupportedCover):
+		pkgError.WriteJSONError(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	case err != nil:
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cover)
+}
+
+// GetCover serves the cover in the size given by ?size=, the original by
+// default, with validators so clients and proxies can cache it.
+func (h *Handler) GetCover(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = CoverOriginal
+	}
+	if _, ok := CoverSizes[size]; !ok && size != CoverOriginal {
+		pkgError.WriteJSONError(w, fmt.Sprintf("invalid size %q", size), http.StatusBadRequest)
+		return
 	}
+
+	cover, data, err := h.svc.GetCover(r.Context(), id, size)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", cover.ContentType)
+	w.Header().Set("Cache-Control", coverCacheControl)
+	w.Header().Set("ETag", fmt.Sprintf("%q", cover.ETag+"-"+size))
+	http.ServeContent(w, r, "", cover.UpdatedAt, bytes.NewReader(data))
 }
@@ -0,0 +1,101 @@
+package book
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryBookStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var b Book
+		if err := json.Unmarshal(rec.Data, &b); err != nil {
+			return fmt.Errorf("invalid book record: %w", err)
+		}
+		store.books[b.ID] = b
+		store.nextID = max(store.nextID, b.ID+1)
+		for _, e := range b.Editions {
+			store.nextEditionID = max(store.nextEditionID, e.ID+1)
+		}
+	case journal.OpDelete:
+		delete(store.books, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledBookStore struct {
+	*InMemoryBookStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledStore(dir string, opts journal.Options) (*JournaledBookStore, error) {
+	mem := NewStore()
+	j, err := journal.OpenStore(dir, "books", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledBookStore{InMemoryBookStore: mem, journal: j}, nil
+}
+
+func (store *JournaledBookStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledBookStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledBookStore) CreateBook(ctx context.Context, book Book) (Book, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	created, err := store.InMemoryBookStore.CreateBook(ctx, book)
+	if err != nil {
+		return Book{}, err
+	}
+	return created, store.journal.Record(journal.OpCreate, created.ID, created)
+}
+
+func (store *JournaledBookStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemoryBookStore.UpdateBook(ctx, id, book)
+	if err != nil {
+		return Book{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledBookStore) DeleteBook(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryBookStore.DeleteBook(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledBookStore) RestoreBook(ctx context.Context, book Book) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryBookStore.RestoreBook(ctx, book); err != nil {
+		return err
+	}
+	// Record the stored copy, which has IDs for any new editions.
+	stored, err := store.InMemoryBookStore.GetBook(ctx, book.ID)
+	if err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, book.ID, stored)
+}
@@ -0,0 +1,108 @@
+package book
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Rating sums up the approved reviews of a book.
+type Rating struct {
+	Average float64 `json:"average"`
+	Count   int     `json:"count"`
+}
+
+// ReviewSource reports the ratings of books, leaving books without approved
+// reviews out of the map if it likes, and drops the reviews of books that
+// are deleted.
+type ReviewSource interface {
+	BookRatings(ctx context.Context, bookIDs []int) (map[int]Rating, error)
+	DeleteBookReviews(ctx context.Context, bookID int) error
+}
+
+// SortKey orders search results. The stores ignore it and return books in
+// ID order; the service sorts them afterwards. Without a sort key, full-text
+// results stay in order of relevance.
+type SortKey string
+
+const (
+	SortID          SortKey = "id"
+	SortRating      SortKey = "rating"
+	SortReviewCount SortKey = "review_count"
+)
+
+func ParseSortKey(s string) (SortKey, error) {
+	switch k := SortKey(s); k {
+	case SortID, SortRating, SortReviewCount:
+		return k, nil
+	case "":
+		return "", nil
+	default:
+		return "", fmt.Errorf("unknown sort %q (want id, rating or review_count)", s)
+	}
+}
+
+// bookLess returns the order of key, best rated or most reviewed first, or
+// nil when key leaves books in the order they are in. Books must have their
+// ratings filled in.
+func bookLess(key SortKey) func(x, y Book) bool {
+	rating := func(b Book) Rating {
+		if b.Rating == nil {
+			return Rating{}
+		}
+		return *b.Rating
+	}
+	switch key {
+	case SortID:
+		return func(x, y Book) bool { return x.ID < y.ID }
+	case SortRating:
+		return func(x, y Book) bool {
+			a, b := rating(x), rating(y)
+			if a.Average != b.Average {
+				return a.Average > b.Average
+			}
+			return a.Count > b.Count
+		}
+	case SortReviewCount:
+		return func(x, y Book) bool {
+			a, b := rating(x), rating(y)
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Average > b.Average
+		}
+	}
+	return nil
+}
+
+func sortBooks(books []Book, key SortKey) {
+	if less := bookLess(key); less != nil {
+		sort.SliceStable(books, func(i, j int) bool { return less(books[i], books[j]) })
+	}
+}
+
+func sortResults(results []SearchResult, key SortKey) {
+	if less := bookLess(key); less != nil {
+		sort.SliceStable(results, func(i, j int) bool { return less(results[i].Book, results[j].Book) })
+	}
+}
+
+// linkRatings fills in the rating of each book.
+func (s *service) linkRatings(ctx context.Context, books []Book) ([]Book, error) {
+	if s.reviews == nil || len(books) == 0 {
+		return books, nil
+	}
+	ids := make([]int, len(books))
+	for i, b := range books {
+		ids[i] = b.ID
+	}
+	ratings, err := s.reviews.BookRatings(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range books {
+		r := ratings[b.ID]
+		books[i].Rating = &r
+	}
+	return books, nil
+}
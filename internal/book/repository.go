@@ -1,35 +1,343 @@
 package book
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/pkg/isbn"
 )
 
+// Role is what a contributor did for a book.
+type Role string
+
+const (
+	RoleAuthor      Role = "author"
+	RoleCoAuthor    Role = "co-author"
+	RoleEditor      Role = "editor"
+	RoleTranslator  Role = "translator"
+	RoleIllustrator Role = "illustrator"
+)
+
+var Roles = []Role{RoleAuthor, RoleCoAuthor, RoleEditor, RoleTranslator, RoleIllustrator}
+
+func ParseRole(s string) (Role, error) {
+	if r := Role(s); slices.Contains(Roles, r) {
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid role %q", s)
+}
+
+// Contributor is an author credited on a book in some role.
+type Contributor struct {
+	author.Author
+	Role Role `json:"role"`
+}
+
+// Book lists its contributors in credit order and the editions it is sold
+// in. Author mirrors the first contributor credited as author or co-author,
+// and Price, Stock and the ISBNs mirror the editions (see withEditions), for
+// clients that only know about a single author and edition; when
+// Contributors or Editions are given, the mirrored fields are derived from
+// them. A book whose stock falls below ReorderPoint raises a reorder alert
+// for ReorderQuantity copies; a zero ReorderPoint turns alerts off. Series
+// places the book in a series, and PublisherID names its publisher, which
+// editions may override with their own. Rating
+// is filled in on reads from the book's approved reviews.
 type Book struct {
-	ID          int           `json:"id"`
-	Title       string        `json:"title"`
-	Author      author.Author `json:"author"`
-	Genre       string        `json:"genres"`
-	PublishedAt time.Time     `json:"published_at"`
-	Price       float64       `json:"price"`
-	Stock       int           `json:"stock"`
+	ID           int           `json:"id"`
+	Title        string        `json:"title"`
+	ISBN13       string        `json:"isbn13,omitempty"`
+	ISBN10       string        `json:"isbn10,omitempty"`
+	Author       author.Author `json:"author"`
+	Contributors []Contributor `json:"contributors"`
+	Editions     []Edition     `json:"editions"`
+	Genres       []int         `json:"genres"`
+	PublishedAt  time.Time     `json:"published_at"`
+	Price        float64       `json:"price"`
+	Stock        int           `json:"stock"`
+
+	ReorderPoint    int `json:"reorder_point"`
+	ReorderQuantity int `json:"reorder_quantity"`
+
+	Cover       *Cover       `json:"cover,omitempty"`
+	Series      *SeriesEntry `json:"series,omitempty"`
+	PublisherID int          `json:"publisher_id,omitempty"`
+	Rating      *Rating      `json:"rating,omitempty"`
+
+	// legacyGenre holds a plain genre string from data written before books
+	// referenced the genre tree, until it is resolved to Genres.
+	legacyGenre string
+	// stockGiven is set when decoded JSON had a "stock" field, so an update
+	// without one keeps the stock the book has.
+	stockGiven bool
+}
+
+// UnmarshalJSON accepts "genres" either as a list of genre IDs or, for older
+// data and clients, as a single comma-separated string of genre names. Books
+// with only an "author" get it as their sole contributor.
+func (b *Book) UnmarshalJSON(data []byte) error {
+	type plain Book
+	var raw struct {
+		plain
+		Genres json.RawMessage `json:"genres"`
+		Stock  *int            `json:"stock"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*b = Book(raw.plain).withContributors()
+	b.Genres = nil
+	if raw.Stock != nil {
+		b.Stock, b.stockGiven = *raw.Stock, true
+	}
+
+	genres := bytes.TrimSpace(raw.Genres)
+	switch {
+	case len(genres) == 0 || bytes.Equal(genres, []byte("null")):
+	case genres[0] == '"':
+		return json.Unmarshal(genres, &b.legacyGenre)
+	default:
+		if err := json.Unmarshal(genres, &b.Genres); err != nil {
+			return fmt.Errorf("genres must be a list of genre IDs: %w", err)
+		}
+	}
+	return nil
+}
+
+// WithGenreNames returns b with its genres given as a comma-separated list
+// of names, resolved to genre IDs when the book is validated or created.
+func (b Book) WithGenreNames(names string) Book {
+	b.Genres = nil
+	b.legacyGenre = names
+	return b
+}
+
+// withContributors keeps Author and Contributors in step: a book with only
+// an author ID gets that author as its sole contributor, otherwise Author is
+// set from the contributors. Contributors without a role are authors.
+func (b Book) withContributors() Book {
+	if len(b.Contributors) == 0 {
+		if b.Author.ID != 0 {
+			b.Contributors = []Contributor{{Author: b.Author, Role: RoleAuthor}}
+		}
+		return b
+	}
+	b.Contributors = slices.Clone(b.Contributors)
+	b.Author = author.Author{}
+	found := false
+	for i, c := range b.Contributors {
+		if c.Role == "" {
+			c.Role = RoleAuthor
+			b.Contributors[i] = c
+		}
+		if !found && (c.Role == RoleAuthor || c.Role == RoleCoAuthor) {
+			b.Author = c.Author
+			found = true
+		}
+	}
+	return b
+}
+
+// contributes reports whether authorID is credited on b, in role if role is
+// not empty.
+func (b Book) contributes(authorID int, role Role) bool {
+	return slices.ContainsFunc(b.Contributors, func(c Contributor) bool {
+		return c.ID == authorID && (role == "" || c.Role == role)
+	})
 }
 
+// NormalizeISBN validates the ISBNs of b's editions and stores them in
+// compact form. An ISBN-10 is converted to fill ISBN13, and a 978-prefixed
+// ISBN-13 fills ISBN10; if both are given they must be the same book.
+func NormalizeISBN(b Book) (Book, error) {
+	b = b.withEditions()
+	seen := make(map[string]bool)
+	for i, e := range b.Editions {
+		isbn10, isbn13, err := normalizeISBNPair(e.ISBN10, e.ISBN13)
+		if err != nil {
+			return Book{}, err
+		}
+		if isbn13 != "" && seen[isbn13] {
+			return Book{}, fmt.Errorf("ISBN %s is given for more than one edition", isbn13)
+		}
+		seen[isbn13] = true
+		b.Editions[i].ISBN10, b.Editions[i].ISBN13 = isbn10, isbn13
+	}
+	return b.withEditions(), nil
+}
+
+func normalizeISBNPair(raw10, raw13 string) (string, string, error) {
+	var isbn10, isbn13 string
+	if raw10 != "" {
+		n, err := isbn.Normalize(raw10)
+		if err != nil {
+			return "", "", err
+		}
+		if len(n) != 10 {
+			return "", "", fmt.Errorf("isbn10 %q is not an ISBN-10", raw10)
+		}
+		isbn10 = n
+	}
+	if raw13 != "" {
+		n, err := isbn.Normalize(raw13)
+		if err != nil {
+			return "", "", err
+		}
+		if len(n) != 13 {
+			return "", "", fmt.Errorf("isbn13 %q is not an ISBN-13", raw13)
+		}
+		isbn13 = n
+	}
+
+	switch {
+	case isbn10 != "" && isbn13 != "":
+		if isbn.To13(isbn10) != isbn13 {
+			return "", "", fmt.Errorf("isbn10 %s and isbn13 %s do not match", isbn10, isbn13)
+		}
+	case isbn10 != "":
+		isbn13 = isbn.To13(isbn10)
+	case isbn13 != "":
+		isbn10, _ = isbn.To10(isbn13)
+	}
+	return isbn10, isbn13, nil
+}
+
+func (b Book) hasGenre(ids []int) bool {
+	for _, g := range b.Genres {
+		if slices.Contains(ids, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrNoBooks is returned by GetAllBooks when the catalog is empty.
+var ErrNoBooks = errors.New("no books found")
+
 type BookStore interface {
 	CreateBook(ctx context.Context, book Book) (Book, error)
 	GetBook(ctx context.Context, id int) (Book, error)
+	// GetBookByISBN looks a book up by the compact ISBN-13 of any of its
+	// editions.
+	GetBookByISBN(ctx context.Context, isbn13 string) (Book, error)
 	UpdateBook(ctx context.Context, id int, book Book) (Book, error)
 	DeleteBook(ctx context.Context, id int) error
 	SearchBooks(ctx context.Context, criteria SearchCriteria) ([]Book, error)
 	GetAllBooks(ctx context.Context) ([]Book, error)
+	RestoreBook(ctx context.Context, book Book) error
+	// ScanBooks calls fn with each book matching criteria, in ID order, and
+	// stops at the first error fn returns. Backends that can do so read the
+	// books one at a time rather than all at once.
+	ScanBooks(ctx context.Context, criteria SearchCriteria, fn func(Book) error) error
+}
+
+// BookModifier is implemented by stores that can change a book in place:
+// ModifyBook passes the stored book to fn and stores the book fn returns,
+// with no other write to the book in between.
+type BookModifier interface {
+	ModifyBook(ctx context.Context, id int, fn func(Book) (Book, error)) (Book, error)
 }
 
+// ModifyBook changes book id in store with fn, atomically when store is a
+// BookModifier and otherwise by reading the book and updating it.
+func ModifyBook(ctx context.Context, store BookStore, id int, fn func(Book) (Book, error)) (Book, error) {
+	if m, ok := store.(BookModifier); ok {
+		return m.ModifyBook(ctx, id, fn)
+	}
+	b, err := store.GetBook(ctx, id)
+	if err != nil {
+		return Book{}, err
+	}
+	if b, err = fn(b); err != nil {
+		return Book{}, err
+	}
+	return store.UpdateBook(ctx, id, b)
+}
+
+// SearchCriteria filters books. Title and Author are case-insensitive
+// substrings; Author matches the "first last" name of any contributor, and
+// with AuthorID may be narrowed to contributors in Role. Genre is a genre ID
+// or name, which the service resolves into GenreIDs covering that genre and
+// all of its descendants. The date and price bounds are inclusive, and a
+// book is within the price range if any of its editions is. Zero values
+// leave the corresponding filter off. Sort orders the results; SearchBooks
+// returns books in ID order and RankedSearch by relevance without it.
 type SearchCriteria struct {
-	Title       string
-	Author      author.Author
-	Genre       string
-	PublishedAt time.Time
-	PriceRange  [2]float64
+	Title           string
+	Author          string
+	AuthorID        int
+	Role            Role
+	Genre           string
+	GenreIDs        []int
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+	MinPrice        float64
+	MaxPrice        float64
+	InStock         bool
+	SeriesID        int
+	PublisherID     int
+	Sort            SortKey
+}
+
+// SearchResult is a book ranked by a full-text query, with the matching
+// parts of its fields highlighted.
+type SearchResult struct {
+	Book       Book              `json:"book"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+func (c SearchCriteria) Matches(b Book) bool {
+	if c.Title != "" && !containsFold(b.Title, c.Title) {
+		return false
+	}
+	if c.Author != "" || c.AuthorID != 0 || c.Role != "" {
+		if !slices.ContainsFunc(b.Contributors, c.matchesContributor) {
+			return false
+		}
+	}
+	if len(c.GenreIDs) > 0 && !b.hasGenre(c.GenreIDs) {
+		return false
+	}
+	if !c.PublishedAfter.IsZero() && b.PublishedAt.Before(c.PublishedAfter) {
+		return false
+	}
+	if !c.PublishedBefore.IsZero() && b.PublishedAt.After(c.PublishedBefore) {
+		return false
+	}
+	if c.MinPrice > 0 || c.MaxPrice > 0 {
+		inRange := func(e Edition) bool {
+			return (c.MinPrice <= 0 || e.Price >= c.MinPrice) && (c.MaxPrice <= 0 || e.Price <= c.MaxPrice)
+		}
+		if !slices.ContainsFunc(b.withEditions().Editions, inRange) {
+			return false
+		}
+	}
+	if c.InStock && b.Stock <= 0 {
+		return false
+	}
+	if c.SeriesID != 0 && (b.Series == nil || b.Series.ID != c.SeriesID) {
+		return false
+	}
+	if c.PublisherID != 0 && !b.publishedBy(c.PublisherID) {
+		return false
+	}
+	return true
+}
+
+func (c SearchCriteria) matchesContributor(ct Contributor) bool {
+	return (c.Author == "" || containsFold(ct.FirstName+" "+ct.LastName, c.Author)) &&
+		(c.AuthorID == 0 || ct.ID == c.AuthorID) &&
+		(c.Role == "" || ct.Role == c.Role)
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
@@ -0,0 +1,307 @@
+package book
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/internal/genre"
+	"um6p.ma/final_project/pkg/textsearch"
+)
+
+var searchBoosts = map[string]float64{
+	"title":             3,
+	"author_first_name": 2,
+	"author_last_name":  2,
+	"genre":             1.5,
+	"author_bio":        0.5,
+}
+
+// SearchIndex keeps a full-text index of the catalog. Book fields come from
+// the book itself and author and genre fields from the latest known author
+// and genre records, so renaming an author or genre reindexes the affected
+// books. A book's genre field includes the names of parent genres, so a
+// query for "fantasy" finds books filed under Fantasy > Epic.
+type SearchIndex struct {
+	mu      sync.Mutex
+	index   *textsearch.Index
+	authors map[int]author.Author
+	genres  []genre.Genre
+	books   map[int]Book
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		index:   textsearch.New(searchBoosts),
+		authors: make(map[int]author.Author),
+		books:   make(map[int]Book),
+	}
+}
+
+// Rebuild indexes everything currently in the stores.
+func (ix *SearchIndex) Rebuild(ctx context.Context, authors author.AuthorStore, genres genre.GenreStore, books BookStore) error {
+	allAuthors, err := authors.ListAuthors(ctx)
+	if err != nil {
+		return err
+	}
+	allGenres, err := genres.ListGenres(ctx)
+	if err != nil {
+		return err
+	}
+	allBooks, err := books.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, ErrNoBooks) {
+		return err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, a := range allAuthors {
+		ix.authors[a.ID] = a
+	}
+	ix.genres = allGenres
+	for _, b := range allBooks {
+		ix.indexBook(b)
+	}
+	return nil
+}
+
+func (ix *SearchIndex) IndexBook(b Book) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.indexBook(b)
+}
+
+func (ix *SearchIndex) indexBook(b Book) {
+	b = b.withContributors()
+	var first, last, bio []string
+	for _, c := range b.Contributors {
+		a := c.Author
+		if known, ok := ix.authors[a.ID]; ok {
+			a = known
+		}
+		first = append(first, a.FirstName)
+		last = append(last, a.LastName)
+		bio = append(bio, a.Bio)
+	}
+	if len(b.Contributors) == 0 {
+		first, last = []string{b.Author.FirstName}, []string{b.Author.LastName}
+	}
+	var genreNames []string
+	for _, id := range b.Genres {
+		genreNames = append(genreNames, genre.Path(ix.genres, id)...)
+	}
+	fields := map[string]string{
+		"title":             b.Title,
+		"genre":             strings.Join(genreNames, " "),
+		"author_first_name": strings.Join(first, " "),
+		"author_last_name":  strings.Join(last, " "),
+		"author_bio":        strings.Join(bio, " "),
+	}
+	ix.books[b.ID] = b
+	if current, ok := ix.index.Fields(b.ID); ok && maps.Equal(current, fields) {
+		return
+	}
+	ix.index.Add(b.ID, fields)
+}
+
+func (ix *SearchIndex) RemoveBook(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	delete(ix.books, id)
+	ix.index.Remove(id)
+}
+
+// IndexAuthor records a's current details and reindexes the books they
+// contributed to.
+func (ix *SearchIndex) IndexAuthor(a author.Author) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.authors[a.ID] = a
+	for _, b := range ix.books {
+		if b.contributes(a.ID, "") {
+			ix.indexBook(b)
+		}
+	}
+}
+
+func (ix *SearchIndex) RemoveAuthor(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	delete(ix.authors, id)
+	for _, b := range ix.books {
+		if b.contributes(id, "") {
+			ix.indexBook(b)
+		}
+	}
+}
+
+// IndexGenre records g's current details and reindexes the catalog, since
+// any book below g may be affected.
+func (ix *SearchIndex) IndexGenre(g genre.Genre) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.genres = slices.DeleteFunc(ix.genres, func(x genre.Genre) bool { return x.ID == g.ID })
+	ix.genres = append(ix.genres, g)
+	for _, b := range ix.books {
+		ix.indexBook(b)
+	}
+}
+
+func (ix *SearchIndex) RemoveGenre(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.genres = slices.DeleteFunc(ix.genres, func(x genre.Genre) bool { return x.ID == id })
+	for _, b := range ix.books {
+		ix.indexBook(b)
+	}
+}
+
+func (ix *SearchIndex) Search(q string) []textsearch.Hit {
+	return ix.index.Search(q)
+}
+
+// IndexedStore keeps a SearchIndex in step with every write to a BookStore.
+type IndexedStore struct {
+	BookStore
+	index *SearchIndex
+}
+
+func NewIndexedStore(store BookStore, index *SearchIndex) *IndexedStore {
+	return &IndexedStore{BookStore: store, index: index}
+}
+
+func (s *IndexedStore) CreateBook(ctx context.Context, b Book) (Book, error) {
+	created, err := s.BookStore.CreateBook(ctx, b)
+	if err != nil {
+		return Book{}, err
+	}
+	s.index.IndexBook(created)
+	return created, nil
+}
+
+func (s *IndexedStore) UpdateBook(ctx context.Context, id int, b Book) (Book, error) {
+	updated, err := s.BookStore.UpdateBook(ctx, id, b)
+	if err != nil {
+		return Book{}, err
+	}
+	s.index.IndexBook(updated)
+	return updated, nil
+}
+
+func (s *IndexedStore) ModifyBook(ctx context.Context, id int, fn func(Book) (Book, error)) (Book, error) {
+	updated, err := ModifyBook(ctx, s.BookStore, id, fn)
+	if err != nil {
+		return Book{}, err
+	}
+	s.index.IndexBook(updated)
+	return updated, nil
+}
+
+func (s *IndexedStore) DeleteBook(ctx context.Context, id int) error {
+	if err := s.BookStore.DeleteBook(ctx, id); err != nil {
+		return err
+	}
+	s.index.RemoveBook(id)
+	return nil
+}
+
+func (s *IndexedStore) RestoreBook(ctx context.Context, b Book) error {
+	if err := s.BookStore.RestoreBook(ctx, b); err != nil {
+		return err
+	}
+	s.index.IndexBook(b)
+	return nil
+}
+
+// IndexedAuthorStore keeps a SearchIndex in step with author writes.
+type IndexedAuthorStore struct {
+	author.AuthorStore
+	index *SearchIndex
+}
+
+func NewIndexedAuthorStore(store author.AuthorStore, index *SearchIndex) *IndexedAuthorStore {
+	return &IndexedAuthorStore{AuthorStore: store, index: index}
+}
+
+func (s *IndexedAuthorStore) CreateAuthor(ctx context.Context, a author.Author) (int, error) {
+	id, err := s.AuthorStore.CreateAuthor(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+	a.ID = id
+	s.index.IndexAuthor(a)
+	return id, nil
+}
+
+func (s *IndexedAuthorStore) UpdateAuthor(ctx context.Context, id int, a author.Author) (author.Author, error) {
+	updated, err := s.AuthorStore.UpdateAuthor(ctx, id, a)
+	if err != nil {
+		return author.Author{}, err
+	}
+	s.index.IndexAuthor(updated)
+	return updated, nil
+}
+
+func (s *IndexedAuthorStore) DeleteAuthor(ctx context.Context, id int) error {
+	if err := s.AuthorStore.DeleteAuthor(ctx, id); err != nil {
+		return err
+	}
+	s.index.RemoveAuthor(id)
+	return nil
+}
+
+func (s *IndexedAuthorStore) RestoreAuthor(ctx context.Context, a author.Author) error {
+	if err := s.AuthorStore.RestoreAuthor(ctx, a); err != nil {
+		return err
+	}
+	s.index.IndexAuthor(a)
+	return nil
+}
+
+// IndexedGenreStore keeps a SearchIndex in step with genre writes.
+type IndexedGenreStore struct {
+	genre.GenreStore
+	index *SearchIndex
+}
+
+func NewIndexedGenreStore(store genre.GenreStore, index *SearchIndex) *IndexedGenreStore {
+	return &IndexedGenreStore{GenreStore: store, index: index}
+}
+
+func (s *IndexedGenreStore) CreateGenre(ctx context.Context, g genre.Genre) (genre.Genre, error) {
+	created, err := s.GenreStore.CreateGenre(ctx, g)
+	if err != nil {
+		return genre.Genre{}, err
+	}
+	s.index.IndexGenre(created)
+	return created, nil
+}
+
+func (s *IndexedGenreStore) UpdateGenre(ctx context.Context, id int, g genre.Genre) (genre.Genre, error) {
+	updated, err := s.GenreStore.UpdateGenre(ctx, id, g)
+	if err != nil {
+		return genre.Genre{}, err
+	}
+	s.index.IndexGenre(updated)
+	return updated, nil
+}
+
+func (s *IndexedGenreStore) DeleteGenre(ctx context.Context, id int) error {
+	if err := s.GenreStore.DeleteGenre(ctx, id); err != nil {
+		return err
+	}
+	s.index.RemoveGenre(id)
+	return nil
+}
+
+func (s *IndexedGenreStore) RestoreGenre(ctx context.Context, g genre.Genre) error {
+	if err := s.GenreStore.RestoreGenre(ctx, g); err != nil {
+		return err
+	}
+	s.index.IndexGenre(g)
+	return nil
+}
@@ -0,0 +1,116 @@
+package book
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"um6p.ma/final_project/internal/series"
+)
+
+// SeriesEntry places a book in a series as volume Volume. Only ID and Volume
+// are stored; Name and the neighbouring volumes are filled in on reads.
+type SeriesEntry struct {
+	ID       int        `json:"id"`
+	Name     string     `json:"name,omitempty"`
+	Volume   int        `json:"volume"`
+	Previous *VolumeRef `json:"previous,omitempty"`
+	Next     *VolumeRef `json:"next,omitempty"`
+}
+
+// VolumeRef identifies another volume of a book's series.
+type VolumeRef struct {
+	BookID int    `json:"book_id"`
+	Title  string `json:"title"`
+	Volume int    `json:"volume"`
+}
+
+// seriesBooks returns the books of series id ordered by volume.
+func (s *service) seriesBooks(ctx context.Context, id int) ([]Book, error) {
+	books, err := s.store.SearchBooks(ctx, SearchCriteria{SeriesID: id})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].Series.Volume < books[j].Series.Volume })
+	return books, nil
+}
+
+// checkSeries checks that the series of book id exists and has no other
+// book as the same volume, and drops the fields filled in on reads.
+func (s *service) checkSeries(ctx context.Context, id int, b Book) (Book, error) {
+	if b.Series == nil {
+		return b, nil
+	}
+	entry := SeriesEntry{ID: b.Series.ID, Volume: b.Series.Volume}
+	if entry.Volume <= 0 {
+		return Book{}, fmt.Errorf("series volume must be positive")
+	}
+	if _, err := s.series.GetSeries(ctx, entry.ID); err != nil {
+		return Book{}, err
+	}
+	books, err := s.seriesBooks(ctx, entry.ID)
+	if err != nil {
+		return Book{}, err
+	}
+	for _, other := range books {
+		if other.ID != id && other.Series.Volume == entry.Volume {
+			return Book{}, fmt.Errorf("volume %d of series %d is already book %d", entry.Volume, entry.ID, other.ID)
+		}
+	}
+	b.Series = &entry
+	return b, nil
+}
+
+// linkSeries fills in the series name and the previous and next volumes of
+// each book in a series.
+func (s *service) linkSeries(ctx context.Context, books ...Book) ([]Book, error) {
+	type seriesInfo struct {
+		name  string
+		books []Book
+	}
+	infos := make(map[int]*seriesInfo)
+	for i, b := range books {
+		if b.Series == nil {
+			continue
+		}
+		info, ok := infos[b.Series.ID]
+		if !ok {
+			sr, err := s.series.GetSeries(ctx, b.Series.ID)
+			if err != nil {
+				return nil, err
+			}
+			volumes, err := s.seriesBooks(ctx, b.Series.ID)
+			if err != nil {
+				return nil, err
+			}
+			info = &seriesInfo{name: sr.Name, books: volumes}
+			infos[b.Series.ID] = info
+		}
+
+		entry := SeriesEntry{ID: b.Series.ID, Name: info.name, Volume: b.Series.Volume}
+		for _, other := range info.books {
+			ref := &VolumeRef{BookID: other.ID, Title: other.Title, Volume: other.Series.Volume}
+			switch {
+			case other.Series.Volume < entry.Volume:
+				entry.Previous = ref
+			case other.Series.Volume > entry.Volume && entry.Next == nil:
+				entry.Next = ref
+			}
+		}
+		books[i].Series = &entry
+	}
+	return books, nil
+}
+
+// SeriesVolumes lists the books of series id in reading order.
+func (s *service) SeriesVolumes(ctx context.Context, id int) ([]series.Volume, error) {
+	books, err := s.seriesBooks(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	volumes := make([]series.Volume, len(books))
+	for i, b := range books {
+		volumes[i] = series.Volume{Volume: b.Series.Volume, BookID: b.ID, Title: b.Title, Stock: b.Stock}
+	}
+	return volumes, nil
+}
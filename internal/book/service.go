@@ -2,17 +2,28 @@ package book
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 
 	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/internal/genre"
+	"um6p.ma/final_project/internal/publisher"
+	"um6p.ma/final_project/internal/series"
+	"um6p.ma/final_project/pkg/blob"
+	"um6p.ma/final_project/pkg/isbn"
+	"um6p.ma/final_project/pkg/logging"
 )
 
 type InMemoryBookStore struct {
-	mu     sync.RWMutex
-	books  map[int]Book
-	nextID int
+	mu            sync.RWMutex
+	books         map[int]Book
+	nextID        int
+	nextEditionID int
 }
 
 func (store *InMemoryBookStore) CreateBook(ctx context.Context, book Book) (Book, error) {
@@ -21,11 +32,17 @@ func (store *InMemoryBookStore) CreateBook(ctx context.Context, book Book) (Book
 
 	for _, existingbook := range store.books {
 		if existingbook.Title == book.Title {
-			log.Printf("book with title %s already exists", book.Title)
+			logging.Printf(ctx, "book with title %s already exists", book.Title)
 			return Book{}, fmt.Errorf("book with title %s already exists", book.Title)
 		}
 	}
+	book = book.withEditions()
+	if err := store.checkEditions(book, 0); err != nil {
+		logging.Printf(ctx, "%v", err)
+		return Book{}, err
+	}
 
+	store.assignEditionIDs(book)
 	book.ID = store.nextID
 	store.books[book.ID] = book
 	store.nextID++
@@ -39,22 +56,79 @@ func (store *InMemoryBookStore) GetBook(ctx context.Context, id int) (Book, erro
 
 	book, found := store.books[id]
 	if !found {
-		log.Printf("book with ID %d not found", id)
+		logging.Printf(ctx, "book with ID %d not found", id)
 		return Book{}, fmt.Errorf("book with ID %d not found", id)
 	}
 
 	return book, nil
 }
 
+// checkEditions reports an error if a book other than exceptID already has
+// one of book's ISBNs or edition IDs.
+func (store *InMemoryBookStore) checkEditions(book Book, exceptID int) error {
+	for id, existing := range store.books {
+		if id == exceptID {
+			continue
+		}
+		for _, e := range book.Editions {
+			for _, other := range existing.Editions {
+				if e.ISBN13 != "" && other.ISBN13 == e.ISBN13 {
+					return fmt.Errorf("book with ISBN %s already exists", e.ISBN13)
+				}
+				if e.ID != 0 && other.ID == e.ID {
+					return fmt.Errorf("edition %d belongs to book with ID %d", e.ID, id)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// assignEditionIDs gives new editions of book an ID, in place.
+func (store *InMemoryBookStore) assignEditionIDs(book Book) {
+	for i, e := range book.Editions {
+		if e.ID == 0 {
+			book.Editions[i].ID = store.nextEditionID
+		}
+		store.nextEditionID = max(store.nextEditionID, book.Editions[i].ID+1)
+	}
+}
+
+func (store *InMemoryBookStore) GetBookByISBN(ctx context.Context, isbn13 string) (Book, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, book := range store.books {
+		for _, e := range book.Editions {
+			if e.ISBN13 == isbn13 {
+				return book, nil
+			}
+		}
+	}
+	logging.Printf(ctx, "book with ISBN %s not found", isbn13)
+	return Book{}, fmt.Errorf("book with ISBN %s not found", isbn13)
+}
+
 func (store *InMemoryBookStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
 	store.mu.Lock()
 	defer store.mu.Unlock()
 
-	book, found := store.books[id]
-	if !found {
-		log.Printf("book with ID %d not found", id)
+	if _, found := store.books[id]; !found {
+		logging.Printf(ctx, "book with ID %d not found", id)
 		return Book{}, fmt.Errorf("book with ID %d not found", id)
 	}
+	for otherID, existingbook := range store.books {
+		if otherID != id && existingbook.Title == book.Title {
+			logging.Printf(ctx, "book with title %s already exists", book.Title)
+			return Book{}, fmt.Errorf("book with title %s already exists", book.Title)
+		}
+	}
+	book = book.withEditions()
+	if err := store.checkEditions(book, id); err != nil {
+		logging.Printf(ctx, "%v", err)
+		return Book{}, err
+	}
+	store.assignEditionIDs(book)
 	book.ID = id
 	store.books[id] = book
 
@@ -67,7 +141,7 @@ func (store *InMemoryBookStore) DeleteBook(ctx context.Context, id int) error {
 
 	_, found := store.books[id]
 	if !found {
-		log.Printf("book with ID %d not found", id)
+		logging.Printf(ctx, "book with ID %d not found", id)
 		return fmt.Errorf("book with ID %d not found", id)
 	}
 
@@ -83,108 +157,697 @@ func (store *InMemoryBookStore) GetAllBooks(ctx context.Context) ([]Book, error)
 		all = append(all, book)
 	}
 	if len(all) == 0 {
-		log.Printf("no books found")
-		return nil, fmt.Errorf("no books found")
+		logging.Printf(ctx, "no books found")
+		return nil, ErrNoBooks
 	}
 	return all, nil
 }
 
+// RestoreBook stores book under its own ID, as needed when importing a
+// backup, and moves nextID past it.
+func (store *InMemoryBookStore) RestoreBook(ctx context.Context, book Book) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if book.ID <= 0 {
+		return fmt.Errorf("invalid book ID %d", book.ID)
+	}
+	for id, existingbook := range store.books {
+		if id != book.ID && existingbook.Title == book.Title {
+			return fmt.Errorf("book with title %s already exists", book.Title)
+		}
+	}
+	book = book.withEditions()
+	if err := store.checkEditions(book, book.ID); err != nil {
+		return err
+	}
+	store.assignEditionIDs(book)
+	store.books[book.ID] = book
+	store.nextID = max(store.nextID, book.ID+1)
+	return nil
+}
+
 func (store *InMemoryBookStore) SearchBooks(ctx context.Context, criteria SearchCriteria) ([]Book, error) {
-	var result []Book
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	result := make([]Book, 0)
 	for _, book := range store.books {
-		if (criteria.Title == "" || book.Title == criteria.Title) &&
-			(criteria.Author == author.Author{} || book.Author == criteria.Author) &&
-			(criteria.Genre == "" || book.Genre == criteria.Genre) &&
-			(criteria.PublishedAt.IsZero() || book.PublishedAt.Equal(criteria.PublishedAt)) &&
-			(criteria.PriceRange == [2]float64{0, 0} || (book.Price >= criteria.PriceRange[0] && book.Price <= criteria.PriceRange[1])) {
+		if criteria.Matches(book) {
 			result = append(result, book)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
 
-	if len(result) == 0 {
-		log.Printf("no books found matching criteria")
-		return nil, fmt.Errorf("no books found matching criteria")
+func (store *InMemoryBookStore) ScanBooks(ctx context.Context, criteria SearchCriteria, fn func(Book) error) error {
+	books, err := store.SearchBooks(ctx, criteria)
+	if err != nil {
+		return err
 	}
-
-	return result, nil
+	for _, b := range books {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type Service interface {
 	CreateBook(ctx context.Context, b Book) (Book, error)
+	ValidateBook(ctx context.Context, b Book) (Book, error)
 	GetBook(ctx context.Context, id int) (Book, error)
+	GetBookByISBN(ctx context.Context, isbn string) (Book, error)
 	UpdateBook(ctx context.Context, id int, b Book) (Book, error)
 	DeleteBook(ctx context.Context, id int) error
 	GetAllBooks(ctx context.Context) ([]Book, error)
 	SearchBooks(ctx context.Context, criteria SearchCriteria) ([]Book, error)
-
-	DecrementStock(ctx context.Context, bookID int, qty int) error
+	ExportBooks(ctx context.Context, w io.Writer, opts ExportOptions) error
+	RankedSearch(ctx context.Context, q string, criteria SearchCriteria, limit int) ([]SearchResult, error)
+	GetBooksByAuthor(ctx context.Context, authorID int) ([]Book, error)
+	GetBooksByContributor(ctx context.Context, authorID int) (map[Role][]Book, error)
+	CountBooksInGenre(ctx context.Context, genreID int) (int, error)
+	CountBooksByPublisher(ctx context.Context, publisherID int) (int, error)
+	BookRefsByAuthor(ctx context.Context, authorID int) ([]author.BookRef, error)
+	ReassignBooks(ctx context.Context, fromAuthorID, toAuthorID int) error
+	DeleteBooksByAuthor(ctx context.Context, authorID int) error
+	SeriesVolumes(ctx context.Context, seriesID int) ([]series.Volume, error)
+
+	SetCover(ctx context.Context, id int, contentType string, data []byte) (Cover, error)
+	GetCover(ctx context.Context, id int, size string) (Cover, []byte, error)
 }
 
 type service struct {
-	store BookStore
+	store      BookStore
+	genres     genre.GenreStore
+	series     series.SeriesStore
+	publishers publisher.PublisherStore
+	reviews    ReviewSource
+	index      *SearchIndex
+	covers     blob.Store
 }
 
-func NewService(bookStore BookStore) Service {
+// NewService returns the book service. index may be nil, in which case
+// RankedSearch is unavailable, and reviews may be nil, in which case books
+// are read without ratings. Cover images are kept in covers.
+func NewService(bookStore BookStore, genres genre.GenreStore, seriesStore series.SeriesStore, publishers publisher.PublisherStore,
+	reviews ReviewSource, index *SearchIndex, covers blob.Store) Service {
 	return &service{
-		store: bookStore,
+		store:      bookStore,
+		genres:     genres,
+		series:     seriesStore,
+		publishers: publishers,
+		reviews:    reviews,
+		index:      index,
+		covers:     covers,
+	}
+}
+
+// decorate fills in the fields of books that are derived on reads: the
+// series links and the rating.
+func (s *service) decorate(ctx context.Context, books ...Book) ([]Book, error) {
+	books, err := s.linkSeries(ctx, books...)
+	if err != nil {
+		return nil, err
+	}
+	return s.linkRatings(ctx, books)
+}
+
+func (s *service) decorateOne(ctx context.Context, b Book) (Book, error) {
+	books, err := s.decorate(ctx, b)
+	if err != nil {
+		return Book{}, err
 	}
+	return books[0], nil
 }
+
+// resolveGenres turns a legacy genre string into genre IDs and checks that
+// every genre exists.
+func (s *service) resolveGenres(ctx context.Context, b Book) (Book, error) {
+	all, err := s.genres.ListGenres(ctx)
+	if err != nil {
+		return Book{}, err
+	}
+	if b.legacyGenre != "" && len(b.Genres) == 0 {
+		for _, name := range splitGenreNames(b.legacyGenre) {
+			matches := genre.Find(all, name)
+			if i := slices.IndexFunc(matches, func(g genre.Genre) bool { return g.ParentID == 0 }); i >= 0 {
+				matches = matches[i : i+1]
+			}
+			switch len(matches) {
+			case 0:
+				return Book{}, fmt.Errorf("unknown genre %q", name)
+			case 1:
+				b.Genres = append(b.Genres, matches[0].ID)
+			default:
+				return Book{}, fmt.Errorf("genre name %q is ambiguous; use genre IDs", name)
+			}
+		}
+	}
+	b.legacyGenre = ""
+
+	slices.Sort(b.Genres)
+	b.Genres = slices.Compact(b.Genres)
+	for _, id := range b.Genres {
+		if !slices.ContainsFunc(all, func(g genre.Genre) bool { return g.ID == id }) {
+			return Book{}, fmt.Errorf("genre with ID %d not found", id)
+		}
+	}
+	return b, nil
+}
+
+// resolveCriteria expands criteria.Genre into the IDs of that genre and its
+// descendants. ok is false when the genre matches nothing, so no book can
+// match either.
+func (s *service) resolveCriteria(ctx context.Context, criteria SearchCriteria) (SearchCriteria, bool, error) {
+	if criteria.Genre == "" {
+		return criteria, true, nil
+	}
+	all, err := s.genres.ListGenres(ctx)
+	if err != nil {
+		return criteria, false, err
+	}
+	criteria.GenreIDs = nil
+	for _, g := range genre.Find(all, criteria.Genre) {
+		criteria.GenreIDs = append(criteria.GenreIDs, genre.Descendants(all, g.ID)...)
+	}
+	return criteria, len(criteria.GenreIDs) > 0, nil
+}
+
+func splitGenreNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ResolveLegacyGenres moves a book's legacy genre string into Genres,
+// creating missing top-level genres. Books without one are returned as is.
+func ResolveLegacyGenres(ctx context.Context, genres genre.GenreStore, b Book) (Book, error) {
+	if b.legacyGenre == "" {
+		return b, nil
+	}
+	for _, name := range splitGenreNames(b.legacyGenre) {
+		g, err := genre.FindOrCreateRoot(ctx, genres, name)
+		if err != nil {
+			return Book{}, fmt.Errorf("failed to migrate genre %q of book %d: %w", name, b.ID, err)
+		}
+		if !slices.Contains(b.Genres, g.ID) {
+			b.Genres = append(b.Genres, g.ID)
+		}
+	}
+	slices.Sort(b.Genres)
+	b.legacyGenre = ""
+	return b, nil
+}
+
+// resolveLegacyPublishers gives every edition stored with a publisher name
+// the publisher of that name, regardless of case, creating publishers that
+// do not exist yet. A book without a publisher gets that of its default
+// edition. byName maps lowercase names to publisher IDs and gains the
+// publishers created.
+func resolveLegacyPublishers(ctx context.Context, publishers publisher.PublisherStore, b Book, byName map[string]int) (Book, error) {
+	b.Editions = slices.Clone(b.Editions)
+	for i, e := range b.Editions {
+		if e.legacyPublisher == "" {
+			continue
+		}
+		key := strings.ToLower(e.legacyPublisher)
+		id, ok := byName[key]
+		if !ok {
+			p, err := publishers.CreatePublisher(ctx, publisher.Publisher{Name: e.legacyPublisher})
+			if err != nil {
+				return Book{}, err
+			}
+			id = p.ID
+			byName[key] = id
+		}
+		if e.PublisherID == 0 {
+			b.Editions[i].PublisherID = id
+		}
+		b.Editions[i].legacyPublisher = ""
+	}
+	if b.PublisherID == 0 && len(b.Editions) > 0 {
+		b.PublisherID = b.Editions[0].PublisherID
+	}
+	return b, nil
+}
+
+// MigrateLegacyBooks converts books loaded from data written before the
+// genre tree, editions or publishers existed, and reports how many were
+// updated. Books without editions are stored again so that their default
+// edition gets an ID.
+func MigrateLegacyBooks(ctx context.Context, books BookStore, genres genre.GenreStore, publishers publisher.PublisherStore) (int, error) {
+	all, err := books.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, ErrNoBooks) {
+		return 0, err
+	}
+	existing, err := publishers.ListPublishers(ctx)
+	if err != nil {
+		return 0, err
+	}
+	publisherIDs := make(map[string]int, len(existing))
+	for _, p := range existing {
+		publisherIDs[strings.ToLower(p.Name)] = p.ID
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	n := 0
+	for _, b := range all {
+		newEditions := len(b.Editions) == 0 || slices.ContainsFunc(b.Editions, func(e Edition) bool { return e.ID == 0 })
+		namedPublishers := slices.ContainsFunc(b.Editions, func(e Edition) bool { return e.legacyPublisher != "" })
+		if b.legacyGenre == "" && !newEditions && !namedPublishers {
+			continue
+		}
+		migrated, err := ResolveLegacyGenres(ctx, genres, b)
+		if err != nil {
+			return n, err
+		}
+		if migrated, err = resolveLegacyPublishers(ctx, publishers, migrated, publisherIDs); err != nil {
+			return n, err
+		}
+		if _, err := books.UpdateBook(ctx, b.ID, migrated); err != nil {
+			return n, fmt.Errorf("failed to migrate book %d: %w", b.ID, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// checkContributors rejects contributors named without their author ID,
+// unknown roles and repeated credits; the store checks that the IDs exist.
+func checkContributors(b Book) (Book, error) {
+	b = b.withContributors()
+	if len(b.Contributors) == 0 && (b.Author.FirstName != "" || b.Author.LastName != "") {
+		return Book{}, fmt.Errorf("author must be given by ID")
+	}
+	seen := make(map[Contributor]bool)
+	for _, c := range b.Contributors {
+		if c.ID == 0 {
+			return Book{}, fmt.Errorf("contributors must be given by author ID")
+		}
+		if _, err := ParseRole(string(c.Role)); err != nil {
+			return Book{}, err
+		}
+		key := Contributor{Author: author.Author{ID: c.ID}, Role: c.Role}
+		if seen[key] {
+			return Book{}, fmt.Errorf("author %d is credited as %s more than once", c.ID, c.Role)
+		}
+		seen[key] = true
+	}
+	return b, nil
+}
+
+// checkPublisher checks that the publishers of b and of its editions exist.
+func (s *service) checkPublisher(ctx context.Context, b Book) error {
+	ids := []int{b.PublisherID}
+	for _, e := range b.Editions {
+		ids = append(ids, e.PublisherID)
+	}
+	slices.Sort(ids)
+	for _, id := range slices.Compact(ids) {
+		if id == 0 {
+			continue
+		}
+		if id < 0 {
+			return fmt.Errorf("invalid publisher ID %d", id)
+		}
+		if _, err := s.publishers.GetPublisher(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkReorder(b Book) error {
+	if b.ReorderPoint < 0 || b.ReorderQuantity < 0 {
+		return fmt.Errorf("reorder point and quantity must not be negative")
+	}
+	return nil
+}
+
 func (s *service) CreateBook(ctx context.Context, b Book) (Book, error) {
-	return s.store.CreateBook(ctx, b)
+	b, err := s.ValidateBook(ctx, b)
+	if err != nil {
+		return Book{}, err
+	}
+	if b, err = s.store.CreateBook(ctx, b); err != nil {
+		return Book{}, err
+	}
+	return s.decorateOne(ctx, b)
 }
+
+// ValidateBook runs the checks CreateBook makes before storing b and returns
+// b as it would be stored. Conflicts with existing books, such as a taken
+// title or ISBN, and unknown author IDs are left to the store.
+func (s *service) ValidateBook(ctx context.Context, b Book) (Book, error) {
+	b, err := checkContributors(b)
+	if err != nil {
+		return Book{}, err
+	}
+	b.Cover, b.Rating = nil, nil
+	b = b.withEditions()
+	if err := checkEditions(b); err != nil {
+		return Book{}, err
+	}
+	if err := checkReorder(b); err != nil {
+		return Book{}, err
+	}
+	if err := s.checkPublisher(ctx, b); err != nil {
+		return Book{}, err
+	}
+	b, err = NormalizeISBN(b)
+	if err != nil {
+		return Book{}, err
+	}
+	if b, err = s.checkSeries(ctx, 0, b); err != nil {
+		return Book{}, err
+	}
+	return s.resolveGenres(ctx, b)
+}
+
 func (s *service) GetBook(ctx context.Context, id int) (Book, error) {
-	return s.store.GetBook(ctx, id)
+	b, err := s.store.GetBook(ctx, id)
+	if err != nil {
+		return Book{}, err
+	}
+	return s.decorateOne(ctx, b)
 }
 
+// GetBookByISBN accepts either form of ISBN, with or without hyphens.
+func (s *service) GetBookByISBN(ctx context.Context, raw string) (Book, error) {
+	isbn13, err := isbn.Canonical(raw)
+	if err != nil {
+		return Book{}, err
+	}
+	b, err := s.store.GetBookByISBN(ctx, isbn13)
+	if err != nil {
+		return Book{}, err
+	}
+	return s.decorateOne(ctx, b)
+}
+
+// UpdateBook replaces book id, except for its cover, which only SetCover
+// changes, and the stock of its default edition when b gives neither
+// editions nor a stock.
 func (s *service) UpdateBook(ctx context.Context, id int, b Book) (Book, error) {
-	return s.store.UpdateBook(ctx, id, b)
+	b, err := checkContributors(b)
+	if err != nil {
+		return Book{}, err
+	}
+	existing, err := s.store.GetBook(ctx, id)
+	if err != nil {
+		return Book{}, err
+	}
+	b.Rating = nil
+	keepStock := len(b.Editions) == 0 && !b.stockGiven
+	if len(b.Editions) == 0 {
+		if b, err = updateDefaultEdition(existing, b); err != nil {
+			return Book{}, err
+		}
+	}
+	b = b.withEditions()
+	if err := checkEditions(b); err != nil {
+		return Book{}, err
+	}
+	if err := checkReorder(b); err != nil {
+		return Book{}, err
+	}
+	if err := s.checkPublisher(ctx, b); err != nil {
+		return Book{}, err
+	}
+	b, err = NormalizeISBN(b)
+	if err != nil {
+		return Book{}, err
+	}
+	if b, err = s.checkSeries(ctx, id, b); err != nil {
+		return Book{}, err
+	}
+	b, err = s.resolveGenres(ctx, b)
+	if err != nil {
+		return Book{}, err
+	}
+	// The stock and cover may have changed since existing was read; take
+	// them from the book as it is when the update is stored.
+	b, err = ModifyBook(ctx, s.store, id, func(current Book) (Book, error) {
+		update := b
+		update.Cover = current.Cover
+		if keepStock {
+			update = update.withStockOf(current)
+		}
+		return update, nil
+	})
+	if err != nil {
+		return Book{}, err
+	}
+	return s.decorateOne(ctx, b)
+}
+
+// updateDefaultEdition applies the price, stock and ISBNs of an update that
+// gives no editions to the book's only edition, keeping its ID and other
+// details, and its stock unless the update gave one. Books with several
+// editions must be updated edition by edition.
+func updateDefaultEdition(existing, b Book) (Book, error) {
+	switch len(existing.Editions) {
+	case 0:
+		if !b.stockGiven {
+			b.Stock = existing.Stock
+		}
+		return b, nil
+	case 1:
+		e := existing.Editions[0]
+		e.Price, e.ISBN13, e.ISBN10 = b.Price, b.ISBN13, b.ISBN10
+		if b.stockGiven {
+			e.Stock = b.Stock
+		}
+		b.Editions = []Edition{e}
+		return b, nil
+	default:
+		return Book{}, fmt.Errorf("book with ID %d has %d editions; give editions to update it", existing.ID, len(existing.Editions))
+	}
 }
 
+// DeleteBook deletes book id together with its cover and reviews.
 func (s *service) DeleteBook(ctx context.Context, id int) error {
-	return s.store.DeleteBook(ctx, id)
+	if err := s.store.DeleteBook(ctx, id); err != nil {
+		return err
+	}
+	s.deleteCover(ctx, id)
+	if s.reviews != nil {
+		if err := s.reviews.DeleteBookReviews(ctx, id); err != nil {
+			return fmt.Errorf("failed to delete reviews of book %d: %w", id, err)
+		}
+	}
+	return nil
 }
 
 func (s *service) GetAllBooks(ctx context.Context) ([]Book, error) {
-	return s.store.GetAllBooks(ctx)
+	books, err := s.store.GetAllBooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.decorate(ctx, books...)
 }
 
 func (s *service) SearchBooks(ctx context.Context, criteria SearchCriteria) ([]Book, error) {
-	return s.store.SearchBooks(ctx, criteria)
+	criteria, ok, err := s.resolveCriteria(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []Book{}, nil
+	}
+	books, err := s.store.SearchBooks(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	if books, err = s.decorate(ctx, books...); err != nil {
+		return nil, err
+	}
+	sortBooks(books, criteria.Sort)
+	return books, nil
 }
 
-func (s *service) DecrementStock(ctx context.Context, bookID int, qty int) error {
-	b, err := s.store.GetBook(ctx, bookID)
+func (s *service) CountBooksInGenre(ctx context.Context, genreID int) (int, error) {
+	all, err := s.store.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, ErrNoBooks) {
+		return 0, err
+	}
+	n := 0
+	for _, b := range all {
+		if slices.Contains(b.Genres, genreID) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *service) CountBooksByPublisher(ctx context.Context, publisherID int) (int, error) {
+	books, err := s.store.SearchBooks(ctx, SearchCriteria{PublisherID: publisherID})
 	if err != nil {
-		return fmt.Errorf("failed to get book with ID %d: %w", bookID, err)
+		return 0, err
 	}
-	if b.Stock < qty {
-		return fmt.Errorf("insufficient stock for book with ID %d; available: %d, requested: %d", bookID, b.Stock, qty)
+	return len(books), nil
+}
+
+// RankedSearch runs q against the full-text index, drops hits that do not
+// satisfy criteria and returns at most limit results, best first, or in the
+// order of criteria.Sort if it is set.
+func (s *service) RankedSearch(ctx context.Context, q string, criteria SearchCriteria, limit int) ([]SearchResult, error) {
+	if s.index == nil {
+		return nil, fmt.Errorf("full-text search is not enabled")
 	}
-	b.Stock -= qty
+	criteria, ok, err := s.resolveCriteria(ctx, criteria)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []SearchResult{}, nil
+	}
+
+	results := make([]SearchResult, 0)
+	for _, hit := range s.index.Search(q) {
+		if limit > 0 && len(results) >= limit && criteria.Sort == "" {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		b, err := s.store.GetBook(ctx, hit.ID)
+		if err != nil {
+			// Deleted since it was indexed.
+			continue
+		}
+		if !criteria.Matches(b) {
+			continue
+		}
+		if b, err = s.decorateOne(ctx, b); err != nil {
+			return nil, err
+		}
+		results = append(results, SearchResult{Book: b, Score: hit.Score, Highlights: hit.Snippets})
+	}
+	sortResults(results, criteria.Sort)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
 
-	_, err = s.store.UpdateBook(ctx, bookID, b)
+func (s *service) GetBooksByAuthor(ctx context.Context, authorID int) ([]Book, error) {
+	books, err := s.booksByAuthor(ctx, authorID)
 	if err != nil {
-		return fmt.Errorf("failed to update book ID %d after decrementing stock: %w", bookID, err)
+		return nil, err
 	}
+	return s.decorate(ctx, books...)
+}
 
-	return nil
+// booksByAuthor is GetBooksByAuthor without the series links, for callers
+// that write the books back.
+func (s *service) booksByAuthor(ctx context.Context, authorID int) ([]Book, error) {
+	all, err := s.store.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, ErrNoBooks) {
+		return nil, err
+	}
+	books := make([]Book, 0)
+	for _, b := range all {
+		if b.contributes(authorID, "") {
+			books = append(books, b)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID < books[j].ID })
+	return books, nil
 }
-func (s *service) AddBookStock(ctx context.Context, bookID int, qty int) error {
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-		b, err := s.store.GetBook(ctx, bookID)
-		if err != nil {
-			return fmt.Errorf("failed to get book with ID %d: %w", bookID, err)
+
+// GetBooksByContributor groups the books crediting authorID by the role
+// they are credited in; a book appears once per role.
+func (s *service) GetBooksByContributor(ctx context.Context, authorID int) (map[Role][]Book, error) {
+	books, err := s.GetBooksByAuthor(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	byRole := make(map[Role][]Book)
+	for _, b := range books {
+		for _, r := range Roles {
+			if b.contributes(authorID, r) {
+				byRole[r] = append(byRole[r], b)
+			}
 		}
+	}
+	return byRole, nil
+}
+
+func (s *service) BookRefsByAuthor(ctx context.Context, authorID int) ([]author.BookRef, error) {
+	books, err := s.booksByAuthor(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]author.BookRef, len(books))
+	for i, b := range books {
+		refs[i] = author.BookRef{ID: b.ID, Title: b.Title}
+	}
+	return refs, nil
+}
 
-		b.Stock += qty
-		_, err = s.store.UpdateBook(ctx, bookID, b)
+func (s *service) ReassignBooks(ctx context.Context, fromAuthorID, toAuthorID int) error {
+	books, err := s.booksByAuthor(ctx, fromAuthorID)
+	if err != nil {
+		return err
+	}
+	for _, b := range books {
+		_, err := ModifyBook(ctx, s.store, b.ID, func(b Book) (Book, error) {
+			var contributors []Contributor
+			for _, c := range b.Contributors {
+				if c.ID == fromAuthorID {
+					c.Author = author.Author{ID: toAuthorID}
+				}
+				if !slices.ContainsFunc(contributors, func(x Contributor) bool { return x.ID == c.ID && x.Role == c.Role }) {
+					contributors = append(contributors, c)
+				}
+			}
+			b.Contributors = contributors
+			return b.withContributors(), nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to update book ID %d after incrementing stock: %w", bookID, err)
+			return fmt.Errorf("failed to reassign book %d to author %d: %w", b.ID, toAuthorID, err)
 		}
+	}
+	return nil
+}
 
-		return nil
+// DeleteBooksByAuthor deletes the books authorID is the only contributor
+// to and removes them from the credits of the others.
+func (s *service) DeleteBooksByAuthor(ctx context.Context, authorID int) error {
+	books, err := s.booksByAuthor(ctx, authorID)
+	if err != nil {
+		return err
 	}
+	byAuthor := func(c Contributor) bool { return c.ID == authorID }
+	for _, b := range books {
+		if slices.ContainsFunc(b.Contributors, func(c Contributor) bool { return !byAuthor(c) }) {
+			_, err := ModifyBook(ctx, s.store, b.ID, func(b Book) (Book, error) {
+				b.Contributors = slices.DeleteFunc(slices.Clone(b.Contributors), byAuthor)
+				return b.withContributors(), nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove author %d from book %d: %w", authorID, b.ID, err)
+			}
+			continue
+		}
+		if err := s.DeleteBook(ctx, b.ID); err != nil {
+			return fmt.Errorf("failed to delete book %d of author %d: %w", b.ID, authorID, err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,522 @@
+package book
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+const bookSelect = `SELECT b.id, b.title,
+	COALESCE((SELECT string_agg(bg.genre_id::text, ',' ORDER BY bg.genre_id) FROM book_genres bg WHERE bg.book_id = b.id), ''),
+	COALESCE((SELECT json_agg(json_build_object('id', ca.id, 'first_name', ca.first_name, 'last_name', ca.last_name, 'bio', ca.bio, 'role', bc.role) ORDER BY bc.position)
+		FROM book_contributors bc JOIN authors ca ON ca.id = bc.author_id WHERE bc.book_id = b.id), '[]'),
+	COALESCE((SELECT json_agg(json_build_object('id', e.id, 'format', e.format, 'isbn13', COALESCE(e.isbn13, ''), 'isbn10', COALESCE(e.isbn10, ''),
+			'publisher_id', COALESCE(e.publisher_id, 0), 'page_count', e.page_count, 'price', e.price, 'stock', e.stock) ORDER BY e.position)
+		FROM editions e WHERE e.book_id = b.id), '[]'),
+	b.published_at, b.reorder_point, b.reorder_quantity, COALESCE(b.cover::text, ''), COALESCE(b.series_id, 0), COALESCE(b.series_volume, 0),
+	COALESCE(b.publisher_id, 0),
+	COALESCE(a.id, 0), COALESCE(a.first_name, ''), COALESCE(a.last_name, ''), COALESCE(a.bio, '')
+	FROM books b LEFT JOIN authors a ON a.id = b.author_id`
+
+type SQLBookStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLBookStore {
+	return &SQLBookStore{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBook(row rowScanner) (Book, error) {
+	var b Book
+	var genres, contributors, editions, cover string
+	var series SeriesEntry
+	err := row.Scan(&b.ID, &b.Title, &genres, &contributors, &editions, &b.PublishedAt, &b.ReorderPoint, &b.ReorderQuantity, &cover,
+		&series.ID, &series.Volume, &b.PublisherID, &b.Author.ID, &b.Author.FirstName, &b.Author.LastName, &b.Author.Bio)
+	if err != nil {
+		return Book{}, err
+	}
+	if series.ID != 0 {
+		b.Series = &series
+	}
+	for _, id := range strings.Split(genres, ",") {
+		if id == "" {
+			continue
+		}
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return Book{}, fmt.Errorf("invalid genre ID %q for book %d", id, b.ID)
+		}
+		b.Genres = append(b.Genres, n)
+	}
+	if err := json.Unmarshal([]byte(contributors), &b.Contributors); err != nil {
+		return Book{}, fmt.Errorf("invalid contributors for book %d: %w", b.ID, err)
+	}
+	if err := json.Unmarshal([]byte(editions), &b.Editions); err != nil {
+		return Book{}, fmt.Errorf("invalid editions for book %d: %w", b.ID, err)
+	}
+	if cover != "" {
+		if err := json.Unmarshal([]byte(cover), &b.Cover); err != nil {
+			return Book{}, fmt.Errorf("invalid cover for book %d: %w", b.ID, err)
+		}
+	}
+	return b.withContributors().withEditions(), nil
+}
+
+// setGenres replaces the genre links of book id.
+func setGenres(ctx context.Context, tx *sql.Tx, id int, genres []int) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_genres WHERE book_id = $1`, id); err != nil {
+		return err
+	}
+	for _, g := range genres {
+		_, err := tx.ExecContext(ctx, `INSERT INTO book_genres (book_id, genre_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, g)
+		if err != nil {
+			return fmt.Errorf("failed to link book %d to genre %d: %w", id, g, err)
+		}
+	}
+	return nil
+}
+
+// setContributors replaces the contributors of book id, keeping their order.
+func setContributors(ctx context.Context, tx *sql.Tx, id int, contributors []Contributor) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM book_contributors WHERE book_id = $1`, id); err != nil {
+		return err
+	}
+	for i, c := range contributors {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO book_contributors (book_id, position, author_id, role) VALUES ($1, $2, $3, $4)`,
+			id, i, c.ID, string(c.Role))
+		if err != nil {
+			return fmt.Errorf("failed to credit author %d on book %d: %w", c.ID, id, err)
+		}
+	}
+	return nil
+}
+
+// coverJSON encodes cover for the cover column, NULL when there is none.
+func coverJSON(cover *Cover) sql.NullString {
+	if cover == nil {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(cover)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// seriesColumns returns the series_id and series_volume of a book, both NULL
+// when it is in no series.
+func seriesColumns(entry *SeriesEntry) (sql.NullInt64, sql.NullInt64) {
+	if entry == nil {
+		return sql.NullInt64{}, sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(entry.ID), Valid: true}, sql.NullInt64{Int64: int64(entry.Volume), Valid: true}
+}
+
+// setEditions stores the editions of book id in order, deleting any no
+// longer listed, and returns them with the IDs of new ones filled in. An
+// edition ID belonging to another book is an error.
+func setEditions(ctx context.Context, tx *sql.Tx, id int, editions []Edition) ([]Edition, error) {
+	editions = slices.Clone(editions)
+	args := []any{id}
+	var kept []string
+	for _, e := range editions {
+		if e.ID != 0 {
+			args = append(args, e.ID)
+			kept = append(kept, fmt.Sprintf("$%d", len(args)))
+		}
+	}
+	query := `DELETE FROM editions WHERE book_id = $1`
+	if len(kept) > 0 {
+		query += ` AND id NOT IN (` + strings.Join(kept, ", ") + `)`
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to remove editions of book %d: %w", id, err)
+	}
+
+	for i, e := range editions {
+		values := []any{id, i, string(e.Format), sqlutil.NullString(e.ISBN13), sqlutil.NullString(e.ISBN10),
+			sqlutil.NullID(e.PublisherID), e.PageCount, e.Price, e.Stock}
+		if e.ID == 0 {
+			err := tx.QueryRowContext(ctx,
+				`INSERT INTO editions (book_id, position, format, isbn13, isbn10, publisher_id, page_count, price, stock)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+				values...,
+			).Scan(&editions[i].ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to add edition to book %d: %w", id, err)
+			}
+			continue
+		}
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO editions (id, book_id, position, format, isbn13, isbn10, publisher_id, page_count, price, stock)
+			 VALUES ($10, $1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 ON CONFLICT (id) DO UPDATE
+			 SET position = EXCLUDED.position, format = EXCLUDED.format, isbn13 = EXCLUDED.isbn13,
+			     isbn10 = EXCLUDED.isbn10, publisher_id = EXCLUDED.publisher_id, page_count = EXCLUDED.page_count,
+			     price = EXCLUDED.price, stock = EXCLUDED.stock
+			 WHERE editions.book_id = EXCLUDED.book_id`,
+			append(values, e.ID)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store edition %d: %w", e.ID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil, fmt.Errorf("edition %d belongs to another book", e.ID)
+		}
+	}
+	if len(kept) > 0 {
+		if err := sqlutil.ResetSequence(ctx, tx, "editions"); err != nil {
+			return nil, err
+		}
+	}
+	return editions, nil
+}
+
+// checkISBNs reports an error if a book other than exceptID already has one
+// of the ISBNs of editions. The unique index on editions.isbn13 backs this
+// up under races.
+func checkISBNs(ctx context.Context, tx *sql.Tx, editions []Edition, exceptID int) error {
+	for _, e := range editions {
+		if e.ISBN13 == "" {
+			continue
+		}
+		var taken bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM editions WHERE isbn13 = $1 AND book_id <> $2)`, e.ISBN13, exceptID,
+		).Scan(&taken)
+		if err != nil {
+			return fmt.Errorf("failed to check ISBN %s: %w", e.ISBN13, err)
+		}
+		if taken {
+			return fmt.Errorf("book with ISBN %s already exists", e.ISBN13)
+		}
+	}
+	return nil
+}
+
+// setRelations stores everything about book id that lives outside the books
+// table and returns book with new edition IDs filled in.
+func setRelations(ctx context.Context, tx *sql.Tx, id int, book Book) (Book, error) {
+	if err := setGenres(ctx, tx, id, book.Genres); err != nil {
+		return Book{}, err
+	}
+	if err := setContributors(ctx, tx, id, book.Contributors); err != nil {
+		return Book{}, err
+	}
+	editions, err := setEditions(ctx, tx, id, book.Editions)
+	if err != nil {
+		return Book{}, err
+	}
+	book.Editions = editions
+	return book.withEditions(), nil
+}
+
+func (store *SQLBookStore) queryBooks(ctx context.Context, query string, args ...any) ([]Book, error) {
+	var books []Book
+	err := store.eachBook(ctx, query, args, func(b Book) error {
+		books = append(books, b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// eachBook calls fn with each book the query returns, as the rows arrive.
+func (store *SQLBookStore) eachBook(ctx context.Context, query string, args []any, fn func(Book) error) error {
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query books: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		b, err := scanBook(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (store *SQLBookStore) CreateBook(ctx context.Context, book Book) (Book, error) {
+	book = book.withEditions()
+	seriesID, volume := seriesColumns(book.Series)
+	err := sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		if err := checkISBNs(ctx, tx, book.Editions, 0); err != nil {
+			return err
+		}
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO books (title, author_id, published_at, reorder_point, reorder_quantity, cover, series_id, series_volume,
+			     publisher_id)
+			 VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7, $8, $9)
+			 ON CONFLICT (title) DO NOTHING
+			 RETURNING id`,
+			book.Title, sqlutil.NullID(book.Author.ID), book.PublishedAt, book.ReorderPoint, book.ReorderQuantity, coverJSON(book.Cover),
+			seriesID, volume, sqlutil.NullID(book.PublisherID),
+		).Scan(&book.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("book with title %s already exists", book.Title)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create book: %w", err)
+		}
+		book, err = setRelations(ctx, tx, book.ID, book)
+		return err
+	})
+	if err != nil {
+		return Book{}, err
+	}
+	return book, nil
+}
+
+func (store *SQLBookStore) GetBook(ctx context.Context, id int) (Book, error) {
+	b, err := scanBook(store.db.QueryRowContext(ctx, bookSelect+` WHERE b.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Book{}, fmt.Errorf("book with ID %d not found", id)
+	}
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to get book %d: %w", id, err)
+	}
+	return b, nil
+}
+
+func (store *SQLBookStore) GetBookByISBN(ctx context.Context, isbn13 string) (Book, error) {
+	b, err := scanBook(store.db.QueryRowContext(ctx, bookSelect+` WHERE EXISTS (SELECT 1 FROM editions e WHERE e.book_id = b.id AND e.isbn13 = $1)`, isbn13))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Book{}, fmt.Errorf("book with ISBN %s not found", isbn13)
+	}
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to get book with ISBN %s: %w", isbn13, err)
+	}
+	return b, nil
+}
+
+func (store *SQLBookStore) UpdateBook(ctx context.Context, id int, book Book) (Book, error) {
+	err := sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		var err error
+		book, err = updateBook(ctx, tx, id, book)
+		return err
+	})
+	if err != nil {
+		return Book{}, err
+	}
+	return book, nil
+}
+
+// ModifyBook locks the book and its editions until fn's result is stored, so
+// stock taken by orders and adjustments in the meantime waits instead of
+// being overwritten.
+func (store *SQLBookStore) ModifyBook(ctx context.Context, id int, fn func(Book) (Book, error)) (Book, error) {
+	var book Book
+	err := sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT 1 FROM books WHERE id = $1 FOR UPDATE`, id); err != nil {
+			return fmt.Errorf("failed to lock book %d: %w", id, err)
+		}
+		if _, err := tx.ExecContext(ctx, `SELECT 1 FROM editions WHERE book_id = $1 FOR UPDATE`, id); err != nil {
+			return fmt.Errorf("failed to lock editions of book %d: %w", id, err)
+		}
+		b, err := scanBook(tx.QueryRowContext(ctx, bookSelect+` WHERE b.id = $1`, id))
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("book with ID %d not found", id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get book %d: %w", id, err)
+		}
+		if b, err = fn(b); err != nil {
+			return err
+		}
+		book, err = updateBook(ctx, tx, id, b)
+		return err
+	})
+	if err != nil {
+		return Book{}, err
+	}
+	return book, nil
+}
+
+func updateBook(ctx context.Context, tx *sql.Tx, id int, book Book) (Book, error) {
+	book = book.withEditions()
+	seriesID, volume := seriesColumns(book.Series)
+	if err := checkISBNs(ctx, tx, book.Editions, id); err != nil {
+		return Book{}, err
+	}
+	res, err := tx.ExecContext(ctx,
+		`UPDATE books SET title = $2, author_id = $3, published_at = $4, reorder_point = $5, reorder_quantity = $6,
+		     cover = $7::jsonb, series_id = $8, series_volume = $9, publisher_id = $10
+		 WHERE id = $1`,
+		id, book.Title, sqlutil.NullID(book.Author.ID), book.PublishedAt, book.ReorderPoint, book.ReorderQuantity,
+		coverJSON(book.Cover), seriesID, volume, sqlutil.NullID(book.PublisherID),
+	)
+	if err != nil {
+		return Book{}, fmt.Errorf("failed to update book %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Book{}, fmt.Errorf("book with ID %d not found", id)
+	}
+	if book, err = setRelations(ctx, tx, id, book); err != nil {
+		return Book{}, err
+	}
+	book.ID = id
+	return book, nil
+}
+
+func (store *SQLBookStore) DeleteBook(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM books WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete book %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("book with ID %d not found", id)
+	}
+	return nil
+}
+
+func (store *SQLBookStore) GetAllBooks(ctx context.Context) ([]Book, error) {
+	books, err := store.queryBooks(ctx, bookSelect+` ORDER BY b.id`)
+	if err != nil {
+		return nil, err
+	}
+	if len(books) == 0 {
+		return nil, ErrNoBooks
+	}
+	return books, nil
+}
+
+func (store *SQLBookStore) SearchBooks(ctx context.Context, criteria SearchCriteria) ([]Book, error) {
+	query, args := searchQuery(criteria)
+	books, err := store.queryBooks(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if books == nil {
+		books = []Book{}
+	}
+	return books, nil
+}
+
+func (store *SQLBookStore) ScanBooks(ctx context.Context, criteria SearchCriteria, fn func(Book) error) error {
+	query, args := searchQuery(criteria)
+	return store.eachBook(ctx, query, args, fn)
+}
+
+// searchQuery returns the query for the books matching criteria, in ID
+// order.
+func searchQuery(criteria SearchCriteria) (string, []any) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if criteria.Title != "" {
+		add("b.title ILIKE $%d", likePattern(criteria.Title))
+	}
+	if criteria.Author != "" || criteria.AuthorID != 0 || criteria.Role != "" {
+		var sub []string
+		subAdd := func(cond string, arg any) {
+			args = append(args, arg)
+			sub = append(sub, fmt.Sprintf(cond, len(args)))
+		}
+		if criteria.Author != "" {
+			subAdd("(ca.first_name || ' ' || ca.last_name) ILIKE $%d", likePattern(criteria.Author))
+		}
+		if criteria.AuthorID != 0 {
+			subAdd("bc.author_id = $%d", criteria.AuthorID)
+		}
+		if criteria.Role != "" {
+			subAdd("bc.role = $%d", string(criteria.Role))
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM book_contributors bc JOIN authors ca ON ca.id = bc.author_id WHERE bc.book_id = b.id AND "+strings.Join(sub, " AND ")+")")
+	}
+	if len(criteria.GenreIDs) > 0 {
+		placeholders := make([]string, len(criteria.GenreIDs))
+		for i, id := range criteria.GenreIDs {
+			args = append(args, id)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM book_genres bg WHERE bg.book_id = b.id AND bg.genre_id IN ("+strings.Join(placeholders, ", ")+"))")
+	}
+	if !criteria.PublishedAfter.IsZero() {
+		add("b.published_at >= $%d", criteria.PublishedAfter)
+	}
+	if !criteria.PublishedBefore.IsZero() {
+		add("b.published_at <= $%d", criteria.PublishedBefore)
+	}
+	if criteria.MinPrice > 0 || criteria.MaxPrice > 0 {
+		var sub []string
+		if criteria.MinPrice > 0 {
+			args = append(args, criteria.MinPrice)
+			sub = append(sub, fmt.Sprintf("e.price >= $%d", len(args)))
+		}
+		if criteria.MaxPrice > 0 {
+			args = append(args, criteria.MaxPrice)
+			sub = append(sub, fmt.Sprintf("e.price <= $%d", len(args)))
+		}
+		conds = append(conds, "EXISTS (SELECT 1 FROM editions e WHERE e.book_id = b.id AND "+strings.Join(sub, " AND ")+")")
+	}
+	if criteria.InStock {
+		conds = append(conds, "EXISTS (SELECT 1 FROM editions e WHERE e.book_id = b.id AND e.stock > 0)")
+	}
+	if criteria.SeriesID != 0 {
+		add("b.series_id = $%d", criteria.SeriesID)
+	}
+	if criteria.PublisherID != 0 {
+		add("(b.publisher_id = $%[1]d OR EXISTS (SELECT 1 FROM editions e WHERE e.book_id = b.id AND e.publisher_id = $%[1]d))",
+			criteria.PublisherID)
+	}
+
+	query := bookSelect
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	return query + " ORDER BY b.id", args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// likePattern turns s into a LIKE pattern matching any value containing it.
+func likePattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+func (store *SQLBookStore) RestoreBook(ctx context.Context, book Book) error {
+	book = book.withEditions()
+	seriesID, volume := seriesColumns(book.Series)
+	return sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		if err := checkISBNs(ctx, tx, book.Editions, book.ID); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO books (id, title, author_id, published_at, reorder_point, reorder_quantity, cover, series_id, series_volume,
+			     publisher_id)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, $8, $9, $10)
+			 ON CONFLICT (id) DO UPDATE
+			 SET title = EXCLUDED.title, author_id = EXCLUDED.author_id, published_at = EXCLUDED.published_at,
+			     reorder_point = EXCLUDED.reorder_point, reorder_quantity = EXCLUDED.reorder_quantity,
+			     cover = EXCLUDED.cover, series_id = EXCLUDED.series_id, series_volume = EXCLUDED.series_volume,
+			     publisher_id = EXCLUDED.publisher_id`,
+			book.ID, book.Title, sqlutil.NullID(book.Author.ID), book.PublishedAt, book.ReorderPoint, book.ReorderQuantity,
+			coverJSON(book.Cover), seriesID, volume, sqlutil.NullID(book.PublisherID))
+		if err != nil {
+			return fmt.Errorf("failed to restore book %d: %w", book.ID, err)
+		}
+		if _, err := setRelations(ctx, tx, book.ID, book); err != nil {
+			return err
+		}
+		return sqlutil.ResetSequence(ctx, tx, "books")
+	})
+}
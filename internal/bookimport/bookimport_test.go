@@ -0,0 +1,239 @@
+package bookimport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/genre"
+	"um6p.ma/final_project/internal/publisher"
+	"um6p.ma/final_project/internal/series"
+	"um6p.ma/final_project/pkg/blob"
+)
+
+// catalogCSV has two valid rows by the same new author and one row whose
+// price does not parse.
+const catalogCSV = `Title,Author,ISBN,Publisher,Price,Stock
+Dune,Frank Herbert,978-0-441-01359-3,Chilton,9.99,4
+Dune Messiah,"Herbert, Frank",,chilton,8.50,2
+Children of Dune,Frank Herbert,,Chilton,cheap,1
+`
+
+type fixture struct {
+	svc        *Service
+	authors    author.AuthorStore
+	publishers publisher.PublisherStore
+	books      book.BookStore
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	authors := author.NewStore()
+	publishers := publisher.NewStore()
+	books := book.NewAuthorLinkedStore(book.NewStore(), authors)
+	bookService := book.NewService(books, genre.NewStore(), series.NewStore(), publishers, nil, nil, blob.NewMemoryStore())
+	return fixture{
+		svc:        NewService(authors, publishers, bookService),
+		authors:    authors,
+		publishers: publishers,
+		books:      books,
+	}
+}
+
+func (f fixture) counts(t *testing.T) (authors, publishers, books int) {
+	t.Helper()
+	ctx := context.Background()
+	a, err := f.authors.ListAuthors(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := f.publishers.ListPublishers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := f.books.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, book.ErrNoBooks) {
+		t.Fatal(err)
+	}
+	return len(a), len(p), len(b)
+}
+
+func statuses(rows []RowResult) []string {
+	var out []string
+	for _, r := range rows {
+		out = append(out, r.Status)
+	}
+	return out
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mode
+		wantErr bool
+	}{
+		{"all-or-nothing", ModeAllOrNothing, false},
+		{"best-effort", ModeBestEffort, false},
+		{"", ModeAllOrNothing, false},
+		{"some", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseColumnMap(t *testing.T) {
+	got, err := ParseColumnMap("Book Name=title, Qty=quantity", "Writer=author")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"book_name": "title", "qty": "stock", "writer": "author"}
+	if len(got) != len(want) {
+		t.Errorf("ParseColumnMap = %v, want %v", got, want)
+	}
+	for header, field := range want {
+		if got[header] != field {
+			t.Errorf("ParseColumnMap maps %q to %q, want %q", header, got[header], field)
+		}
+	}
+
+	for _, spec := range []string{"Title", "=title", "Title=colour"} {
+		if _, err := ParseColumnMap(spec); err == nil {
+			t.Errorf("ParseColumnMap(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestSplitAuthorName(t *testing.T) {
+	tests := []struct {
+		in          string
+		first, last string
+	}{
+		{"Frank Herbert", "Frank", "Herbert"},
+		{"Herbert, Frank", "Frank", "Herbert"},
+		{"Ursula K. Le Guin", "Ursula K. Le", "Guin"},
+		{"Le Guin, Ursula K.", "Ursula K.", "Le Guin"},
+		{"Homer", "", "Homer"},
+	}
+	for _, tt := range tests {
+		got := splitAuthorName(tt.in)
+		if got.FirstName != tt.first || got.LastName != tt.last {
+			t.Errorf("splitAuthorName(%q) = %q %q, want %q %q", tt.in, got.FirstName, got.LastName, tt.first, tt.last)
+		}
+	}
+}
+
+func TestReadRowsRejects(t *testing.T) {
+	tests := []struct {
+		name, csv string
+	}{
+		{"empty file", ""},
+		{"no title column", "Author,Price\nFrank Herbert,9.99\n"},
+		{"duplicate field", "Title,Name\nDune,Dune\n"},
+		{"author given twice", "Title,Author,Last Name\nDune,Frank Herbert,Herbert\n"},
+	}
+	for _, tt := range tests {
+		_, err := newFixture(t).svc.Import(context.Background(), strings.NewReader(tt.csv), Options{})
+		if !errors.Is(err, ErrInvalidCSV) {
+			t.Errorf("%s: Import = %v, want %v", tt.name, err, ErrInvalidCSV)
+		}
+	}
+}
+
+func TestImportAllOrNothing(t *testing.T) {
+	f := newFixture(t)
+	report, err := f.svc.Import(context.Background(), strings.NewReader(catalogCSV), Options{Mode: ModeAllOrNothing})
+	if !errors.Is(err, ErrInvalidRows) {
+		t.Fatalf("Import = %v, want %v", err, ErrInvalidRows)
+	}
+	want := []string{StatusSkipped, StatusSkipped, StatusInvalid}
+	if got := statuses(report.Rows); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("row statuses = %v, want %v", got, want)
+	}
+	if report.Valid != 2 || report.Invalid != 1 || report.Created != 0 {
+		t.Errorf("report counts valid=%d invalid=%d created=%d, want 2, 1, 0", report.Valid, report.Invalid, report.Created)
+	}
+	if a, p, b := f.counts(t); a+p+b != 0 {
+		t.Errorf("catalog has %d authors, %d publishers and %d books, want nothing written", a, p, b)
+	}
+}
+
+func TestImportBestEffort(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	report, err := f.svc.Import(ctx, strings.NewReader(catalogCSV), Options{Mode: ModeBestEffort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{StatusCreated, StatusCreated, StatusInvalid}
+	if got := statuses(report.Rows); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("row statuses = %v, want %v", got, want)
+	}
+	if report.Created != 2 || report.AuthorsCreated != 1 || report.PublishersCreated != 1 {
+		t.Errorf("created %d books, %d authors and %d publishers, want 2, 1 and 1",
+			report.Created, report.AuthorsCreated, report.PublishersCreated)
+	}
+	if a, p, b := f.counts(t); a != 1 || p != 1 || b != 2 {
+		t.Errorf("catalog has %d authors, %d publishers and %d books, want 1, 1 and 2", a, p, b)
+	}
+
+	dune, err := f.books.GetBook(ctx, report.Rows[0].BookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := dune.Editions[0]
+	if e.ISBN13 != "9780441013593" || e.Price != 9.99 || e.Stock != 4 || dune.PublisherID == 0 {
+		t.Errorf("Dune = %+v, want the ISBN, price, stock and publisher from its row", dune)
+	}
+	if dune.Author.FirstName != "Frank" || dune.Author.LastName != "Herbert" {
+		t.Errorf("Dune is by %+v, want Frank Herbert", dune.Author)
+	}
+	if report.Rows[0].AuthorID != report.Rows[1].AuthorID {
+		t.Errorf("rows credit authors %d and %d, want the same author", report.Rows[0].AuthorID, report.Rows[1].AuthorID)
+	}
+}
+
+func TestImportMatchesExisting(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	id, err := f.authors.CreateAuthor(ctx, author.Author{FirstName: "frank", LastName: "HERBERT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.svc.books.CreateBook(ctx, book.Book{Title: "Dune Messiah", Author: author.Author{ID: id}}); err != nil {
+		t.Fatal(err)
+	}
+
+	csv := "Title,Author\nDune,Frank Herbert\nDune Messiah,Frank Herbert\nDune,Frank Herbert\n"
+	report, err := f.svc.Import(ctx, strings.NewReader(csv), Options{Mode: ModeBestEffort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{StatusCreated, StatusInvalid, StatusInvalid}
+	if got := statuses(report.Rows); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("row statuses = %v, want %v (existing and repeated titles rejected)", got, want)
+	}
+	if report.Rows[0].AuthorID != id || report.Rows[0].NewAuthor || report.AuthorsCreated != 0 {
+		t.Errorf("first row = %+v, want it matched to author %d regardless of case", report.Rows[0], id)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	f := newFixture(t)
+	report, err := f.svc.Import(context.Background(), strings.NewReader(catalogCSV), Options{DryRun: true, Mode: ModeBestEffort})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid != 2 || report.AuthorsCreated != 1 || report.PublishersCreated != 1 || report.Created != 0 {
+		t.Errorf("dry run report = %+v, want 2 valid rows and 1 author and publisher to create", report)
+	}
+	if a, p, b := f.counts(t); a+p+b != 0 {
+		t.Errorf("catalog has %d authors, %d publishers and %d books, want nothing written", a, p, b)
+	}
+}
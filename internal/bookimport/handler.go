@@ -0,0 +1,74 @@
+package bookimport
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+)
+
+const maxImportSize = 32 << 20
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodPost, "/books/import", h.Import)
+}
+
+// Import takes the CSV file as the request body. The query may set dry_run,
+// mode and any number of map parameters, each "Header=field".
+func (h *Handler) Import(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	mode, err := ParseMode(q.Get("mode"))
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun := false
+	if v := q.Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			pkgError.WriteJSONError(w, "invalid 'dry_run' value", http.StatusBadRequest)
+			return
+		}
+	}
+	columns, err := ParseColumnMap(q["map"]...)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	report, err := h.svc.Import(r.Context(), body, Options{DryRun: dryRun, Mode: mode, Columns: columns})
+
+	status := http.StatusOK
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrInvalidCSV):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrInvalidRows):
+		status = http.StatusUnprocessableEntity
+	case err != nil && report.Failed > 0:
+		status = http.StatusConflict
+	case err != nil:
+		status = http.StatusInternalServerError
+	}
+	if err != nil && len(report.Rows) == 0 {
+		pkgError.WriteJSONError(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
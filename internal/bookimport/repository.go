@@ -0,0 +1,138 @@
+package bookimport
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode decides what happens to the valid rows of a file that also has
+// invalid ones.
+type Mode string
+
+const (
+	ModeAllOrNothing Mode = "all-or-nothing"
+	ModeBestEffort   Mode = "best-effort"
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case ModeAllOrNothing, ModeBestEffort:
+		return m, nil
+	case "":
+		return ModeAllOrNothing, nil
+	default:
+		return "", fmt.Errorf("unknown import mode %q (want all-or-nothing or best-effort)", s)
+	}
+}
+
+// Fields are the book fields a CSV column can map to. A book's author is
+// given either as one "author" column, "First Last" or "Last, First", or as
+// separate first and last name columns. "isbn" takes either form of ISBN
+// and "genres" a list of genre names separated by commas or semicolons.
+// "publisher" names a publisher, matched regardless of case and created if
+// there is none of that name.
+var Fields = []string{
+	"title",
+	"author",
+	"author_first_name",
+	"author_last_name",
+	"isbn",
+	"isbn13",
+	"isbn10",
+	"format",
+	"publisher",
+	"page_count",
+	"genres",
+	"published_at",
+	"price",
+	"stock",
+	"reorder_point",
+	"reorder_quantity",
+}
+
+// fieldAliases are other common header names for Fields.
+var fieldAliases = map[string]string{
+	"name":             "title",
+	"author_name":      "author",
+	"first_name":       "author_first_name",
+	"last_name":        "author_last_name",
+	"genre":            "genres",
+	"published":        "published_at",
+	"publication_date": "published_at",
+	"quantity":         "stock",
+	"pages":            "page_count",
+}
+
+// ParseColumnMap parses header mappings of the form "Header=field", several
+// of which may be given in one spec separated by commas.
+func ParseColumnMap(specs ...string) (map[string]string, error) {
+	columns := make(map[string]string)
+	for _, spec := range specs {
+		for _, pair := range strings.Split(spec, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			header, field, ok := strings.Cut(pair, "=")
+			header, field = strings.TrimSpace(header), strings.TrimSpace(field)
+			if !ok || header == "" || field == "" {
+				return nil, fmt.Errorf("invalid column mapping %q (want Header=field)", pair)
+			}
+			if f, ok := canonicalField(field); ok {
+				field = f
+			} else {
+				return nil, fmt.Errorf("unknown book field %q in column mapping", field)
+			}
+			columns[normalizeHeader(header)] = field
+		}
+	}
+	return columns, nil
+}
+
+type Options struct {
+	DryRun bool
+	Mode   Mode
+	// Columns maps CSV headers to Fields, for headers that do not already
+	// name one.
+	Columns map[string]string
+}
+
+const (
+	StatusCreated = "created"
+	StatusValid   = "valid"
+	StatusInvalid = "invalid"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// RowResult is the outcome of one CSV row. Line is its line in the file.
+// NewAuthor is set when the row's author did not exist and was, or on a
+// dry run would be, created, and NewPublisher likewise for its publisher.
+type RowResult struct {
+	Line         int      `json:"line"`
+	Title        string   `json:"title,omitempty"`
+	Status       string   `json:"status"`
+	BookID       int      `json:"book_id,omitempty"`
+	AuthorID     int      `json:"author_id,omitempty"`
+	NewAuthor    bool     `json:"new_author,omitempty"`
+	PublisherID  int      `json:"publisher_id,omitempty"`
+	NewPublisher bool     `json:"new_publisher,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+type Report struct {
+	DryRun            bool              `json:"dry_run"`
+	Mode              Mode              `json:"mode"`
+	Columns           map[string]string `json:"columns"`
+	IgnoredColumns    []string          `json:"ignored_columns,omitempty"`
+	Total             int               `json:"total"`
+	Valid             int               `json:"valid"`
+	Invalid           int               `json:"invalid"`
+	Created           int               `json:"created"`
+	Failed            int               `json:"failed"`
+	AuthorsMatched    int               `json:"authors_matched"`
+	AuthorsCreated    int               `json:"authors_created"`
+	PublishersMatched int               `json:"publishers_matched"`
+	PublishersCreated int               `json:"publishers_created"`
+	Rows              []RowResult       `json:"rows"`
+	Errors            []string          `json:"errors,omitempty"`
+}
@@ -0,0 +1,594 @@
+package bookimport
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/publisher"
+)
+
+var (
+	ErrInvalidCSV  = errors.New("invalid CSV")
+	ErrInvalidRows = errors.New("CSV has invalid rows")
+)
+
+// dateLayouts are the accepted forms of published_at.
+var dateLayouts = []string{time.RFC3339, "2006-01-02", "2006-01", "2006"}
+
+type Service struct {
+	authors    author.AuthorStore
+	publishers publisher.PublisherStore
+	books      book.Service
+}
+
+func NewService(authors author.AuthorStore, publishers publisher.PublisherStore, books book.Service) *Service {
+	return &Service{authors: authors, publishers: publishers, books: books}
+}
+
+func normalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(s)
+}
+
+func canonicalField(name string) (string, bool) {
+	name = normalizeHeader(name)
+	if slices.Contains(Fields, name) {
+		return name, true
+	}
+	f, ok := fieldAliases[name]
+	return f, ok
+}
+
+// row is a CSV record keyed by field.
+type row struct {
+	line   int
+	values map[string]string
+	err    error
+}
+
+func (r row) get(field string) string {
+	return strings.TrimSpace(r.values[field])
+}
+
+// readRows reads the header and every record of a CSV file. Records with the
+// wrong number of fields are returned with err set; other syntax errors
+// reject the whole file.
+func readRows(r io.Reader, columns map[string]string, report *Report) ([]row, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidCSV)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+	}
+
+	report.Columns = make(map[string]string)
+	fields := make([]string, len(header))
+	seen := make(map[string]string)
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\uFEFF")
+		}
+		field, ok := columns[normalizeHeader(name)]
+		if !ok {
+			field, ok = canonicalField(name)
+		}
+		if !ok {
+			report.IgnoredColumns = append(report.IgnoredColumns, name)
+			continue
+		}
+		if other, dup := seen[field]; dup {
+			return nil, fmt.Errorf("%w: columns %q and %q both map to %s", ErrInvalidCSV, other, name, field)
+		}
+		seen[field] = name
+		fields[i] = field
+		report.Columns[name] = field
+	}
+	if _, ok := seen["title"]; !ok {
+		return nil, fmt.Errorf("%w: no title column", ErrInvalidCSV)
+	}
+	if _, ok := seen["author"]; ok {
+		if _, ok := seen["author_first_name"]; ok {
+			return nil, fmt.Errorf("%w: give either an author column or author name columns", ErrInvalidCSV)
+		}
+		if _, ok := seen["author_last_name"]; ok {
+			return nil, fmt.Errorf("%w: give either an author column or author name columns", ErrInvalidCSV)
+		}
+	}
+
+	var rows []row
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		line, _ := cr.FieldPos(0)
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		rw := row{line: line, values: make(map[string]string)}
+		if len(record) != len(header) {
+			rw.err = fmt.Errorf("row has %d fields, header has %d", len(record), len(header))
+		}
+		for i, v := range record {
+			if i < len(fields) && fields[i] != "" {
+				rw.values[fields[i]] = v
+			}
+		}
+		rows = append(rows, rw)
+	}
+}
+
+// splitAuthorName splits "First Last" at its last space, or "Last, First"
+// at the comma. A single name is taken as the last name.
+func splitAuthorName(name string) author.Author {
+	if last, first, ok := strings.Cut(name, ","); ok {
+		return author.Author{FirstName: strings.TrimSpace(first), LastName: strings.TrimSpace(last)}
+	}
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		return author.Author{FirstName: strings.TrimSpace(name[:i]), LastName: name[i+1:]}
+	}
+	return author.Author{LastName: name}
+}
+
+func authorKey(a author.Author) string {
+	return strings.ToLower(a.FirstName) + "\x00" + strings.ToLower(a.LastName)
+}
+
+func publisherKey(name string) string {
+	return strings.ToLower(name)
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("published_at: invalid date %q (want YYYY-MM-DD, YYYY-MM, YYYY or RFC 3339)", s)
+}
+
+// parseRow builds the book and author named by a row, collecting an error
+// for every field that does not parse.
+func parseRow(r row) (book.Book, author.Author, []string) {
+	var errs []string
+	b := book.Book{Title: r.get("title")}
+	if b.Title == "" {
+		errs = append(errs, "title is required")
+	}
+
+	var a author.Author
+	if name := r.get("author"); name != "" {
+		a = splitAuthorName(name)
+	} else {
+		a = author.Author{FirstName: r.get("author_first_name"), LastName: r.get("author_last_name")}
+	}
+
+	count := func(field string) int {
+		v := r.get(field)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: invalid whole number %q", field, v))
+		} else if n < 0 {
+			errs = append(errs, fmt.Sprintf("%s must not be negative", field))
+		}
+		return n
+	}
+
+	e := book.Edition{
+		ISBN13: r.get("isbn13"),
+		ISBN10: r.get("isbn10"),
+	}
+	if v := r.get("price"); v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		switch {
+		case err != nil || math.IsNaN(price) || math.IsInf(price, 0):
+			errs = append(errs, fmt.Sprintf("price: invalid number %q", v))
+		case price < 0:
+			errs = append(errs, "price must not be negative")
+		default:
+			e.Price = price
+		}
+	}
+	e.Stock = count("stock")
+	e.PageCount = count("page_count")
+	b.ReorderPoint = count("reorder_point")
+	b.ReorderQuantity = count("reorder_quantity")
+
+	if v := r.get("format"); v != "" {
+		f, err := book.ParseFormat(strings.ToLower(v))
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		e.Format = f
+	}
+	if v := r.get("isbn"); v != "" {
+		digits := strings.NewReplacer("-", "", " ", "").Replace(v)
+		if len(digits) == 10 {
+			e.ISBN10 = v
+		} else {
+			e.ISBN13 = v
+		}
+	}
+	if v := r.get("published_at"); v != "" {
+		t, err := parseDate(v)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		b.PublishedAt = t
+	}
+
+	b.Editions = []book.Edition{e}
+	if v := r.get("genres"); v != "" {
+		b = b.WithGenreNames(strings.ReplaceAll(v, ";", ","))
+	}
+	return b, a, errs
+}
+
+// plan is a row checked and ready to be written.
+type plan struct {
+	result       *RowResult
+	book         book.Book
+	newAuthor    *author.Author
+	newPublisher *publisher.Publisher
+}
+
+// catalog is what the rows are checked against: the current books,
+// authors and publishers plus the rows read so far.
+type catalog struct {
+	titles            map[string]int
+	isbns             map[string]int
+	authors           map[string]author.Author
+	publishers        map[string]publisher.Publisher
+	pending           map[string]bool
+	pendingPublishers map[string]bool
+}
+
+func (s *Service) loadCatalog(ctx context.Context) (*catalog, error) {
+	books, err := s.books.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, book.ErrNoBooks) {
+		return nil, err
+	}
+	authors, err := s.authors.ListAuthors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	publishers, err := s.publishers.ListPublishers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := &catalog{
+		titles:            make(map[string]int),
+		isbns:             make(map[string]int),
+		authors:           make(map[string]author.Author),
+		publishers:        make(map[string]publisher.Publisher),
+		pending:           make(map[string]bool),
+		pendingPublishers: make(map[string]bool),
+	}
+	for _, b := range books {
+		c.titles[b.Title] = 0
+		for _, e := range b.Editions {
+			if e.ISBN13 != "" {
+				c.isbns[e.ISBN13] = 0
+			}
+		}
+	}
+	for _, a := range authors {
+		if _, ok := c.authors[authorKey(a)]; !ok {
+			c.authors[authorKey(a)] = a
+		}
+	}
+	for _, p := range publishers {
+		c.publishers[publisherKey(p.Name)] = p
+	}
+	return c, nil
+}
+
+// check validates one row the way CreateBook would, and against the
+// catalog, including earlier rows. A row whose author or publisher is new
+// is validated without it, since it gets an ID only when created.
+func (s *Service) check(ctx context.Context, r row, c *catalog, res *RowResult) plan {
+	*res = RowResult{Line: r.line, Status: StatusValid}
+	if r.err != nil {
+		res.Errors = append(res.Errors, r.err.Error())
+	}
+	b, a, errs := parseRow(r)
+	res.Title = b.Title
+	res.Errors = append(res.Errors, errs...)
+	p := plan{result: res}
+
+	if a.FirstName != "" || a.LastName != "" {
+		key := authorKey(a)
+		if existing, ok := c.authors[key]; ok {
+			res.AuthorID = existing.ID
+			b.Author = existing
+		} else {
+			res.NewAuthor = true
+			p.newAuthor = &a
+		}
+	}
+	if name := r.get("publisher"); name != "" {
+		if existing, ok := c.publishers[publisherKey(name)]; ok {
+			res.PublisherID = existing.ID
+			b.PublisherID = existing.ID
+		} else {
+			res.NewPublisher = true
+			p.newPublisher = &publisher.Publisher{Name: name}
+		}
+	}
+
+	if len(res.Errors) == 0 {
+		validated, err := s.books.ValidateBook(ctx, b)
+		if err != nil {
+			res.Errors = append(res.Errors, err.Error())
+		} else {
+			b = validated
+		}
+	}
+
+	if b.Title != "" {
+		if line, ok := c.titles[b.Title]; ok {
+			res.Errors = append(res.Errors, duplicate("title", b.Title, line))
+		} else {
+			c.titles[b.Title] = r.line
+		}
+	}
+	for _, e := range b.Editions {
+		if e.ISBN13 == "" {
+			continue
+		}
+		if line, ok := c.isbns[e.ISBN13]; ok {
+			res.Errors = append(res.Errors, duplicate("ISBN", e.ISBN13, line))
+		} else {
+			c.isbns[e.ISBN13] = r.line
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		res.Status = StatusInvalid
+		res.NewAuthor, res.NewPublisher = false, false
+		return p
+	}
+	if p.newAuthor != nil {
+		c.pending[authorKey(a)] = true
+	}
+	if p.newPublisher != nil {
+		c.pendingPublishers[publisherKey(p.newPublisher.Name)] = true
+	}
+	p.book = b
+	return p
+}
+
+func duplicate(what, value string, line int) string {
+	if line == 0 {
+		return fmt.Sprintf("book with %s %s already exists", what, value)
+	}
+	return fmt.Sprintf("%s %s is already used on line %d", what, value, line)
+}
+
+// Import creates a book, and its author and publisher if none of that name
+// exists, for every row of a CSV file whose header row names the columns. Every row
+// is checked before anything is written; with ModeAllOrNothing any invalid
+// row, or a failed write, leaves the catalog unchanged, while
+// ModeBestEffort imports the rows it can. With opts.DryRun nothing is
+// written at all.
+func (s *Service) Import(ctx context.Context, r io.Reader, opts Options) (Report, error) {
+	if opts.Mode == "" {
+		opts.Mode = ModeAllOrNothing
+	}
+	report := Report{DryRun: opts.DryRun, Mode: opts.Mode, Rows: []RowResult{}}
+
+	rows, err := readRows(r, opts.Columns, &report)
+	if err != nil {
+		return report, err
+	}
+	c, err := s.loadCatalog(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	report.Total = len(rows)
+	report.Rows = make([]RowResult, len(rows))
+	plans := make([]plan, len(rows))
+	for i, rw := range rows {
+		plans[i] = s.check(ctx, rw, c, &report.Rows[i])
+		if report.Rows[i].Status == StatusValid {
+			report.Valid++
+		} else {
+			report.Invalid++
+		}
+		if report.Rows[i].AuthorID != 0 {
+			report.AuthorsMatched++
+		}
+		if report.Rows[i].PublisherID != 0 {
+			report.PublishersMatched++
+		}
+	}
+	report.AuthorsCreated = len(c.pending)
+	report.PublishersCreated = len(c.pendingPublishers)
+
+	if report.Invalid > 0 && opts.Mode == ModeAllOrNothing {
+		if !opts.DryRun {
+			skip(report.Rows)
+			report.AuthorsCreated, report.PublishersCreated = 0, 0
+		}
+		return report, fmt.Errorf("%w: %d of %d rows", ErrInvalidRows, report.Invalid, report.Total)
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+	return report, s.write(ctx, plans, opts.Mode, &report)
+}
+
+// skip marks the rows that were valid, or created and since rolled back, as
+// skipped.
+func skip(rows []RowResult) {
+	for i, res := range rows {
+		if res.Status != StatusValid && res.Status != StatusCreated {
+			continue
+		}
+		rows[i].Status = StatusSkipped
+		rows[i].BookID = 0
+		if res.NewAuthor {
+			rows[i].AuthorID = 0
+		}
+		if res.NewPublisher {
+			rows[i].PublisherID = 0
+		}
+	}
+}
+
+// created maps the keys of the authors and publishers made by an import to
+// their IDs.
+type created struct {
+	authors    map[string]int
+	publishers map[string]int
+}
+
+// rowWrites are the author and publisher a row created, if any.
+type rowWrites struct {
+	authorID    int
+	publisherID int
+}
+
+// write creates the authors, publishers and books of the valid rows in
+// order. In ModeAllOrNothing the first failure deletes everything created
+// so far.
+func (s *Service) write(ctx context.Context, plans []plan, mode Mode, report *Report) error {
+	made := created{authors: make(map[string]int), publishers: make(map[string]int)}
+	var authorIDs, publisherIDs, bookIDs []int
+	defer func() {
+		report.Created = len(bookIDs)
+		report.AuthorsCreated = len(authorIDs)
+		report.PublishersCreated = len(publisherIDs)
+	}()
+
+	for _, p := range plans {
+		res := p.result
+		if res.Status != StatusValid {
+			continue
+		}
+		bookID, w, err := s.writeRow(ctx, p, made)
+		if w.authorID != 0 {
+			authorIDs = append(authorIDs, w.authorID)
+		}
+		if w.publisherID != 0 {
+			publisherIDs = append(publisherIDs, w.publisherID)
+		}
+		if err == nil {
+			res.Status, res.BookID = StatusCreated, bookID
+			bookIDs = append(bookIDs, bookID)
+			continue
+		}
+
+		res.Status = StatusFailed
+		res.Errors = append(res.Errors, err.Error())
+		if res.NewAuthor {
+			res.AuthorID = 0
+		}
+		if res.NewPublisher {
+			res.PublisherID = 0
+		}
+		report.Failed++
+		if mode == ModeBestEffort {
+			if w.authorID != 0 {
+				s.deleteAuthor(ctx, w.authorID, report)
+				delete(made.authors, authorKey(*p.newAuthor))
+				authorIDs = authorIDs[:len(authorIDs)-1]
+			}
+			if w.publisherID != 0 {
+				s.deletePublisher(ctx, w.publisherID, report)
+				delete(made.publishers, publisherKey(p.newPublisher.Name))
+				publisherIDs = publisherIDs[:len(publisherIDs)-1]
+			}
+			continue
+		}
+
+		for _, id := range slices.Backward(bookIDs) {
+			if err := s.books.DeleteBook(ctx, id); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("failed to roll back book %d: %v", id, err))
+			}
+		}
+		for _, id := range slices.Backward(authorIDs) {
+			s.deleteAuthor(ctx, id, report)
+		}
+		for _, id := range slices.Backward(publisherIDs) {
+			s.deletePublisher(ctx, id, report)
+		}
+		bookIDs, authorIDs, publisherIDs = nil, nil, nil
+		skip(report.Rows)
+		return fmt.Errorf("line %d: %w", res.Line, err)
+	}
+	return nil
+}
+
+// writeRow creates the book of p, creating its author and publisher first
+// unless an earlier row already did. w holds those it created, even when
+// creating the book failed.
+func (s *Service) writeRow(ctx context.Context, p plan, made created) (bookID int, w rowWrites, err error) {
+	b := p.book
+	if p.newAuthor != nil {
+		a := *p.newAuthor
+		key := authorKey(a)
+		id, ok := made.authors[key]
+		if !ok {
+			if id, err = s.authors.CreateAuthor(ctx, a); err != nil {
+				return 0, w, fmt.Errorf("failed to create author: %w", err)
+			}
+			made.authors[key] = id
+			w.authorID = id
+		}
+		a.ID = id
+		b.Author, b.Contributors = a, nil
+		p.result.AuthorID = id
+	}
+	if p.newPublisher != nil {
+		key := publisherKey(p.newPublisher.Name)
+		id, ok := made.publishers[key]
+		if !ok {
+			pub, err := s.publishers.CreatePublisher(ctx, *p.newPublisher)
+			if err != nil {
+				return 0, w, fmt.Errorf("failed to create publisher: %w", err)
+			}
+			id = pub.ID
+			made.publishers[key] = id
+			w.publisherID = id
+		}
+		b.PublisherID = id
+		p.result.PublisherID = id
+	}
+	nb, err := s.books.CreateBook(ctx, b)
+	if err != nil {
+		return 0, w, err
+	}
+	return nb.ID, w, nil
+}
+
+func (s *Service) deleteAuthor(ctx context.Context, id int, report *Report) {
+	if err := s.authors.DeleteAuthor(ctx, id); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to roll back author %d: %v", id, err))
+	}
+}
+
+func (s *Service) deletePublisher(ctx context.Context, id int, report *Report) {
+	if err := s.publishers.DeletePublisher(ctx, id); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("failed to roll back publisher %d: %v", id, err))
+	}
+}
@@ -0,0 +1,362 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+const envPrefix = "BOOKSTORE_"
+
+type Config struct {
+	Addr                string        `json:"addr"`
+	ReadTimeout         time.Duration `json:"read_timeout"`
+	WriteTimeout        time.Duration `json:"write_timeout"`
+	OrderTimeout        time.Duration `json:"order_timeout"`
+	ShutdownTimeout     time.Duration `json:"shutdown_timeout"`
+	Store               string        `json:"store"`
+	DataDir             string        `json:"data_dir"`
+	BlobDir             string        `json:"blob_dir"`
+	DatabaseURL         string        `json:"database_url"`
+	JournalSync         string        `json:"journal_sync"`
+	JournalSyncInterval time.Duration `json:"journal_sync_interval"`
+	JournalCompactEvery int           `json:"journal_compact_every"`
+	ReportInterval      time.Duration `json:"report_interval"`
+	AlertInterval       time.Duration `json:"alert_interval"`
+	Currency            string        `json:"currency"`
+	LogLevel            string        `json:"log_level"`
+
+	PrintConfig bool `json:"-"`
+}
+
+func Default() Config {
+	return Config{
+		Addr:                ":8085",
+		ReadTimeout:         10 * time.Second,
+		WriteTimeout:        10 * time.Second,
+		OrderTimeout:        5 * time.Second,
+		ShutdownTimeout:     15 * time.Second,
+		Store:               "memory",
+		DataDir:             "data",
+		JournalSync:         "batched",
+		JournalSyncInterval: time.Second,
+		JournalCompactEvery: 1000,
+		ReportInterval:      24 * time.Hour,
+		AlertInterval:       5 * time.Minute,
+		Currency:            "USD",
+		LogLevel:            "info",
+	}
+}
+
+type option struct {
+	key   string
+	usage string
+	get   func(c *Config) string
+	set   func(c *Config, value string) error
+}
+
+var options = []option{
+	{"addr", "HTTP listen address",
+		func(c *Config) string { return c.Addr },
+		func(c *Config, v string) error { c.Addr = v; return nil }},
+	{"read_timeout", "maximum duration for reading a request",
+		func(c *Config) string { return c.ReadTimeout.String() },
+		durationSetter(func(c *Config) *time.Duration { return &c.ReadTimeout })},
+	{"write_timeout", "maximum duration for writing a response",
+		func(c *Config) string { return c.WriteTimeout.String() },
+		durationSetter(func(c *Config) *time.Duration { return &c.WriteTimeout })},
+	{"order_timeout", "deadline applied to order requests",
+		func(c *Config) string { return c.OrderTimeout.String() },
+		durationSetter(func(c *Config) *time.Duration { return &c.OrderTimeout })},
+	{"shutdown_timeout", "deadline for draining requests on shutdown",
+		func(c *Config) string { return c.ShutdownTimeout.String() },
+		durationSetter(func(c *Config) *time.Duration { return &c.ShutdownTimeout })},
+	{"store", "store backend (memory, file, journal, sql)",
+		func(c *Config) string { return c.Store },
+		func(c *Config, v string) error { c.Store = v; return nil }},
+	{"data_dir", "directory holding the file store data",
+		func(c *Config) string { return c.DataDir },
+		func(c *Config, v string) error { c.DataDir = v; return nil }},
+	{"blob_dir", "directory for uploaded files such as book covers (default: blobs in data_dir, or memory for the memory store)",
+		func(c *Config) string { return c.BlobDir },
+		func(c *Config, v string) error { c.BlobDir = v; return nil }},
+	{"database_url", "PostgreSQL connection string for the sql store",
+		func(c *Config) string { return c.DatabaseURL },
+		func(c *Config, v string) error { c.DatabaseURL = v; return nil }},
+	{"journal_sync", "journal fsync policy (always, batched, never)",
+		func(c *Config) string { return c.JournalSync },
+		func(c *Config, v string) error { c.JournalSync = v; return nil }},
+	{"journal_sync_interval", "interval between fsyncs with the batched policy",
+		func(c *Config) string { return c.JournalSyncInterval.String() },
+		durationSetter(func(c *Config) *time.Duration { return &c.JournalSyncInterval })},
+	{"journal_compact_every", "number of journal records before compacting into a snapshot",
+		func(c *Config) string { return strconv.Itoa(c.JournalCompactEvery) },
+		func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			c.JournalCompactEvery = n
+			return nil
+		}},
+	{"report_interval", "interval between periodic sales reports",
+		func(c *Config) string { return c.ReportInterval.String() },
+		durationSetter(func(c *Config) *time.Duration { return &c.ReportInterval })},
+	{"alert_interval", "interval between low-stock checks",
+		func(c *Config) string { return c.AlertInterval.String() },
+		durationSetter(func(c *Config) *time.Duration { return &c.AlertInterval })},
+	{"currency", "ISO 4217 code of the currency prices are in, for catalog feeds",
+		func(c *Config) string { return c.Currency },
+		func(c *Config, v string) error { c.Currency = v; return nil }},
+	{"log_level", "log level (debug, info, warn, error)",
+		func(c *Config) string { return c.LogLevel },
+		func(c *Config, v string) error { c.LogLevel = v; return nil }},
+}
+
+func durationSetter(field func(c *Config) *time.Duration) func(c *Config, v string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = d
+		return nil
+	}
+}
+
+func lookupOption(key string) (option, bool) {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	for _, opt := range options {
+		if opt.key == key {
+			return opt, true
+		}
+	}
+	return option{}, false
+}
+
+func (c *Config) set(key, value string) error {
+	opt, ok := lookupOption(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	if err := opt.set(c, value); err != nil {
+		return fmt.Errorf("invalid value %q for %s: %w", value, opt.key, err)
+	}
+	return nil
+}
+
+// Load builds the configuration from defaults, then the optional config file,
+// then BOOKSTORE_* environment variables, then command-line flags.
+func Load(name string, args []string) (Config, error) {
+	cfg, _, err := LoadWithFlags(name, args, nil)
+	return cfg, err
+}
+
+// LoadWithFlags is Load for subcommands: extra registers command-specific
+// flags on the same flag set, and the remaining positional arguments are
+// returned.
+func LoadWithFlags(name string, args []string, extra func(fs *flag.FlagSet)) (Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if extra != nil {
+		extra(fs)
+	}
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "path to a JSON or key: value config file")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	flagValues := make(map[string]*string, len(options))
+	for _, opt := range options {
+		flagName := strings.ReplaceAll(opt.key, "_", "-")
+		flagValues[flagName] = fs.String(flagName, "", fmt.Sprintf("%s (default %q)", opt.usage, opt.get(&cfg)))
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return Config{}, nil, err
+		}
+	}
+
+	for _, opt := range options {
+		if v, ok := os.LookupEnv(envPrefix + strings.ToUpper(opt.key)); ok {
+			if err := cfg.set(opt.key, v); err != nil {
+				return Config{}, nil, fmt.Errorf("env %s%s: %w", envPrefix, strings.ToUpper(opt.key), err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		v, ok := flagValues[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		if err := cfg.set(f.Name, *v); err != nil {
+			flagErr = fmt.Errorf("flag -%s: %w", f.Name, err)
+		}
+	})
+	if flagErr != nil {
+		return Config{}, nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		// Numbers are kept as written, since a float64 such as 1000000
+		// would print as 1e+06 and fail to parse as an integer.
+		dec := json.NewDecoder(f)
+		dec.UseNumber()
+		var values map[string]any
+		if err := dec.Decode(&values); err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		for key, v := range values {
+			if err := c.set(key, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sep := strings.IndexAny(line, ":=")
+		if sep < 0 {
+			return fmt.Errorf("%s:%d: expected \"key: value\"", path, lineNo)
+		}
+		key, value := line[:sep], line[sep+1:]
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if err := c.set(strings.TrimSpace(key), value); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Config) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("addr must not be empty")
+	}
+	if c.ReadTimeout <= 0 || c.WriteTimeout <= 0 || c.OrderTimeout <= 0 || c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("timeouts must be positive")
+	}
+	if c.ReportInterval <= 0 {
+		return fmt.Errorf("report_interval must be positive")
+	}
+	if c.AlertInterval <= 0 {
+		return fmt.Errorf("alert_interval must be positive")
+	}
+	if !isCurrencyCode(c.Currency) {
+		return fmt.Errorf("currency %q is not an ISO 4217 code", c.Currency)
+	}
+	switch c.Store {
+	case "memory":
+	case "file":
+		if c.DataDir == "" {
+			return fmt.Errorf("data_dir is required for the %s store", c.Store)
+		}
+	case "journal":
+		if c.DataDir == "" {
+			return fmt.Errorf("data_dir is required for the %s store", c.Store)
+		}
+		if _, err := journal.ParseSyncPolicy(c.JournalSync); err != nil {
+			return err
+		}
+		if c.JournalSyncInterval <= 0 || c.JournalCompactEvery < 0 {
+			return fmt.Errorf("journal_sync_interval must be positive and journal_compact_every not negative")
+		}
+	case "sql":
+		if c.DatabaseURL == "" {
+			return fmt.Errorf("database_url is required for the sql store")
+		}
+	default:
+		return fmt.Errorf("unknown store backend %q", c.Store)
+	}
+	if _, err := c.SlogLevel(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c Config) JournalOptions() journal.Options {
+	policy, _ := journal.ParseSyncPolicy(c.JournalSync)
+	return journal.Options{
+		Sync:         policy,
+		SyncInterval: c.JournalSyncInterval,
+		CompactEvery: c.JournalCompactEvery,
+	}
+}
+
+func (c Config) SlogLevel() (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return 0, fmt.Errorf("invalid log_level %q", c.LogLevel)
+	}
+	return level, nil
+}
+
+// String renders the configuration in the key: value file format, so the
+// output of --print-config can be fed back through --config.
+func (c Config) String() string {
+	var b strings.Builder
+	for _, opt := range options {
+		value := opt.get(&c)
+		if opt.key == "database_url" {
+			value = redactURL(value)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", opt.key, value)
+	}
+	return b.String()
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return u.String()
+}
@@ -0,0 +1,84 @@
+package customer
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID    int        `json:"next_id"`
+	Customers []Customer `json:"customers"`
+}
+
+func (store *InMemoryCustomerStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	snap := storeSnapshot{NextID: store.nextID, Customers: make([]Customer, 0, len(store.customers))}
+	for _, c := range store.customers {
+		snap.Customers = append(snap.Customers, c)
+	}
+	return snap
+}
+
+func (store *InMemoryCustomerStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.customers = make(map[int]Customer, len(snap.Customers))
+	store.nextID = max(snap.NextID, 1)
+	for _, c := range snap.Customers {
+		store.customers[c.ID] = c
+		store.nextID = max(store.nextID, c.ID+1)
+	}
+}
+
+type FileCustomerStore struct {
+	*InMemoryCustomerStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileCustomerStore(dir string) (*FileCustomerStore, error) {
+	mem := NewCustomerStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "customers.json"), "customers", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileCustomerStore{InMemoryCustomerStore: mem, file: file}, nil
+}
+
+func (store *FileCustomerStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileCustomerStore) CreateCustomer(ctx context.Context, customer *Customer) (Customer, error) {
+	created, err := store.InMemoryCustomerStore.CreateCustomer(ctx, customer)
+	if err != nil {
+		return Customer{}, err
+	}
+	return created, store.file.Save()
+}
+
+func (store *FileCustomerStore) UpdateCustomer(ctx context.Context, id int, customer *Customer) (Customer, error) {
+	updated, err := store.InMemoryCustomerStore.UpdateCustomer(ctx, id, customer)
+	if err != nil {
+		return Customer{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FileCustomerStore) DeleteCustomer(ctx context.Context, id int) error {
+	if err := store.InMemoryCustomerStore.DeleteCustomer(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileCustomerStore) RestoreCustomer(ctx context.Context, customer Customer) error {
+	if err := store.InMemoryCustomerStore.RestoreCustomer(ctx, customer); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
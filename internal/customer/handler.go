@@ -3,9 +3,9 @@ package customer
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
-	"um6p.ma/final_project/pkg/error"
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
 )
 
 func NewCustomerStore() *InMemoryCustomerStore {
@@ -15,87 +15,101 @@ func NewCustomerStore() *InMemoryCustomerStore {
 	}
 }
 
-var customerStore = NewCustomerStore()
-
-func CustomersHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	switch r.Method {
-	case http.MethodPost:
-		var customer Customer
-		if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-			error.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
-
-		createdCustomer, err := customerStore.CreateCustomer(ctx, &customer)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(createdCustomer)
-
-	case http.MethodGet:
-		customers, err := customerStore.GetAllCustomers(ctx)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(customers)
-
-	default:
-		error.WriteJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+type Handler struct {
+	store CustomerStore
+}
+
+func NewHandler(store CustomerStore) *Handler {
+	return &Handler{store: store}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/customers", h.ListCustomers)
+	r.HandleFunc(http.MethodPost, "/customers", h.CreateCustomer)
+	r.HandleFunc(http.MethodGet, "/customers/{id}", h.GetCustomer)
+	r.HandleFunc(http.MethodPut, "/customers/{id}", h.UpdateCustomer)
+	r.HandleFunc(http.MethodDelete, "/customers/{id}", h.DeleteCustomer)
+}
+
+func (h *Handler) CreateCustomer(w http.ResponseWriter, r *http.Request) {
+	var customer Customer
+	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	createdCustomer, err := h.store.CreateCustomer(r.Context(), &customer)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdCustomer)
+}
+
+func (h *Handler) ListCustomers(w http.ResponseWriter, r *http.Request) {
+	customers, err := h.store.GetAllCustomers(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customers)
+}
+
+func (h *Handler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	customer, err := h.store.GetCustomerByID(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(customer)
 }
 
-func CustomerHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	id, err := strconv.Atoi(r.URL.Path[len("/customers/"):])
+func (h *Handler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	var updatedData Customer
+	if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+	updatedData.ID = id
+	updatedCustomer, err := h.store.UpdateCustomer(r.Context(), id, &updatedData)
 	if err != nil {
-		error.WriteJSONError(w, "invalid customer ID", http.StatusBadRequest)
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		customer, err := customerStore.GetCustomerByID(ctx, id)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(customer)
-
-	case http.MethodPut:
-		var updatedData Customer
-		if err := json.NewDecoder(r.Body).Decode(&updatedData); err != nil {
-			error.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
-		updatedData.ID = id
-		updatedCustomer, err := customerStore.UpdateCustomer(ctx, id, &updatedData)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(updatedCustomer)
-
-	case http.MethodDelete:
-		if err := customerStore.DeleteCustomer(ctx, id); err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusNoContent)
-
-	default:
-		error.WriteJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedCustomer)
+}
+
+func (h *Handler) DeleteCustomer(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid customer ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.store.DeleteCustomer(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
 	}
+	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,93 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryCustomerStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var c Customer
+		if err := json.Unmarshal(rec.Data, &c); err != nil {
+			return fmt.Errorf("invalid customer record: %w", err)
+		}
+		store.customers[c.ID] = c
+		store.nextID = max(store.nextID, c.ID+1)
+	case journal.OpDelete:
+		delete(store.customers, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledCustomerStore struct {
+	*InMemoryCustomerStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledCustomerStore(dir string, opts journal.Options) (*JournaledCustomerStore, error) {
+	mem := NewCustomerStore()
+	j, err := journal.OpenStore(dir, "customers", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledCustomerStore{InMemoryCustomerStore: mem, journal: j}, nil
+}
+
+func (store *JournaledCustomerStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledCustomerStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledCustomerStore) CreateCustomer(ctx context.Context, customer *Customer) (Customer, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	created, err := store.InMemoryCustomerStore.CreateCustomer(ctx, customer)
+	if err != nil {
+		return Customer{}, err
+	}
+	return created, store.journal.Record(journal.OpCreate, created.ID, created)
+}
+
+func (store *JournaledCustomerStore) UpdateCustomer(ctx context.Context, id int, customer *Customer) (Customer, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemoryCustomerStore.UpdateCustomer(ctx, id, customer)
+	if err != nil {
+		return Customer{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledCustomerStore) DeleteCustomer(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryCustomerStore.DeleteCustomer(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledCustomerStore) RestoreCustomer(ctx context.Context, customer Customer) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryCustomerStore.RestoreCustomer(ctx, customer); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, customer.ID, customer)
+}
@@ -2,6 +2,7 @@ package customer
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -21,10 +22,14 @@ type Customer struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// ErrNoCustomers is returned by GetAllCustomers when there are no customers.
+var ErrNoCustomers = errors.New("no customers found")
+
 type CustomerStore interface {
 	GetCustomerByID(ctx context.Context, id int) (Customer, error)
 	CreateCustomer(ctx context.Context, customer *Customer) (Customer, error)
 	UpdateCustomer(ctx context.Context, id int, customer *Customer) (Customer, error) // Note: `*Customer`
 	DeleteCustomer(ctx context.Context, id int) error
 	GetAllCustomers(ctx context.Context) ([]Customer, error)
+	RestoreCustomer(ctx context.Context, customer Customer) error
 }
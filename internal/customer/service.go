@@ -3,8 +3,9 @@ package customer
 import (
 	"context"
 	"fmt"
-	"log"
 	"sync"
+
+	"um6p.ma/final_project/pkg/logging"
 )
 
 type InMemoryCustomerStore struct {
@@ -25,7 +26,7 @@ func (store *InMemoryCustomerStore) CreateCustomer(ctx context.Context, customer
 
 	for _, existingCustomer := range store.customers {
 		if existingCustomer.Name == customer.Name {
-			log.Printf("customer with name %s already exists", customer.Name)
+			logging.Printf(ctx, "customer with name %s already exists", customer.Name)
 			return Customer{}, fmt.Errorf("customer with name %s already exists", customer.Name)
 		}
 	}
@@ -49,7 +50,7 @@ func (store *InMemoryCustomerStore) GetCustomerByID(ctx context.Context, id int)
 
 	customer, found := store.customers[id]
 	if !found {
-		log.Printf("customer with ID %d not found", id)
+		logging.Printf(ctx, "customer with ID %d not found", id)
 		return Customer{}, fmt.Errorf("customer with ID %d not found", id)
 	}
 
@@ -84,7 +85,7 @@ func (store *InMemoryCustomerStore) DeleteCustomer(ctx context.Context, id int)
 
 	_, found := store.customers[id]
 	if !found {
-		log.Printf("customer with ID %d not found", id)
+		logging.Printf(ctx, "customer with ID %d not found", id)
 		return fmt.Errorf("customer with ID %d not found", id)
 	}
 
@@ -92,6 +93,25 @@ func (store *InMemoryCustomerStore) DeleteCustomer(ctx context.Context, id int)
 	return nil
 }
 
+// RestoreCustomer stores customer under its own ID, as needed when importing
+// a backup, and moves nextID past it.
+func (store *InMemoryCustomerStore) RestoreCustomer(ctx context.Context, customer Customer) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if customer.ID <= 0 {
+		return fmt.Errorf("invalid customer ID %d", customer.ID)
+	}
+	for id, existingCustomer := range store.customers {
+		if id != customer.ID && existingCustomer.Name == customer.Name {
+			return fmt.Errorf("customer with name %s already exists", customer.Name)
+		}
+	}
+	store.customers[customer.ID] = customer
+	store.nextID = max(store.nextID, customer.ID+1)
+	return nil
+}
+
 func (store *InMemoryCustomerStore) GetAllCustomers(ctx context.Context) ([]Customer, error) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
@@ -108,8 +128,8 @@ func (store *InMemoryCustomerStore) GetAllCustomers(ctx context.Context) ([]Cust
 	}
 
 	if len(all) == 0 {
-		log.Printf("no customers found")
-		return nil, fmt.Errorf("no customers found")
+		logging.Printf(ctx, "no customers found")
+		return nil, ErrNoCustomers
 	}
 
 	return all, nil
@@ -0,0 +1,130 @@
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+const customerColumns = `id, name, email, street, city, state, postal_code, country, created_at`
+
+type SQLCustomerStore struct {
+	db *sql.DB
+}
+
+func NewSQLCustomerStore(db *sql.DB) *SQLCustomerStore {
+	return &SQLCustomerStore{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCustomer(row rowScanner) (Customer, error) {
+	var c Customer
+	err := row.Scan(&c.ID, &c.Name, &c.Email,
+		&c.Address.Street, &c.Address.City, &c.Address.State, &c.Address.PostalCode, &c.Address.Country,
+		&c.CreatedAt)
+	return c, err
+}
+
+func (store *SQLCustomerStore) CreateCustomer(ctx context.Context, customer *Customer) (Customer, error) {
+	a := customer.Address
+	err := store.db.QueryRowContext(ctx,
+		`INSERT INTO customers (name, email, street, city, state, postal_code, country, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (name) DO NOTHING
+		 RETURNING id`,
+		customer.Name, customer.Email, a.Street, a.City, a.State, a.PostalCode, a.Country, customer.CreatedAt,
+	).Scan(&customer.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Customer{}, fmt.Errorf("customer with name %s already exists", customer.Name)
+	}
+	if err != nil {
+		return Customer{}, fmt.Errorf("failed to create customer: %w", err)
+	}
+	return *customer, nil
+}
+
+func (store *SQLCustomerStore) GetCustomerByID(ctx context.Context, id int) (Customer, error) {
+	c, err := scanCustomer(store.db.QueryRowContext(ctx,
+		`SELECT `+customerColumns+` FROM customers WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Customer{}, fmt.Errorf("customer with ID %d not found", id)
+	}
+	if err != nil {
+		return Customer{}, fmt.Errorf("failed to get customer %d: %w", id, err)
+	}
+	return c, nil
+}
+
+func (store *SQLCustomerStore) UpdateCustomer(ctx context.Context, id int, customer *Customer) (Customer, error) {
+	a := customer.Address
+	c, err := scanCustomer(store.db.QueryRowContext(ctx,
+		`UPDATE customers
+		 SET name = $2, email = $3, street = $4, city = $5, state = $6, postal_code = $7, country = $8
+		 WHERE id = $1
+		 RETURNING `+customerColumns,
+		id, customer.Name, customer.Email, a.Street, a.City, a.State, a.PostalCode, a.Country))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Customer{}, fmt.Errorf("customer with ID %d not found", id)
+	}
+	if err != nil {
+		return Customer{}, fmt.Errorf("failed to update customer %d: %w", id, err)
+	}
+	return c, nil
+}
+
+func (store *SQLCustomerStore) DeleteCustomer(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM customers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete customer %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("customer with ID %d not found", id)
+	}
+	return nil
+}
+
+func (store *SQLCustomerStore) GetAllCustomers(ctx context.Context) ([]Customer, error) {
+	rows, err := store.db.QueryContext(ctx, `SELECT `+customerColumns+` FROM customers ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customers: %w", err)
+	}
+	defer rows.Close()
+
+	var all []Customer
+	for rows.Next() {
+		c, err := scanCustomer(rows)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(all) == 0 {
+		return nil, ErrNoCustomers
+	}
+	return all, nil
+}
+
+func (store *SQLCustomerStore) RestoreCustomer(ctx context.Context, customer Customer) error {
+	a := customer.Address
+	err := sqlutil.RestoreRow(ctx, store.db, "customers",
+		`INSERT INTO customers (id, name, email, street, city, state, postal_code, country, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 ON CONFLICT (id) DO UPDATE
+		 SET name = EXCLUDED.name, email = EXCLUDED.email, street = EXCLUDED.street, city = EXCLUDED.city,
+		     state = EXCLUDED.state, postal_code = EXCLUDED.postal_code, country = EXCLUDED.country,
+		     created_at = EXCLUDED.created_at`,
+		customer.ID, customer.Name, customer.Email, a.Street, a.City, a.State, a.PostalCode, a.Country, customer.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to restore customer %d: %w", customer.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package genre
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID int     `json:"next_id"`
+	Genres []Genre `json:"genres"`
+}
+
+func (store *InMemoryGenreStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	snap := storeSnapshot{NextID: store.nextID, Genres: make([]Genre, 0, len(store.genres))}
+	for _, g := range store.genres {
+		snap.Genres = append(snap.Genres, g)
+	}
+	return snap
+}
+
+func (store *InMemoryGenreStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.genres = make(map[int]Genre, len(snap.Genres))
+	store.nextID = max(snap.NextID, 1)
+	for _, g := range snap.Genres {
+		store.genres[g.ID] = g
+		store.nextID = max(store.nextID, g.ID+1)
+	}
+}
+
+type FileGenreStore struct {
+	*InMemoryGenreStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileStore(dir string) (*FileGenreStore, error) {
+	mem := NewStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "genres.json"), "genres", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileGenreStore{InMemoryGenreStore: mem, file: file}, nil
+}
+
+func (store *FileGenreStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileGenreStore) CreateGenre(ctx context.Context, g Genre) (Genre, error) {
+	created, err := store.InMemoryGenreStore.CreateGenre(ctx, g)
+	if err != nil {
+		return Genre{}, err
+	}
+	return created, store.file.Save()
+}
+
+func (store *FileGenreStore) UpdateGenre(ctx context.Context, id int, g Genre) (Genre, error) {
+	updated, err := store.InMemoryGenreStore.UpdateGenre(ctx, id, g)
+	if err != nil {
+		return Genre{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FileGenreStore) DeleteGenre(ctx context.Context, id int) error {
+	if err := store.InMemoryGenreStore.DeleteGenre(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileGenreStore) RestoreGenre(ctx context.Context, g Genre) error {
+	if err := store.InMemoryGenreStore.RestoreGenre(ctx, g); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
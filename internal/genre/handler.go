@@ -0,0 +1,137 @@
+package genre
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+)
+
+func NewStore() *InMemoryGenreStore {
+	return &InMemoryGenreStore{
+		genres: make(map[int]Genre),
+		nextID: 1,
+	}
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/genres", h.ListGenres)
+	r.HandleFunc(http.MethodPost, "/genres", h.CreateGenre)
+	r.HandleFunc(http.MethodGet, "/genres/tree", h.GetTree)
+	r.HandleFunc(http.MethodGet, "/genres/{id}", h.GetGenre)
+	r.HandleFunc(http.MethodPut, "/genres/{id}", h.UpdateGenre)
+	r.HandleFunc(http.MethodDelete, "/genres/{id}", h.DeleteGenre)
+}
+
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInUse):
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+func (h *Handler) ListGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := h.svc.ListGenres(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(genres)
+}
+
+func (h *Handler) GetTree(w http.ResponseWriter, r *http.Request) {
+	tree, err := h.svc.GetTree(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tree == nil {
+		tree = []*Node{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+func (h *Handler) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	var g Genre
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.svc.CreateGenre(r.Context(), g)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) GetGenre(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	g, err := h.svc.GetGenre(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g)
+}
+
+func (h *Handler) UpdateGenre(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	var g Genre
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.svc.UpdateGenre(r.Context(), id, g)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteGenre(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid genre ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteGenre(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
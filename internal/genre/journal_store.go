@@ -0,0 +1,93 @@
+package genre
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryGenreStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var g Genre
+		if err := json.Unmarshal(rec.Data, &g); err != nil {
+			return fmt.Errorf("invalid genre record: %w", err)
+		}
+		store.genres[g.ID] = g
+		store.nextID = max(store.nextID, g.ID+1)
+	case journal.OpDelete:
+		delete(store.genres, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledGenreStore struct {
+	*InMemoryGenreStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledStore(dir string, opts journal.Options) (*JournaledGenreStore, error) {
+	mem := NewStore()
+	j, err := journal.OpenStore(dir, "genres", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledGenreStore{InMemoryGenreStore: mem, journal: j}, nil
+}
+
+func (store *JournaledGenreStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledGenreStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledGenreStore) CreateGenre(ctx context.Context, g Genre) (Genre, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	created, err := store.InMemoryGenreStore.CreateGenre(ctx, g)
+	if err != nil {
+		return Genre{}, err
+	}
+	return created, store.journal.Record(journal.OpCreate, created.ID, created)
+}
+
+func (store *JournaledGenreStore) UpdateGenre(ctx context.Context, id int, g Genre) (Genre, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemoryGenreStore.UpdateGenre(ctx, id, g)
+	if err != nil {
+		return Genre{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledGenreStore) DeleteGenre(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryGenreStore.DeleteGenre(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledGenreStore) RestoreGenre(ctx context.Context, g Genre) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryGenreStore.RestoreGenre(ctx, g); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, g.ID, g)
+}
@@ -0,0 +1,136 @@
+package genre
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNotFound = errors.New("genre not found")
+	ErrInUse    = errors.New("genre in use")
+)
+
+// Genre is a node in the genre tree. Root genres have no ParentID.
+type Genre struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	ParentID int    `json:"parent_id,omitempty"`
+}
+
+// Node is a genre with its subgenres, as returned by GET /genres/tree.
+type Node struct {
+	Genre
+	Children []*Node `json:"children,omitempty"`
+}
+
+type GenreStore interface {
+	CreateGenre(ctx context.Context, g Genre) (Genre, error)
+	GetGenre(ctx context.Context, id int) (Genre, error)
+	UpdateGenre(ctx context.Context, id int, g Genre) (Genre, error)
+	DeleteGenre(ctx context.Context, id int) error
+	ListGenres(ctx context.Context) ([]Genre, error)
+	RestoreGenre(ctx context.Context, g Genre) error
+}
+
+// BookCounter reports how many books are filed under a genre, so genres
+// that are still used cannot be deleted.
+type BookCounter interface {
+	CountBooksInGenre(ctx context.Context, genreID int) (int, error)
+}
+
+// Descendants returns id followed by the IDs of all genres below it.
+func Descendants(all []Genre, id int) []int {
+	children := make(map[int][]int)
+	for _, g := range all {
+		children[g.ParentID] = append(children[g.ParentID], g.ID)
+	}
+	out := []int{id}
+	for i := 0; i < len(out); i++ {
+		out = append(out, children[out[i]]...)
+	}
+	return out
+}
+
+// Path returns the names from the root down to id, e.g. Fiction, Fantasy,
+// Epic. Unknown IDs yield nil.
+func Path(all []Genre, id int) []string {
+	byID := make(map[int]Genre, len(all))
+	for _, g := range all {
+		byID[g.ID] = g
+	}
+	var path []string
+	for seen := 0; id != 0 && seen <= len(all); seen++ {
+		g, ok := byID[id]
+		if !ok {
+			break
+		}
+		path = append([]string{g.Name}, path...)
+		id = g.ParentID
+	}
+	return path
+}
+
+// Find resolves ref, either a numeric ID or a case-insensitive name, to the
+// genres it names. A name can match genres under different parents.
+func Find(all []Genre, ref string) []Genre {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, g := range all {
+			if g.ID == id {
+				return []Genre{g}
+			}
+		}
+		return nil
+	}
+	var out []Genre
+	for _, g := range all {
+		if strings.EqualFold(g.Name, ref) {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// Tree arranges genres into their hierarchy, with siblings sorted by name.
+func Tree(all []Genre) []*Node {
+	nodes := make(map[int]*Node, len(all))
+	for _, g := range all {
+		nodes[g.ID] = &Node{Genre: g}
+	}
+	var roots []*Node
+	for _, g := range all {
+		n := nodes[g.ID]
+		if parent, ok := nodes[g.ParentID]; ok && g.ParentID != 0 {
+			parent.Children = append(parent.Children, n)
+		} else {
+			roots = append(roots, n)
+		}
+	}
+	var sortNodes func([]*Node)
+	sortNodes = func(ns []*Node) {
+		sort.Slice(ns, func(i, j int) bool { return strings.ToLower(ns[i].Name) < strings.ToLower(ns[j].Name) })
+		for _, n := range ns {
+			sortNodes(n.Children)
+		}
+	}
+	sortNodes(roots)
+	return roots
+}
+
+// ParentsFirst orders genres so that every parent precedes its children,
+// as needed when restoring them into a store that checks references.
+func ParentsFirst(all []Genre) []Genre {
+	var out []Genre
+	var walk func([]*Node)
+	walk = func(ns []*Node) {
+		for _, n := range ns {
+			out = append(out, n.Genre)
+			walk(n.Children)
+		}
+	}
+	walk(Tree(all))
+	return out
+}
@@ -0,0 +1,229 @@
+package genre
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"um6p.ma/final_project/pkg/logging"
+)
+
+type InMemoryGenreStore struct {
+	mu     sync.RWMutex
+	genres map[int]Genre
+	nextID int
+}
+
+// siblingExists reports whether another genre under parentID already uses
+// name. Callers hold the lock.
+func (store *InMemoryGenreStore) siblingExists(id, parentID int, name string) bool {
+	for _, g := range store.genres {
+		if g.ID != id && g.ParentID == parentID && strings.EqualFold(g.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (store *InMemoryGenreStore) CreateGenre(ctx context.Context, g Genre) (Genre, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.siblingExists(0, g.ParentID, g.Name) {
+		logging.Printf(ctx, "genre %s already exists", g.Name)
+		return Genre{}, fmt.Errorf("genre %s already exists", g.Name)
+	}
+	g.ID = store.nextID
+	store.nextID++
+	store.genres[g.ID] = g
+	return g, nil
+}
+
+func (store *InMemoryGenreStore) GetGenre(ctx context.Context, id int) (Genre, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	g, found := store.genres[id]
+	if !found {
+		logging.Printf(ctx, "genre with ID %d not found", id)
+		return Genre{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return g, nil
+}
+
+func (store *InMemoryGenreStore) UpdateGenre(ctx context.Context, id int, g Genre) (Genre, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.genres[id]; !found {
+		logging.Printf(ctx, "genre with ID %d not found", id)
+		return Genre{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if store.siblingExists(id, g.ParentID, g.Name) {
+		return Genre{}, fmt.Errorf("genre %s already exists", g.Name)
+	}
+	g.ID = id
+	store.genres[id] = g
+	return g, nil
+}
+
+func (store *InMemoryGenreStore) DeleteGenre(ctx context.Context, id int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.genres[id]; !found {
+		logging.Printf(ctx, "genre with ID %d not found", id)
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	delete(store.genres, id)
+	return nil
+}
+
+func (store *InMemoryGenreStore) ListGenres(ctx context.Context) ([]Genre, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	all := make([]Genre, 0, len(store.genres))
+	for _, g := range store.genres {
+		all = append(all, g)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all, nil
+}
+
+// RestoreGenre stores g under its own ID and moves nextID past it.
+func (store *InMemoryGenreStore) RestoreGenre(ctx context.Context, g Genre) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if g.ID <= 0 {
+		return fmt.Errorf("invalid genre ID %d", g.ID)
+	}
+	store.genres[g.ID] = g
+	store.nextID = max(store.nextID, g.ID+1)
+	return nil
+}
+
+// FindOrCreateRoot returns the top-level genre called name, creating it if
+// needed. It is used to migrate books that still carry a plain genre string.
+func FindOrCreateRoot(ctx context.Context, store GenreStore, name string) (Genre, error) {
+	all, err := store.ListGenres(ctx)
+	if err != nil {
+		return Genre{}, err
+	}
+	for _, g := range all {
+		if g.ParentID == 0 && strings.EqualFold(g.Name, name) {
+			return g, nil
+		}
+	}
+	return store.CreateGenre(ctx, Genre{Name: name})
+}
+
+type Service interface {
+	CreateGenre(ctx context.Context, g Genre) (Genre, error)
+	GetGenre(ctx context.Context, id int) (Genre, error)
+	UpdateGenre(ctx context.Context, id int, g Genre) (Genre, error)
+	DeleteGenre(ctx context.Context, id int) error
+	ListGenres(ctx context.Context) ([]Genre, error)
+	GetTree(ctx context.Context) ([]*Node, error)
+}
+
+type service struct {
+	store GenreStore
+	books BookCounter
+}
+
+func NewService(store GenreStore, books BookCounter) Service {
+	return &service{store: store, books: books}
+}
+
+func (s *service) validate(ctx context.Context, id int, g Genre) error {
+	if strings.TrimSpace(g.Name) == "" {
+		return fmt.Errorf("genre name is required")
+	}
+	if g.ParentID == 0 {
+		return nil
+	}
+	all, err := s.store.ListGenres(ctx)
+	if err != nil {
+		return err
+	}
+	if len(Path(all, g.ParentID)) == 0 {
+		return fmt.Errorf("parent genre with ID %d not found", g.ParentID)
+	}
+	if id != 0 {
+		for _, d := range Descendants(all, id) {
+			if d == g.ParentID {
+				return fmt.Errorf("genre %d cannot be moved under its own subgenre %d", id, g.ParentID)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *service) CreateGenre(ctx context.Context, g Genre) (Genre, error) {
+	g.Name = strings.TrimSpace(g.Name)
+	if err := s.validate(ctx, 0, g); err != nil {
+		return Genre{}, err
+	}
+	return s.store.CreateGenre(ctx, g)
+}
+
+func (s *service) GetGenre(ctx context.Context, id int) (Genre, error) {
+	return s.store.GetGenre(ctx, id)
+}
+
+func (s *service) UpdateGenre(ctx context.Context, id int, g Genre) (Genre, error) {
+	g.Name = strings.TrimSpace(g.Name)
+	if _, err := s.store.GetGenre(ctx, id); err != nil {
+		return Genre{}, err
+	}
+	if err := s.validate(ctx, id, g); err != nil {
+		return Genre{}, err
+	}
+	return s.store.UpdateGenre(ctx, id, g)
+}
+
+// DeleteGenre refuses to delete genres that still have subgenres or books.
+func (s *service) DeleteGenre(ctx context.Context, id int) error {
+	all, err := s.store.ListGenres(ctx)
+	if err != nil {
+		return err
+	}
+	if len(Path(all, id)) == 0 {
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if n := len(Descendants(all, id)) - 1; n > 0 {
+		return fmt.Errorf("%w: genre %d has %d subgenre(s)", ErrInUse, id, n)
+	}
+	if s.books != nil {
+		n, err := s.books.CountBooksInGenre(ctx, id)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w: genre %d is assigned to %d book(s)", ErrInUse, id, n)
+		}
+	}
+	return s.store.DeleteGenre(ctx, id)
+}
+
+func (s *service) ListGenres(ctx context.Context) ([]Genre, error) {
+	return s.store.ListGenres(ctx)
+}
+
+func (s *service) GetTree(ctx context.Context) ([]*Node, error) {
+	all, err := s.store.ListGenres(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Tree(all), nil
+}
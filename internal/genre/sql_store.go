@@ -0,0 +1,103 @@
+package genre
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+type SQLGenreStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLGenreStore {
+	return &SQLGenreStore{db: db}
+}
+
+func (store *SQLGenreStore) CreateGenre(ctx context.Context, g Genre) (Genre, error) {
+	err := store.db.QueryRowContext(ctx,
+		`INSERT INTO genres (name, parent_id) VALUES ($1, $2)
+		 ON CONFLICT DO NOTHING
+		 RETURNING id`,
+		g.Name, sqlutil.NullID(g.ParentID),
+	).Scan(&g.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Genre{}, fmt.Errorf("genre %s already exists", g.Name)
+	}
+	if err != nil {
+		return Genre{}, fmt.Errorf("failed to create genre: %w", err)
+	}
+	return g, nil
+}
+
+func (store *SQLGenreStore) GetGenre(ctx context.Context, id int) (Genre, error) {
+	var g Genre
+	err := store.db.QueryRowContext(ctx,
+		`SELECT id, name, COALESCE(parent_id, 0) FROM genres WHERE id = $1`, id,
+	).Scan(&g.ID, &g.Name, &g.ParentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Genre{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if err != nil {
+		return Genre{}, fmt.Errorf("failed to get genre %d: %w", id, err)
+	}
+	return g, nil
+}
+
+func (store *SQLGenreStore) UpdateGenre(ctx context.Context, id int, g Genre) (Genre, error) {
+	res, err := store.db.ExecContext(ctx,
+		`UPDATE genres SET name = $2, parent_id = $3 WHERE id = $1`,
+		id, g.Name, sqlutil.NullID(g.ParentID),
+	)
+	if err != nil {
+		return Genre{}, fmt.Errorf("failed to update genre %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Genre{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	g.ID = id
+	return g, nil
+}
+
+func (store *SQLGenreStore) DeleteGenre(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM genres WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete genre %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+func (store *SQLGenreStore) ListGenres(ctx context.Context) ([]Genre, error) {
+	rows, err := store.db.QueryContext(ctx, `SELECT id, name, COALESCE(parent_id, 0) FROM genres ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list genres: %w", err)
+	}
+	defer rows.Close()
+
+	genres := make([]Genre, 0)
+	for rows.Next() {
+		var g Genre
+		if err := rows.Scan(&g.ID, &g.Name, &g.ParentID); err != nil {
+			return nil, err
+		}
+		genres = append(genres, g)
+	}
+	return genres, rows.Err()
+}
+
+func (store *SQLGenreStore) RestoreGenre(ctx context.Context, g Genre) error {
+	err := sqlutil.RestoreRow(ctx, store.db, "genres",
+		`INSERT INTO genres (id, name, parent_id) VALUES ($1, $2, $3)
+		 ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, parent_id = EXCLUDED.parent_id`,
+		g.ID, g.Name, sqlutil.NullID(g.ParentID))
+	if err != nil {
+		return fmt.Errorf("failed to restore genre %d: %w", g.ID, err)
+	}
+	return nil
+}
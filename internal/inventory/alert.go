@@ -0,0 +1,35 @@
+package inventory
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Alert reports a book whose stock has fallen below its reorder point.
+// RaisedAt is when the check first saw it low; the alert stays active until
+// the stock is back at or above the reorder point.
+type Alert struct {
+	BookID          int       `json:"book_id"`
+	Title           string    `json:"title"`
+	Stock           int       `json:"stock"`
+	ReorderPoint    int       `json:"reorder_point"`
+	ReorderQuantity int       `json:"reorder_quantity"`
+	RaisedAt        time.Time `json:"raised_at"`
+}
+
+// Notifier delivers alerts as they are raised. Each alert is delivered once
+// per shortage, though a restart forgets which ones were already sent.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// LogNotifier writes alerts to the default logger.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, a Alert) error {
+	slog.WarnContext(ctx, "book below reorder point",
+		"book_id", a.BookID, "title", a.Title, "stock", a.Stock,
+		"reorder_point", a.ReorderPoint, "reorder_quantity", a.ReorderQuantity)
+	return nil
+}
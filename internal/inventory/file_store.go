@@ -0,0 +1,65 @@
+package inventory
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID    int        `json:"next_id"`
+	Movements []Movement `json:"movements"`
+}
+
+func (store *InMemoryMovementStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	return storeSnapshot{NextID: store.nextID, Movements: slices.Clone(store.movements)}
+}
+
+func (store *InMemoryMovementStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.movements = slices.Clone(snap.Movements)
+	store.nextID = max(snap.NextID, 1)
+	for _, m := range snap.Movements {
+		store.nextID = max(store.nextID, m.ID+1)
+	}
+}
+
+type FileMovementStore struct {
+	*InMemoryMovementStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileStore(dir string) (*FileMovementStore, error) {
+	mem := NewStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "stock_movements.json"), "stock movements", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileMovementStore{InMemoryMovementStore: mem, file: file}, nil
+}
+
+func (store *FileMovementStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileMovementStore) RecordMovement(ctx context.Context, m Movement) (Movement, error) {
+	recorded, err := store.InMemoryMovementStore.RecordMovement(ctx, m)
+	if err != nil {
+		return Movement{}, err
+	}
+	return recorded, store.file.Save()
+}
+
+func (store *FileMovementStore) RestoreMovement(ctx context.Context, m Movement) error {
+	if err := store.InMemoryMovementStore.RestoreMovement(ctx, m); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
@@ -0,0 +1,79 @@
+package inventory
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+)
+
+func NewStore() *InMemoryMovementStore {
+	return &InMemoryMovementStore{nextID: 1}
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodPost, "/books/{id}/stock/adjustments", h.AdjustStock)
+	r.HandleFunc(http.MethodGet, "/books/{id}/stock/movements", h.ListMovements)
+	r.HandleFunc(http.MethodGet, "/inventory/alerts", h.ListAlerts)
+}
+
+func (h *Handler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	var a Adjustment
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.svc.AdjustStock(r.Context(), id, a)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(m)
+}
+
+func (h *Handler) ListMovements(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+
+	movements, err := h.svc.ListMovements(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
+
+func (h *Handler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	alerts, err := h.svc.ActiveAlerts(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(alerts)
+}
@@ -0,0 +1,212 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"um6p.ma/final_project/internal/book"
+)
+
+// newTracked returns a tracked store holding one book per stock level, with
+// their opening balances recorded.
+func newTracked(t *testing.T, stocks ...int) (*TrackedBookStore, *InMemoryMovementStore, []book.Book) {
+	t.Helper()
+	movements := NewStore()
+	s := NewTrackedBookStore(book.NewStore(), movements)
+	var books []book.Book
+	for i, stock := range stocks {
+		b, err := s.CreateBook(context.Background(), book.Book{Title: string(rune('A' + i)), Price: 10, Stock: stock})
+		if err != nil {
+			t.Fatal(err)
+		}
+		books = append(books, b)
+	}
+	return s, movements, books
+}
+
+// stockOf returns the stock of the default edition of book id.
+func stockOf(t *testing.T, s book.BookStore, id int) int {
+	t.Helper()
+	b, err := s.GetBook(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Editions[0].Stock
+}
+
+// checkLedger fails unless the ledger of every book adds up to its stock.
+func checkLedger(t *testing.T, s book.BookStore, movements MovementStore, books []book.Book) {
+	t.Helper()
+	for _, b := range books {
+		ledger, err := movements.ListMovements(context.Background(), b.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := 0
+		for _, m := range ledger {
+			sum += m.Delta
+		}
+		if stock := stockOf(t, s, b.ID); sum != stock {
+			t.Errorf("ledger of book %d adds up to %d, stock is %d", b.ID, sum, stock)
+		}
+	}
+}
+
+func TestAdjustmentDelta(t *testing.T) {
+	tests := []struct {
+		a       Adjustment
+		want    int
+		wantErr bool
+	}{
+		{Adjustment{Reason: ReasonReceive, Quantity: 4}, 4, false},
+		{Adjustment{Reason: ReasonReturn, Quantity: 1}, 1, false},
+		{Adjustment{Reason: ReasonDamage, Quantity: 2}, -2, false},
+		{Adjustment{Reason: ReasonCorrection, Quantity: -3}, -3, false},
+		{Adjustment{Reason: ReasonCorrection, Quantity: 0}, 0, true},
+		{Adjustment{Reason: ReasonReceive, Quantity: -1}, 0, true},
+		{Adjustment{Reason: ReasonSale, Quantity: 1}, 0, true},
+		{Adjustment{Reason: "lost", Quantity: 1}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Delta()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%+v.Delta() = %d, %v; want %d, error %v", tt.a, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTrackedStoreRecordsEdits(t *testing.T) {
+	ctx := context.Background()
+	s, movements, books := newTracked(t, 5)
+	id := books[0].ID
+
+	edited := books[0]
+	edited.Editions = slices.Clone(edited.Editions)
+	edited.Editions[0].Stock = 8
+	if _, err := s.UpdateBook(ctx, id, edited); err != nil {
+		t.Fatal(err)
+	}
+
+	ledger, _ := movements.ListMovements(ctx, id)
+	if len(ledger) != 2 {
+		t.Fatalf("got %d movements, want an opening balance and a correction", len(ledger))
+	}
+	if m := ledger[0]; m.Reason != ReasonOpening || m.Delta != 5 || m.StockAfter != 5 {
+		t.Errorf("first movement = %+v, want an opening balance of 5", m)
+	}
+	if m := ledger[1]; m.Reason != ReasonCorrection || m.Delta != 3 || m.StockAfter != 8 {
+		t.Errorf("second movement = %+v, want a correction of 3 leaving 8", m)
+	}
+	checkLedger(t, s, movements, books)
+}
+
+func TestApplyMovements(t *testing.T) {
+	ctx := context.Background()
+	s, movements, books := newTracked(t, 5, 2)
+	a, b := books[0].ID, books[1].ID
+
+	applied, err := s.ApplyMovements(ctx, []Movement{
+		{BookID: a, Reason: ReasonSale, Delta: -2},
+		{BookID: a, Reason: ReasonSale, Delta: -3},
+		{BookID: b, Reason: ReasonReceive, Delta: 4},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAfter := []int{3, 0, 6}
+	for i, m := range applied {
+		if m.ID == 0 || m.EditionID == 0 || m.StockAfter != wantAfter[i] {
+			t.Errorf("movement %d = %+v, want it recorded with stock after %d", i, m, wantAfter[i])
+		}
+	}
+	if got := stockOf(t, s, a); got != 0 {
+		t.Errorf("stock of book %d = %d, want 0", a, got)
+	}
+	if got := stockOf(t, s, b); got != 6 {
+		t.Errorf("stock of book %d = %d, want 6", b, got)
+	}
+	checkLedger(t, s, movements, books)
+}
+
+func TestApplyMovementsInsufficientStock(t *testing.T) {
+	ctx := context.Background()
+	s, movements, books := newTracked(t, 5, 1)
+	a, b := books[0].ID, books[1].ID
+
+	_, err := s.ApplyMovements(ctx, []Movement{
+		{BookID: a, Reason: ReasonSale, Delta: -2},
+		{BookID: b, Reason: ReasonSale, Delta: -2},
+	})
+	if err == nil {
+		t.Fatal("ApplyMovements took more stock than there is")
+	}
+	if got := stockOf(t, s, a); got != 5 {
+		t.Errorf("stock of book %d = %d, want it left at 5", a, got)
+	}
+	all, _ := movements.ListAllMovements(ctx)
+	if len(all) != 2 {
+		t.Errorf("got %d movements, want only the 2 opening balances", len(all))
+	}
+}
+
+func TestTakeStock(t *testing.T) {
+	ctx := context.Background()
+	s, movements, books := newTracked(t, 3)
+	id := books[0].ID
+	sale := []Movement{{BookID: id, Reason: ReasonSale, Delta: -2}}
+
+	errSave := errors.New("save failed")
+	_, err := s.TakeStock(ctx, sale, func() (int, error) {
+		if got := stockOf(t, s, id); got != 1 {
+			t.Errorf("stock when saving = %d, want it taken already", got)
+		}
+		return 0, errSave
+	})
+	if !errors.Is(err, errSave) {
+		t.Fatalf("TakeStock with a failing save = %v, want %v", err, errSave)
+	}
+	if got := stockOf(t, s, id); got != 3 {
+		t.Errorf("stock after a failed save = %d, want it put back to 3", got)
+	}
+	checkLedger(t, s, movements, books)
+
+	applied, err := s.TakeStock(ctx, sale, func() (int, error) { return 42, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied[0].OrderID != 42 {
+		t.Errorf("order ID of the sale = %d, want 42", applied[0].OrderID)
+	}
+	if got := stockOf(t, s, id); got != 1 {
+		t.Errorf("stock after the sale = %d, want 1", got)
+	}
+	checkLedger(t, s, movements, books)
+}
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	books := book.NewStore()
+	movements := NewStore()
+	b, err := books.CreateBook(ctx, book.Book{Title: "Untracked", Price: 10, Stock: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Reconcile(ctx, books, movements)
+	if err != nil || n != 1 {
+		t.Fatalf("Reconcile = %d, %v; want 1 opening balance", n, err)
+	}
+	ledger, _ := movements.ListMovements(ctx, b.ID)
+	if len(ledger) != 1 || ledger[0].Reason != ReasonOpening || ledger[0].Delta != 4 {
+		t.Errorf("ledger = %+v, want an opening balance of 4", ledger)
+	}
+
+	if n, err := Reconcile(ctx, books, movements); err != nil || n != 0 {
+		t.Errorf("second Reconcile = %d, %v; want nothing to record", n, err)
+	}
+	if n, err := Reconcile(ctx, book.NewStore(), NewStore()); err != nil || n != 0 {
+		t.Errorf("Reconcile of an empty catalog = %d, %v; want 0, nil", n, err)
+	}
+}
@@ -0,0 +1,71 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryMovementStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var m Movement
+		if err := json.Unmarshal(rec.Data, &m); err != nil {
+			return fmt.Errorf("invalid stock movement record: %w", err)
+		}
+		store.put(m)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+// JournaledMovementStore appends new movements as creates; only restoring
+// a backup writes updates.
+type JournaledMovementStore struct {
+	*InMemoryMovementStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledStore(dir string, opts journal.Options) (*JournaledMovementStore, error) {
+	mem := NewStore()
+	j, err := journal.OpenStore(dir, "stock_movements", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledMovementStore{InMemoryMovementStore: mem, journal: j}, nil
+}
+
+func (store *JournaledMovementStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledMovementStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledMovementStore) RecordMovement(ctx context.Context, m Movement) (Movement, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	recorded, err := store.InMemoryMovementStore.RecordMovement(ctx, m)
+	if err != nil {
+		return Movement{}, err
+	}
+	return recorded, store.journal.Record(journal.OpCreate, recorded.ID, recorded)
+}
+
+func (store *JournaledMovementStore) RestoreMovement(ctx context.Context, m Movement) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryMovementStore.RestoreMovement(ctx, m); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpUpdate, m.ID, m)
+}
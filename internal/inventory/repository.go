@@ -0,0 +1,103 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Reason says why stock moved.
+type Reason string
+
+const (
+	ReasonReceive    Reason = "receive"
+	ReasonDamage     Reason = "damage"
+	ReasonCorrection Reason = "correction"
+	ReasonReturn     Reason = "return"
+	ReasonSale       Reason = "sale"
+	ReasonOpening    Reason = "opening"
+)
+
+// AdjustmentReasons are the reasons a manual adjustment may give; sales and
+// opening balances are only recorded by the system.
+var AdjustmentReasons = []Reason{ReasonReceive, ReasonDamage, ReasonCorrection, ReasonReturn}
+
+func ParseReason(s string) (Reason, error) {
+	if r := Reason(s); slices.Contains(AdjustmentReasons, r) {
+		return r, nil
+	}
+	return "", fmt.Errorf("invalid adjustment reason %q", s)
+}
+
+// Movement is one entry of the stock ledger. The deltas recorded for an
+// edition add up to its current stock, and StockAfter is the stock the
+// movement left it with.
+type Movement struct {
+	ID         int       `json:"id"`
+	BookID     int       `json:"book_id"`
+	EditionID  int       `json:"edition_id"`
+	Reason     Reason    `json:"reason"`
+	Delta      int       `json:"delta"`
+	StockAfter int       `json:"stock_after"`
+	Actor      string    `json:"actor,omitempty"`
+	OrderID    int       `json:"order_id,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Adjustment is a manual stock change. Quantity is the number of copies
+// received, damaged or returned; for a correction it is the signed change.
+type Adjustment struct {
+	EditionID int    `json:"edition_id"`
+	Reason    Reason `json:"reason"`
+	Quantity  int    `json:"quantity"`
+	Actor     string `json:"actor"`
+	OrderID   int    `json:"order_id,omitempty"`
+	Note      string `json:"note,omitempty"`
+}
+
+// Delta checks a and returns the signed stock change it makes.
+func (a Adjustment) Delta() (int, error) {
+	if _, err := ParseReason(string(a.Reason)); err != nil {
+		return 0, err
+	}
+	if a.Reason == ReasonCorrection {
+		if a.Quantity == 0 {
+			return 0, fmt.Errorf("correction quantity must not be zero")
+		}
+		return a.Quantity, nil
+	}
+	if a.Quantity <= 0 {
+		return 0, fmt.Errorf("%s quantity must be positive", a.Reason)
+	}
+	if a.Reason == ReasonDamage {
+		return -a.Quantity, nil
+	}
+	return a.Quantity, nil
+}
+
+type MovementStore interface {
+	RecordMovement(ctx context.Context, m Movement) (Movement, error)
+	ListMovements(ctx context.Context, bookID int) ([]Movement, error)
+	// ListAllMovements returns the whole ledger in ID order, including the
+	// movements of deleted books.
+	ListAllMovements(ctx context.Context) ([]Movement, error)
+	RestoreMovement(ctx context.Context, m Movement) error
+}
+
+// StockApplier changes the stock of editions and records the movements
+// atomically: every movement is checked before any stock changes, and
+// either all of them are applied or none is. StockAfter is filled in by the
+// applier.
+type StockApplier interface {
+	ApplyMovements(ctx context.Context, movements []Movement) ([]Movement, error)
+}
+
+// StockTaker takes the stock of an order before the order is saved: it
+// applies movements as a StockApplier does, then calls save and records the
+// movements with the order ID save returns. If save fails the stock is put
+// back, so an order is never stored without its stock having been taken.
+type StockTaker interface {
+	TakeStock(ctx context.Context, movements []Movement, save func() (orderID int, err error)) ([]Movement, error)
+}
@@ -0,0 +1,328 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"um6p.ma/final_project/internal/book"
+)
+
+type InMemoryMovementStore struct {
+	mu        sync.RWMutex
+	movements []Movement
+	nextID    int
+}
+
+func (store *InMemoryMovementStore) RecordMovement(ctx context.Context, m Movement) (Movement, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return Movement{}, ctx.Err()
+	default:
+	}
+
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	m.ID = store.nextID
+	store.nextID++
+	store.movements = append(store.movements, m)
+	return m, nil
+}
+
+func (store *InMemoryMovementStore) ListMovements(ctx context.Context, bookID int) ([]Movement, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	result := []Movement{}
+	for _, m := range store.movements {
+		if m.BookID == bookID {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+func (store *InMemoryMovementStore) ListAllMovements(ctx context.Context) ([]Movement, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	return slices.Clone(store.movements), nil
+}
+
+// RestoreMovement stores m under its own ID, replacing any movement with
+// that ID, and moves nextID past it.
+func (store *InMemoryMovementStore) RestoreMovement(ctx context.Context, m Movement) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if m.ID <= 0 {
+		return fmt.Errorf("invalid stock movement ID %d", m.ID)
+	}
+	store.put(m)
+	return nil
+}
+
+// put inserts m in ID order, replacing any movement with the same ID.
+// Callers hold the lock.
+func (store *InMemoryMovementStore) put(m Movement) {
+	i, found := slices.BinarySearchFunc(store.movements, m.ID, func(x Movement, id int) int { return x.ID - id })
+	if found {
+		store.movements[i] = m
+	} else {
+		store.movements = slices.Insert(store.movements, i, m)
+	}
+	store.nextID = max(store.nextID, m.ID+1)
+}
+
+type Service interface {
+	AdjustStock(ctx context.Context, bookID int, a Adjustment) (Movement, error)
+	ListMovements(ctx context.Context, bookID int) ([]Movement, error)
+
+	ActiveAlerts(ctx context.Context) ([]Alert, error)
+	StartPeriodicAlertChecks(ctx context.Context)
+	Stop()
+}
+
+type service struct {
+	books     book.BookStore
+	movements MovementStore
+	stock     StockApplier
+	notifier  Notifier
+
+	alertMu sync.Mutex
+	active  map[int]Alert
+
+	interval time.Duration
+	ticker   *time.Ticker
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	running  atomic.Bool
+}
+
+// NewService returns the inventory service, which makes stock adjustments
+// through stock, checks for books below their reorder point every interval
+// once started and hands new alerts to notifier.
+func NewService(books book.BookStore, movements MovementStore, stock StockApplier, notifier Notifier, interval time.Duration) Service {
+	return &service{
+		books:     books,
+		movements: movements,
+		stock:     stock,
+		notifier:  notifier,
+		active:    make(map[int]Alert),
+		interval:  interval,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+func (s *service) AdjustStock(ctx context.Context, bookID int, a Adjustment) (Movement, error) {
+	delta, err := a.Delta()
+	if err != nil {
+		return Movement{}, err
+	}
+	if a.Actor == "" {
+		return Movement{}, fmt.Errorf("actor is required")
+	}
+
+	b, err := s.books.GetBook(ctx, bookID)
+	if err != nil {
+		return Movement{}, err
+	}
+	i := b.EditionIndex(a.EditionID)
+	if i < 0 {
+		return Movement{}, fmt.Errorf("edition %d not found for book with ID %d", a.EditionID, bookID)
+	}
+	m := Movement{
+		BookID:    bookID,
+		EditionID: b.Editions[i].ID,
+		Reason:    a.Reason,
+		Delta:     delta,
+		Actor:     a.Actor,
+		OrderID:   a.OrderID,
+		Note:      a.Note,
+		CreatedAt: time.Now(),
+	}
+
+	applied, err := s.stock.ApplyMovements(ctx, []Movement{m})
+	if err != nil {
+		return Movement{}, err
+	}
+	return applied[0], nil
+}
+
+// ListMovements returns the ledger of a book, oldest first. Movements of a
+// deleted book stay listed.
+func (s *service) ListMovements(ctx context.Context, bookID int) ([]Movement, error) {
+	movements, err := s.movements.ListMovements(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	if len(movements) == 0 {
+		if _, err := s.books.GetBook(ctx, bookID); err != nil {
+			return nil, err
+		}
+	}
+	return movements, nil
+}
+
+// Reconcile records an opening balance, or a correction, for every edition
+// whose stock is not explained by the ledger, as happens for stock set before
+// the ledger existed or restored from a backup. It returns the number of
+// movements recorded.
+func Reconcile(ctx context.Context, books book.BookStore, movements MovementStore) (int, error) {
+	all, err := books.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, book.ErrNoBooks) {
+		return 0, err
+	}
+	n := 0
+	for _, b := range all {
+		ledger, err := movements.ListMovements(ctx, b.ID)
+		if err != nil {
+			return n, err
+		}
+		balance := make(map[int]int)
+		seen := make(map[int]bool)
+		for _, m := range ledger {
+			balance[m.EditionID] += m.Delta
+			seen[m.EditionID] = true
+		}
+		for _, e := range b.Editions {
+			if e.Stock == balance[e.ID] {
+				continue
+			}
+			reason := ReasonOpening
+			if seen[e.ID] {
+				reason = ReasonCorrection
+			}
+			_, err := movements.RecordMovement(ctx, Movement{
+				BookID:     b.ID,
+				EditionID:  e.ID,
+				Reason:     reason,
+				Delta:      e.Stock - balance[e.ID],
+				StockAfter: e.Stock,
+				Note:       "stock not explained by the ledger",
+			})
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *service) StartPeriodicAlertChecks(ctx context.Context) {
+	if !s.running.CompareAndSwap(false, true) {
+		return
+	}
+
+	s.ticker = time.NewTicker(s.interval)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case <-s.ticker.C:
+				if err := s.check(ctx); err != nil {
+					log.Printf("InventoryService Failed to check stock levels: %v\n", err)
+				}
+
+			case <-s.stopCh:
+				s.ticker.Stop()
+				return
+
+			case <-ctx.Done():
+				log.Println("InventoryService context cancelled")
+				s.ticker.Stop()
+				return
+			}
+		}
+	}()
+}
+
+func (s *service) Stop() {
+	if !s.running.CompareAndSwap(true, false) {
+		return
+	}
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// ActiveAlerts returns an alert for every book now below its reorder point,
+// without notifying or remembering them. Alerts already raised by a check
+// keep the time they were raised.
+func (s *service) ActiveAlerts(ctx context.Context) ([]Alert, error) {
+	books, err := s.books.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, book.ErrNoBooks) {
+		return nil, err
+	}
+
+	s.alertMu.Lock()
+	active, _ := s.alerts(books)
+	s.alertMu.Unlock()
+
+	result := make([]Alert, 0, len(active))
+	for _, a := range active {
+		result = append(result, a)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].BookID < result[j].BookID })
+	return result, nil
+}
+
+// check compares every book against its reorder point and notifies the
+// alerts that were not active at the previous check.
+func (s *service) check(ctx context.Context) error {
+	books, err := s.books.GetAllBooks(ctx)
+	if err != nil && !errors.Is(err, book.ErrNoBooks) {
+		return err
+	}
+
+	s.alertMu.Lock()
+	active, raised := s.alerts(books)
+	s.active = active
+	s.alertMu.Unlock()
+
+	for _, a := range raised {
+		if err := s.notifier.Notify(ctx, a); err != nil {
+			log.Printf("InventoryService Failed to deliver alert for book %d: %v", a.BookID, err)
+		}
+	}
+	return nil
+}
+
+// alerts returns the alerts for books below their reorder point, and those
+// of them not active at the last check. Callers hold alertMu.
+func (s *service) alerts(books []book.Book) (active map[int]Alert, raised []Alert) {
+	active = make(map[int]Alert)
+	for _, b := range books {
+		if !b.NeedsReorder() {
+			continue
+		}
+		a, seen := s.active[b.ID]
+		if !seen {
+			a.RaisedAt = time.Now()
+		}
+		a.BookID, a.Title, a.Stock = b.ID, b.Title, b.Stock
+		a.ReorderPoint, a.ReorderQuantity = b.ReorderPoint, b.ReorderQuantity
+		active[b.ID] = a
+		if !seen {
+			raised = append(raised, a)
+		}
+	}
+	return active, raised
+}
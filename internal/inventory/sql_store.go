@@ -0,0 +1,123 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+type SQLMovementStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLMovementStore {
+	return &SQLMovementStore{db: db}
+}
+
+// InsertMovement adds m to the ledger through q, which lets the order store
+// record sales in the transaction that takes the stock.
+func InsertMovement(ctx context.Context, q sqlutil.RowQueryer, m Movement) (Movement, error) {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	err := q.QueryRowContext(ctx,
+		`INSERT INTO stock_movements (book_id, edition_id, reason, delta, stock_after, actor, order_id, note, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id`,
+		m.BookID, m.EditionID, string(m.Reason), m.Delta, m.StockAfter, m.Actor, sqlutil.NullID(m.OrderID), m.Note, m.CreatedAt,
+	).Scan(&m.ID)
+	if err != nil {
+		return Movement{}, fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	return m, nil
+}
+
+func (store *SQLMovementStore) RecordMovement(ctx context.Context, m Movement) (Movement, error) {
+	return InsertMovement(ctx, store.db, m)
+}
+
+// ApplyMovements changes the stock of each movement's edition and records
+// the movements in one transaction, refusing to take any stock below zero.
+// The edition rows stay locked until it commits.
+func (store *SQLMovementStore) ApplyMovements(ctx context.Context, movements []Movement) ([]Movement, error) {
+	applied := make([]Movement, 0, len(movements))
+	err := sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		for _, m := range movements {
+			var stock int
+			err := tx.QueryRowContext(ctx,
+				`SELECT stock FROM editions WHERE id = $1 AND book_id = $2 FOR UPDATE`, m.EditionID, m.BookID,
+			).Scan(&stock)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("edition %d not found for book with ID %d", m.EditionID, m.BookID)
+			}
+			if err != nil {
+				return err
+			}
+			if stock+m.Delta < 0 {
+				return fmt.Errorf("insufficient stock for edition %d of book with ID %d; available: %d, requested: %d", m.EditionID, m.BookID, stock, -m.Delta)
+			}
+			m.StockAfter = stock + m.Delta
+			if _, err := tx.ExecContext(ctx, `UPDATE editions SET stock = $2 WHERE id = $1`, m.EditionID, m.StockAfter); err != nil {
+				return fmt.Errorf("failed to update edition %d: %w", m.EditionID, err)
+			}
+			if m, err = InsertMovement(ctx, tx, m); err != nil {
+				return err
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+const movementSelect = `SELECT id, book_id, edition_id, reason, delta, stock_after, actor, COALESCE(order_id, 0), note, created_at
+	FROM stock_movements`
+
+func (store *SQLMovementStore) queryMovements(ctx context.Context, where string, args ...any) ([]Movement, error) {
+	rows, err := store.db.QueryContext(ctx, movementSelect+" "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Movement{}
+	for rows.Next() {
+		var m Movement
+		if err := rows.Scan(&m.ID, &m.BookID, &m.EditionID, &m.Reason, &m.Delta, &m.StockAfter,
+			&m.Actor, &m.OrderID, &m.Note, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+	return result, rows.Err()
+}
+
+func (store *SQLMovementStore) ListMovements(ctx context.Context, bookID int) ([]Movement, error) {
+	return store.queryMovements(ctx, "WHERE book_id = $1", bookID)
+}
+
+func (store *SQLMovementStore) ListAllMovements(ctx context.Context) ([]Movement, error) {
+	return store.queryMovements(ctx, "")
+}
+
+func (store *SQLMovementStore) RestoreMovement(ctx context.Context, m Movement) error {
+	err := sqlutil.RestoreRow(ctx, store.db, "stock_movements",
+		`INSERT INTO stock_movements (id, book_id, edition_id, reason, delta, stock_after, actor, order_id, note, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT (id) DO UPDATE
+		 SET book_id = EXCLUDED.book_id, edition_id = EXCLUDED.edition_id, reason = EXCLUDED.reason,
+		     delta = EXCLUDED.delta, stock_after = EXCLUDED.stock_after, actor = EXCLUDED.actor,
+		     order_id = EXCLUDED.order_id, note = EXCLUDED.note, created_at = EXCLUDED.created_at`,
+		m.ID, m.BookID, m.EditionID, string(m.Reason), m.Delta, m.StockAfter, m.Actor, sqlutil.NullID(m.OrderID), m.Note, m.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to restore stock movement %d: %w", m.ID, err)
+	}
+	return nil
+}
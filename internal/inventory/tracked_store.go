@@ -0,0 +1,203 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"um6p.ma/final_project/internal/book"
+)
+
+// TrackedBookStore records the stock set when a book is created and any
+// stock change made by editing a book, so that edits through the book API
+// show up in the ledger too. It is also the StockApplier for backends
+// without transactions: its lock serializes every stock change made through
+// it.
+type TrackedBookStore struct {
+	book.BookStore
+	movements MovementStore
+	mu        sync.Mutex
+}
+
+func NewTrackedBookStore(store book.BookStore, movements MovementStore) *TrackedBookStore {
+	return &TrackedBookStore{BookStore: store, movements: movements}
+}
+
+func (s *TrackedBookStore) CreateBook(ctx context.Context, b book.Book) (book.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, err := s.BookStore.CreateBook(ctx, b)
+	if err != nil {
+		return book.Book{}, err
+	}
+	return created, s.record(ctx, book.Book{}, created, ReasonOpening, "initial stock")
+}
+
+func (s *TrackedBookStore) UpdateBook(ctx context.Context, id int, b book.Book) (book.Book, error) {
+	return s.ModifyBook(ctx, id, func(book.Book) (book.Book, error) { return b, nil })
+}
+
+// ModifyBook changes book id under the store lock, so that fn sees the
+// stock left by every change made through the store before it, and records
+// any stock fn changes.
+func (s *TrackedBookStore) ModifyBook(ctx context.Context, id int, fn func(book.Book) (book.Book, error)) (book.Book, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var old book.Book
+	updated, err := book.ModifyBook(ctx, s.BookStore, id, func(b book.Book) (book.Book, error) {
+		old = b
+		return fn(b)
+	})
+	if err != nil {
+		return book.Book{}, err
+	}
+	return updated, s.record(ctx, old, updated, ReasonCorrection, "book edited")
+}
+
+// ApplyMovements checks every movement against the stock it would leave,
+// then stores the changed books and records the movements, all under the
+// store lock. Stores that implement StockApplier themselves, such as the SQL
+// ledger, are left to do it in a transaction.
+func (s *TrackedBookStore) ApplyMovements(ctx context.Context, movements []Movement) ([]Movement, error) {
+	return s.TakeStock(ctx, movements, nil)
+}
+
+// TakeStock is ApplyMovements with save, if not nil, called under the store
+// lock once the books are stored and before the movements are recorded.
+// With a StockApplier ledger the movements are applied and recorded first,
+// and reversed by corrections if save fails.
+func (s *TrackedBookStore) TakeStock(ctx context.Context, movements []Movement, save func() (int, error)) ([]Movement, error) {
+	if applier, ok := s.movements.(StockApplier); ok {
+		return takeWith(ctx, applier, movements, save)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := make(map[int]book.Book)
+	changed := make(map[int]book.Book)
+	var order []int
+	applied := make([]Movement, len(movements))
+	for i, m := range movements {
+		b, ok := changed[m.BookID]
+		if !ok {
+			var err error
+			if b, err = s.BookStore.GetBook(ctx, m.BookID); err != nil {
+				return nil, err
+			}
+			old[b.ID] = b
+			order = append(order, b.ID)
+		}
+		b, err := b.AdjustStock(m.EditionID, m.Delta)
+		if err != nil {
+			return nil, err
+		}
+		changed[b.ID] = b
+		e := b.Editions[b.EditionIndex(m.EditionID)]
+		m.EditionID, m.StockAfter = e.ID, e.Stock
+		applied[i] = m
+	}
+
+	for i, id := range order {
+		if _, err := s.BookStore.UpdateBook(ctx, id, changed[id]); err != nil {
+			return nil, errors.Join(
+				fmt.Errorf("failed to update stock of book with ID %d: %w", id, err),
+				s.revert(ctx, old, order[:i]))
+		}
+	}
+	if save != nil {
+		orderID, err := save()
+		if err != nil {
+			return nil, errors.Join(err, s.revert(ctx, old, order))
+		}
+		for i := range applied {
+			applied[i].OrderID = orderID
+		}
+	}
+	for i, m := range applied {
+		recorded, err := s.movements.RecordMovement(ctx, m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record stock movement for book with ID %d: %w", m.BookID, err)
+		}
+		applied[i] = recorded
+	}
+	return applied, nil
+}
+
+// takeWith takes stock through a StockApplier before calling save, and
+// books corrections that put the stock back if save fails.
+func takeWith(ctx context.Context, applier StockApplier, movements []Movement, save func() (int, error)) ([]Movement, error) {
+	applied, err := applier.ApplyMovements(ctx, movements)
+	if err != nil || save == nil {
+		return applied, err
+	}
+	if _, err := save(); err != nil {
+		undo := make([]Movement, len(applied))
+		for i, m := range applied {
+			undo[i] = Movement{
+				BookID:    m.BookID,
+				EditionID: m.EditionID,
+				Reason:    ReasonCorrection,
+				Delta:     -m.Delta,
+				Actor:     m.Actor,
+				Note:      "order not saved",
+			}
+		}
+		if _, undoErr := applier.ApplyMovements(context.WithoutCancel(ctx), undo); undoErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to put back stock: %w", undoErr))
+		}
+		return nil, err
+	}
+	return applied, nil
+}
+
+// revert stores the books ids back as they were in old.
+func (s *TrackedBookStore) revert(ctx context.Context, old map[int]book.Book, ids []int) error {
+	var errs []error
+	for _, id := range ids {
+		if _, err := s.BookStore.UpdateBook(context.WithoutCancel(ctx), id, old[id]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore stock of book with ID %d: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// record adds a movement for every edition whose stock differs between old
+// and updated, including editions only one of them has.
+func (s *TrackedBookStore) record(ctx context.Context, old, updated book.Book, reason Reason, note string) error {
+	before := make(map[int]int)
+	for _, e := range old.Editions {
+		before[e.ID] = e.Stock
+	}
+	after := make(map[int]int)
+	for _, e := range updated.Editions {
+		after[e.ID] = e.Stock
+	}
+
+	var changes []Movement
+	for _, e := range updated.Editions {
+		if delta := e.Stock - before[e.ID]; delta != 0 {
+			changes = append(changes, Movement{EditionID: e.ID, Delta: delta, StockAfter: e.Stock})
+		}
+	}
+	for _, e := range old.Editions {
+		if _, kept := after[e.ID]; !kept && e.Stock != 0 {
+			changes = append(changes, Movement{EditionID: e.ID, Delta: -e.Stock, Note: "edition removed"})
+		}
+	}
+
+	for _, m := range changes {
+		m.BookID = updated.ID
+		m.Reason = reason
+		if m.Note == "" {
+			m.Note = note
+		}
+		if _, err := s.movements.RecordMovement(ctx, m); err != nil {
+			return fmt.Errorf("failed to record stock movement for book with ID %d: %w", updated.ID, err)
+		}
+	}
+	return nil
+}
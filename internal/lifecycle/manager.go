@@ -0,0 +1,108 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+type Worker struct {
+	Name  string
+	Start func(ctx context.Context)
+	Stop  func()
+}
+
+type Flusher interface {
+	Flush() error
+}
+
+type namedFlusher struct {
+	name string
+	f    Flusher
+}
+
+type Manager struct {
+	server          *http.Server
+	shutdownTimeout time.Duration
+	workers         []Worker
+	flushers        []namedFlusher
+}
+
+func NewManager(server *http.Server, shutdownTimeout time.Duration) *Manager {
+	return &Manager{
+		server:          server,
+		shutdownTimeout: shutdownTimeout,
+	}
+}
+
+func (m *Manager) AddWorker(w Worker) {
+	m.workers = append(m.workers, w)
+}
+
+func (m *Manager) AddFlusher(name string, f Flusher) {
+	m.flushers = append(m.flushers, namedFlusher{name: name, f: f})
+}
+
+// Run starts the registered workers and the HTTP server, then blocks until
+// ctx is cancelled (typically by SIGINT/SIGTERM) or the server fails. It then
+// drains in-flight requests, stops workers and flushes stores, in that order.
+func (m *Manager) Run(ctx context.Context) error {
+	workerCtx, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+
+	for _, w := range m.workers {
+		log.Printf("starting worker %s", w.Name)
+		w.Start(workerCtx)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s", m.server.Addr)
+		if err := m.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Printf("shutdown requested, draining requests (deadline %s)", m.shutdownTimeout)
+	case err := <-serverErr:
+		if err != nil {
+			runErr = fmt.Errorf("server failed: %w", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), m.shutdownTimeout)
+	defer cancel()
+	if err := m.server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
+		runErr = errors.Join(runErr, fmt.Errorf("server shutdown: %w", err))
+	}
+
+	for i := len(m.workers) - 1; i >= 0; i-- {
+		log.Printf("stopping worker %s", m.workers[i].Name)
+		m.workers[i].Stop()
+	}
+	cancelWorkers()
+
+	for _, nf := range m.flushers {
+		if err := nf.f.Flush(); err != nil {
+			log.Printf("failed to flush %s: %v", nf.name, err)
+			runErr = errors.Join(runErr, fmt.Errorf("flush %s: %w", nf.name, err))
+		}
+		if c, ok := nf.f.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Printf("failed to close %s: %v", nf.name, err)
+				runErr = errors.Join(runErr, fmt.Errorf("close %s: %w", nf.name, err))
+			}
+		}
+	}
+
+	return runErr
+}
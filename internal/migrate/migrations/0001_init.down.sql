@@ -0,0 +1,6 @@
+DROP TABLE sales;
+DROP TABLE order_items;
+DROP TABLE orders;
+DROP TABLE customers;
+DROP TABLE books;
+DROP TABLE authors;
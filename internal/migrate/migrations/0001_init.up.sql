@@ -0,0 +1,61 @@
+CREATE TABLE authors (
+    id         SERIAL PRIMARY KEY,
+    first_name TEXT NOT NULL,
+    last_name  TEXT NOT NULL,
+    bio        TEXT NOT NULL DEFAULT '',
+    UNIQUE (first_name, last_name)
+);
+
+CREATE TABLE books (
+    id           SERIAL PRIMARY KEY,
+    title        TEXT NOT NULL UNIQUE,
+    author_id    INTEGER REFERENCES authors (id),
+    genre        TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMPTZ NOT NULL,
+    price        NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    stock        INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0)
+);
+
+CREATE INDEX books_author_id_idx ON books (author_id);
+
+CREATE TABLE customers (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    email       TEXT NOT NULL DEFAULT '',
+    street      TEXT NOT NULL DEFAULT '',
+    city        TEXT NOT NULL DEFAULT '',
+    state       TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL DEFAULT '',
+    country     TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE orders (
+    id          SERIAL PRIMARY KEY,
+    customer_id INTEGER NOT NULL REFERENCES customers (id),
+    total_price NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL,
+    status      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX orders_created_at_idx ON orders (created_at);
+
+CREATE TABLE order_items (
+    order_id   INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    book_id    INTEGER NOT NULL REFERENCES books (id),
+    quantity   INTEGER NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(12, 2) NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
+
+CREATE TABLE sales (
+    id          SERIAL PRIMARY KEY,
+    book_id     INTEGER REFERENCES books (id) ON DELETE SET NULL,
+    title       TEXT NOT NULL DEFAULT '',
+    unit_price  NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    quantity    INTEGER NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX sales_recorded_at_idx ON sales (recorded_at);
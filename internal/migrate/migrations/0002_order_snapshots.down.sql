@@ -0,0 +1,8 @@
+ALTER TABLE orders
+    DROP COLUMN ship_street,
+    DROP COLUMN ship_city,
+    DROP COLUMN ship_state,
+    DROP COLUMN ship_postal_code,
+    DROP COLUMN ship_country;
+
+ALTER TABLE order_items DROP COLUMN title;
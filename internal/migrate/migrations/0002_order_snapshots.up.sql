@@ -0,0 +1,19 @@
+ALTER TABLE order_items ADD COLUMN title TEXT NOT NULL DEFAULT '';
+
+UPDATE order_items oi SET title = b.title FROM books b WHERE b.id = oi.book_id;
+
+ALTER TABLE orders
+    ADD COLUMN ship_street      TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ship_city        TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ship_state       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ship_postal_code TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ship_country     TEXT NOT NULL DEFAULT '';
+
+UPDATE orders o
+SET ship_street = c.street,
+    ship_city = c.city,
+    ship_state = c.state,
+    ship_postal_code = c.postal_code,
+    ship_country = c.country
+FROM customers c
+WHERE c.id = o.customer_id;
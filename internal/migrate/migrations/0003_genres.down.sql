@@ -0,0 +1,13 @@
+ALTER TABLE books ADD COLUMN genre TEXT NOT NULL DEFAULT '';
+
+UPDATE books b
+SET genre = s.names
+FROM (
+    SELECT bg.book_id, string_agg(g.name, ', ' ORDER BY g.name) AS names
+    FROM book_genres bg JOIN genres g ON g.id = bg.genre_id
+    GROUP BY bg.book_id
+) s
+WHERE s.book_id = b.id;
+
+DROP TABLE book_genres;
+DROP TABLE genres;
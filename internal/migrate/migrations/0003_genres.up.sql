@@ -0,0 +1,32 @@
+CREATE TABLE genres (
+    id        SERIAL PRIMARY KEY,
+    name      TEXT NOT NULL,
+    parent_id INTEGER REFERENCES genres (id)
+);
+
+CREATE UNIQUE INDEX genres_parent_name_idx ON genres (COALESCE(parent_id, 0), lower(name));
+
+CREATE TABLE book_genres (
+    book_id  INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    genre_id INTEGER NOT NULL REFERENCES genres (id),
+    PRIMARY KEY (book_id, genre_id)
+);
+
+CREATE INDEX book_genres_genre_idx ON book_genres (genre_id);
+
+-- Turn each comma-separated entry of the old genre column into a top-level
+-- genre and link the book to it.
+CREATE TEMPORARY TABLE legacy_genres ON COMMIT DROP AS
+SELECT b.id AS book_id, btrim(part) AS name
+FROM books b, regexp_split_to_table(b.genre, ',') AS part
+WHERE btrim(part) <> '';
+
+INSERT INTO genres (name)
+SELECT DISTINCT ON (lower(name)) name FROM legacy_genres ORDER BY lower(name), name;
+
+INSERT INTO book_genres (book_id, genre_id)
+SELECT DISTINCT l.book_id, g.id
+FROM legacy_genres l
+JOIN genres g ON g.parent_id IS NULL AND lower(g.name) = lower(l.name);
+
+ALTER TABLE books DROP COLUMN genre;
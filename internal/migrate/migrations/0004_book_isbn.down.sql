@@ -0,0 +1,5 @@
+DROP INDEX books_isbn13_idx;
+
+ALTER TABLE books
+    DROP COLUMN isbn10,
+    DROP COLUMN isbn13;
@@ -0,0 +1,5 @@
+ALTER TABLE books
+    ADD COLUMN isbn13 TEXT,
+    ADD COLUMN isbn10 TEXT;
+
+CREATE UNIQUE INDEX books_isbn13_idx ON books (isbn13);
@@ -0,0 +1,13 @@
+CREATE TABLE book_contributors (
+    book_id   INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL,
+    author_id INTEGER NOT NULL REFERENCES authors (id),
+    role      TEXT NOT NULL,
+    PRIMARY KEY (book_id, position),
+    UNIQUE (book_id, author_id, role)
+);
+
+CREATE INDEX book_contributors_author_idx ON book_contributors (author_id);
+
+INSERT INTO book_contributors (book_id, position, author_id, role)
+SELECT id, 0, author_id, 'author' FROM books WHERE author_id IS NOT NULL;
@@ -0,0 +1,19 @@
+ALTER TABLE books
+    ADD COLUMN isbn13 TEXT,
+    ADD COLUMN isbn10 TEXT,
+    ADD COLUMN price  NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN stock  INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0);
+
+UPDATE books b
+SET isbn13 = e.isbn13, isbn10 = e.isbn10, price = e.price,
+    stock = (SELECT SUM(x.stock) FROM editions x WHERE x.book_id = b.id)
+FROM editions e
+WHERE e.book_id = b.id AND e.position = 0;
+
+CREATE UNIQUE INDEX books_isbn13_idx ON books (isbn13);
+
+ALTER TABLE order_items
+    DROP COLUMN format,
+    DROP COLUMN edition_id;
+
+DROP TABLE editions;
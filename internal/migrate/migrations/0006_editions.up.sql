@@ -0,0 +1,33 @@
+CREATE TABLE editions (
+    id         SERIAL PRIMARY KEY,
+    book_id    INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    position   INTEGER NOT NULL,
+    format     TEXT NOT NULL,
+    isbn13     TEXT UNIQUE,
+    isbn10     TEXT,
+    publisher  TEXT NOT NULL DEFAULT '',
+    page_count INTEGER NOT NULL DEFAULT 0,
+    price      NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    stock      INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    UNIQUE (book_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+INSERT INTO editions (book_id, position, format, isbn13, isbn10, price, stock)
+SELECT id, 0, 'paperback', isbn13, isbn10, price, stock FROM books;
+
+ALTER TABLE order_items
+    ADD COLUMN edition_id INTEGER REFERENCES editions (id) ON DELETE SET NULL,
+    ADD COLUMN format     TEXT NOT NULL DEFAULT '';
+
+UPDATE order_items oi
+SET edition_id = e.id, format = e.format
+FROM editions e
+WHERE e.book_id = oi.book_id AND e.position = 0;
+
+DROP INDEX books_isbn13_idx;
+
+ALTER TABLE books
+    DROP COLUMN isbn13,
+    DROP COLUMN isbn10,
+    DROP COLUMN price,
+    DROP COLUMN stock;
@@ -0,0 +1,14 @@
+CREATE TABLE stock_movements (
+    id          SERIAL PRIMARY KEY,
+    book_id     INTEGER NOT NULL,
+    edition_id  INTEGER NOT NULL,
+    reason      TEXT NOT NULL,
+    delta       INTEGER NOT NULL,
+    stock_after INTEGER NOT NULL,
+    actor       TEXT NOT NULL DEFAULT '',
+    order_id    INTEGER REFERENCES orders (id) ON DELETE SET NULL,
+    note        TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX stock_movements_book_id_idx ON stock_movements (book_id, id);
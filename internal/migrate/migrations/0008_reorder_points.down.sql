@@ -0,0 +1,3 @@
+ALTER TABLE books
+    DROP COLUMN reorder_point,
+    DROP COLUMN reorder_quantity;
@@ -0,0 +1,3 @@
+ALTER TABLE books
+    ADD COLUMN reorder_point    INTEGER NOT NULL DEFAULT 0 CHECK (reorder_point >= 0),
+    ADD COLUMN reorder_quantity INTEGER NOT NULL DEFAULT 0 CHECK (reorder_quantity >= 0);
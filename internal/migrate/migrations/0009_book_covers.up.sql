@@ -0,0 +1 @@
+ALTER TABLE books ADD COLUMN cover JSONB;
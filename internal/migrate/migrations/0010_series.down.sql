@@ -0,0 +1,5 @@
+ALTER TABLE books
+    DROP COLUMN series_volume,
+    DROP COLUMN series_id;
+
+DROP TABLE series;
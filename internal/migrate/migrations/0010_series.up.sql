@@ -0,0 +1,15 @@
+CREATE TABLE series (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    author_id   INTEGER REFERENCES authors (id) ON DELETE SET NULL
+);
+
+CREATE UNIQUE INDEX series_name_idx ON series (lower(name));
+
+ALTER TABLE books
+    ADD COLUMN series_id     INTEGER REFERENCES series (id),
+    ADD COLUMN series_volume INTEGER CHECK (series_volume > 0),
+    ADD CONSTRAINT books_series_check CHECK ((series_id IS NULL) = (series_volume IS NULL));
+
+CREATE UNIQUE INDEX books_series_volume_idx ON books (series_id, series_volume);
@@ -0,0 +1,4 @@
+ALTER TABLE books
+    DROP COLUMN publisher_id;
+
+DROP TABLE publishers;
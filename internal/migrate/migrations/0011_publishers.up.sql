@@ -0,0 +1,13 @@
+CREATE TABLE publishers (
+    id      SERIAL PRIMARY KEY,
+    name    TEXT NOT NULL,
+    email   TEXT NOT NULL DEFAULT '',
+    website TEXT NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX publishers_name_idx ON publishers (lower(name));
+
+ALTER TABLE books
+    ADD COLUMN publisher_id INTEGER REFERENCES publishers (id);
+
+CREATE INDEX books_publisher_idx ON books (publisher_id);
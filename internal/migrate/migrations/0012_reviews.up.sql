@@ -0,0 +1,13 @@
+CREATE TABLE reviews (
+    id          SERIAL PRIMARY KEY,
+    book_id     INTEGER NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    customer_id INTEGER NOT NULL REFERENCES customers (id) ON DELETE CASCADE,
+    rating      INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    text        TEXT NOT NULL DEFAULT '',
+    status      TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (book_id, customer_id)
+);
+
+CREATE INDEX reviews_book_status_idx ON reviews (book_id, status);
+CREATE INDEX reviews_status_idx ON reviews (status, created_at);
@@ -0,0 +1,10 @@
+ALTER TABLE editions
+    ADD COLUMN publisher TEXT NOT NULL DEFAULT '';
+
+UPDATE editions e
+SET publisher = p.name
+FROM publishers p
+WHERE p.id = COALESCE(e.publisher_id, (SELECT b.publisher_id FROM books b WHERE b.id = e.book_id));
+
+ALTER TABLE editions
+    DROP COLUMN publisher_id;
@@ -0,0 +1,21 @@
+ALTER TABLE editions
+    ADD COLUMN publisher_id INTEGER REFERENCES publishers (id);
+
+CREATE INDEX editions_publisher_idx ON editions (publisher_id);
+
+INSERT INTO publishers (name)
+SELECT DISTINCT ON (lower(btrim(publisher))) btrim(publisher) FROM editions WHERE btrim(publisher) <> ''
+ON CONFLICT DO NOTHING;
+
+UPDATE editions e
+SET publisher_id = p.id
+FROM publishers p
+WHERE btrim(e.publisher) <> '' AND lower(p.name) = lower(btrim(e.publisher));
+
+UPDATE books b
+SET publisher_id = e.publisher_id
+FROM editions e
+WHERE b.publisher_id IS NULL AND e.book_id = b.id AND e.position = 0 AND e.publisher_id IS NOT NULL;
+
+ALTER TABLE editions
+    DROP COLUMN publisher;
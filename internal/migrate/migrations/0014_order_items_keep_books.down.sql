@@ -0,0 +1,4 @@
+DROP INDEX order_items_book_idx;
+
+ALTER TABLE order_items
+    ADD CONSTRAINT order_items_book_id_fkey FOREIGN KEY (book_id) REFERENCES books (id) NOT VALID;
@@ -0,0 +1,6 @@
+-- Order items keep the ID of a deleted book, as the in-memory store does;
+-- the title, format and price they need are copied at purchase time.
+ALTER TABLE order_items
+    DROP CONSTRAINT order_items_book_id_fkey;
+
+CREATE INDEX order_items_book_idx ON order_items (book_id);
@@ -0,0 +1,84 @@
+package order
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID int     `json:"next_id"`
+	Orders []Order `json:"orders"`
+}
+
+func (store *InMemoryOrderStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	snap := storeSnapshot{NextID: store.nextID, Orders: make([]Order, 0, len(store.orders))}
+	for _, o := range store.orders {
+		snap.Orders = append(snap.Orders, o)
+	}
+	return snap
+}
+
+func (store *InMemoryOrderStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.orders = make(map[int]Order, len(snap.Orders))
+	store.nextID = max(snap.NextID, 1)
+	for _, o := range snap.Orders {
+		store.orders[o.ID] = o.Normalized()
+		store.nextID = max(store.nextID, o.ID+1)
+	}
+}
+
+type FileOrderStore struct {
+	*InMemoryOrderStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileOrderStore(dir string) (*FileOrderStore, error) {
+	mem := NewOrderStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "orders.json"), "orders", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileOrderStore{InMemoryOrderStore: mem, file: file}, nil
+}
+
+func (store *FileOrderStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileOrderStore) Create(ctx context.Context, order Order) (Order, error) {
+	created, err := store.InMemoryOrderStore.Create(ctx, order)
+	if err != nil {
+		return Order{}, err
+	}
+	return created, store.file.Save()
+}
+
+func (store *FileOrderStore) Update(ctx context.Context, id int, order Order) (Order, error) {
+	updated, err := store.InMemoryOrderStore.Update(ctx, id, order)
+	if err != nil {
+		return Order{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FileOrderStore) Delete(ctx context.Context, id int) error {
+	if err := store.InMemoryOrderStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileOrderStore) Restore(ctx context.Context, order Order) error {
+	if err := store.InMemoryOrderStore.Restore(ctx, order); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
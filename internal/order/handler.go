@@ -4,18 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
-	"um6p.ma/final_project/internal/book"
-	"um6p.ma/final_project/internal/customer"
-	"um6p.ma/final_project/pkg/error"
-)
-
-var (
-	customerStore customer.CustomerStore = customer.NewCustomerStore()
-	bookStore     book.BookStore         = book.NewStore()
-	ordStore      OrderStore             = NewOrderStore()
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
 )
 
 func NewOrderStore() *InMemoryOrderStore {
@@ -25,93 +18,137 @@ func NewOrderStore() *InMemoryOrderStore {
 	}
 }
 
-var orderService = NewService(
-	ordStore,
-	customerStore,
-	bookStore,
-)
+type Handler struct {
+	svc     Service
+	timeout time.Duration
+}
 
-func OrdersHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
+func NewHandler(svc Service, timeout time.Duration) *Handler {
+	return &Handler{svc: svc, timeout: timeout}
+}
 
-	switch r.Method {
-	case http.MethodGet:
-		orders, err := orderService.ListOrders(ctx)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(orders)
-
-	case http.MethodPost:
-		var o Order
-		if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
-			error.WriteJSONError(w, "Bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/orders", h.withTimeout(h.ListOrders))
+	r.HandleFunc(http.MethodPost, "/orders", h.withTimeout(h.CreateOrder))
+	r.HandleFunc(http.MethodGet, "/orders/{id}", h.withTimeout(h.GetOrder))
+	r.HandleFunc(http.MethodPut, "/orders/{id}", h.withTimeout(h.UpdateOrder))
+	r.HandleFunc(http.MethodDelete, "/orders/{id}", h.withTimeout(h.DeleteOrder))
+}
+
+func (h *Handler) withTimeout(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
+}
 
-		createdOrder, err := orderService.CreateOrder(ctx, o)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusBadRequest)
-			return
+// parseExpand reads ?expand=book,customer.
+func parseExpand(r *http.Request) (Expand, bool) {
+	var e Expand
+	for _, part := range strings.Split(r.URL.Query().Get("expand"), ",") {
+		switch strings.TrimSpace(part) {
+		case "":
+		case "book", "books":
+			e.Book = true
+		case "customer":
+			e.Customer = true
+		default:
+			return Expand{}, false
 		}
+	}
+	return e, true
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(createdOrder)
+func (h *Handler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	expand, ok := parseExpand(r)
+	if !ok {
+		pkgError.WriteJSONError(w, "Invalid expand parameter: use book, customer", http.StatusBadRequest)
+		return
+	}
 
-	default:
-		error.WriteJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	orders, err := h.svc.ListOrders(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.svc.ExpandOrders(r.Context(), orders, expand))
 }
 
-func OrderHandler(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
+func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var o Order
+	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+		pkgError.WriteJSONError(w, "Bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
 
-	idStr := r.URL.Path[len("/orders/"):]
-	id, err := strconv.Atoi(idStr)
+	createdOrder, err := h.svc.CreateOrder(r.Context(), o)
 	if err != nil {
-		error.WriteJSONError(w, "Invalid order ID", http.StatusBadRequest)
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		ord, err := orderService.GetOrderByID(ctx, id)
-		if err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(ord)
-
-	case http.MethodPut:
-		var updated Order
-		if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
-			error.WriteJSONError(w, "Bad request: invalid JSON", http.StatusBadRequest)
-			return
-		}
-		updated.ID = id
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(createdOrder)
+}
 
-		if err := orderService.UpdateOrder(ctx, id, updated); err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+	expand, ok := parseExpand(r)
+	if !ok {
+		pkgError.WriteJSONError(w, "Invalid expand parameter: use book, customer", http.StatusBadRequest)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(updated)
+	ord, err := h.svc.GetOrderByID(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.svc.ExpandOrders(r.Context(), []Order{ord}, expand)[0])
+}
 
-	case http.MethodDelete:
-		if err := orderService.DeleteOrder(ctx, id); err != nil {
-			error.WriteJSONError(w, err.Error(), http.StatusNotFound)
-			return
-		}
-		w.WriteHeader(http.StatusNoContent)
+func (h *Handler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
 
-	default:
-		error.WriteJSONError(w, "Method not allowed", http.StatusMethodNotAllowed)
+	var updated Order
+	if err := json.NewDecoder(r.Body).Decode(&updated); err != nil {
+		pkgError.WriteJSONError(w, "Bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+	updated = updated.Normalized()
+	updated.ID = id
+
+	if err := h.svc.UpdateOrder(r.Context(), id, updated); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteOrder(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
 	}
+	w.WriteHeader(http.StatusNoContent)
 }
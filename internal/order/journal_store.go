@@ -0,0 +1,93 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryOrderStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var o Order
+		if err := json.Unmarshal(rec.Data, &o); err != nil {
+			return fmt.Errorf("invalid order record: %w", err)
+		}
+		store.orders[o.ID] = o.Normalized()
+		store.nextID = max(store.nextID, o.ID+1)
+	case journal.OpDelete:
+		delete(store.orders, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledOrderStore struct {
+	*InMemoryOrderStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledOrderStore(dir string, opts journal.Options) (*JournaledOrderStore, error) {
+	mem := NewOrderStore()
+	j, err := journal.OpenStore(dir, "orders", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledOrderStore{InMemoryOrderStore: mem, journal: j}, nil
+}
+
+func (store *JournaledOrderStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledOrderStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledOrderStore) Create(ctx context.Context, order Order) (Order, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	created, err := store.InMemoryOrderStore.Create(ctx, order)
+	if err != nil {
+		return Order{}, err
+	}
+	return created, store.journal.Record(journal.OpCreate, created.ID, created)
+}
+
+func (store *JournaledOrderStore) Update(ctx context.Context, id int, order Order) (Order, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemoryOrderStore.Update(ctx, id, order)
+	if err != nil {
+		return Order{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledOrderStore) Delete(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryOrderStore.Delete(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledOrderStore) Restore(ctx context.Context, order Order) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryOrderStore.Restore(ctx, order); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, order.ID, order)
+}
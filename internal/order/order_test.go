@@ -0,0 +1,224 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/customer"
+	"um6p.ma/final_project/internal/inventory"
+)
+
+var errStoreDown = errors.New("order store down")
+
+// failingStore is an order store whose Create always fails.
+type failingStore struct {
+	*InMemoryOrderStore
+}
+
+func (failingStore) Create(context.Context, Order) (Order, error) {
+	return Order{}, errStoreDown
+}
+
+type fixture struct {
+	svc        Service
+	orders     OrderStore
+	books      *inventory.TrackedBookStore
+	movements  *inventory.InMemoryMovementStore
+	customerID int
+	bookID     int
+}
+
+// newFixture returns an order service over in-memory stores holding one
+// customer and one book with stock copies.
+func newFixture(t *testing.T, orders OrderStore, stock int) fixture {
+	t.Helper()
+	ctx := context.Background()
+	customers := customer.NewCustomerStore()
+	c, err := customers.CreateCustomer(ctx, &customer.Customer{
+		Name:    "Reader",
+		Email:   "reader@example.com",
+		Address: customer.Address{Street: "1 Main St", City: "Rabat", Country: "MA"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	movements := inventory.NewStore()
+	books := inventory.NewTrackedBookStore(book.NewStore(), movements)
+	b, err := books.CreateBook(ctx, book.Book{Title: "Dune", Price: 12.5, Stock: stock})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fixture{
+		svc:        NewService(orders, customers, books, books),
+		orders:     orders,
+		books:      books,
+		movements:  movements,
+		customerID: c.ID,
+		bookID:     b.ID,
+	}
+}
+
+func (f fixture) order(quantity int) Order {
+	return Order{CustomerID: f.customerID, Items: []OrderItem{{BookID: f.bookID, Quantity: quantity}}}
+}
+
+func (f fixture) stock(t *testing.T) int {
+	t.Helper()
+	b, err := f.books.GetBook(context.Background(), f.bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Editions[0].Stock
+}
+
+// sales returns the sale movements in the ledger of the fixture's book.
+func (f fixture) sales(t *testing.T) []inventory.Movement {
+	t.Helper()
+	ledger, err := f.movements.ListMovements(context.Background(), f.bookID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sales []inventory.Movement
+	for _, m := range ledger {
+		if m.Reason == inventory.ReasonSale {
+			sales = append(sales, m)
+		}
+	}
+	return sales
+}
+
+func TestCreateOrderValidation(t *testing.T) {
+	f := newFixture(t, NewOrderStore(), 5)
+	tests := []struct {
+		name string
+		o    Order
+	}{
+		{"no customer", Order{Items: []OrderItem{{BookID: f.bookID, Quantity: 1}}}},
+		{"unknown customer", Order{CustomerID: 99, Items: []OrderItem{{BookID: f.bookID, Quantity: 1}}}},
+		{"no items", Order{CustomerID: f.customerID}},
+		{"zero quantity", f.order(0)},
+		{"unknown book", Order{CustomerID: f.customerID, Items: []OrderItem{{BookID: 99, Quantity: 1}}}},
+		{"unknown edition", Order{CustomerID: f.customerID, Items: []OrderItem{{BookID: f.bookID, EditionID: 99, Quantity: 1}}}},
+		{"insufficient stock", f.order(6)},
+	}
+	for _, tt := range tests {
+		if _, err := f.svc.CreateOrder(context.Background(), tt.o); err == nil {
+			t.Errorf("%s: CreateOrder succeeded", tt.name)
+		}
+	}
+	if got := f.stock(t); got != 5 {
+		t.Errorf("stock = %d, want 5 after rejected orders", got)
+	}
+	if _, err := f.orders.List(context.Background()); !errors.Is(err, ErrNoOrders) {
+		t.Errorf("List = %v, want %v after rejected orders", err, ErrNoOrders)
+	}
+}
+
+func TestCreateOrder(t *testing.T) {
+	f := newFixture(t, NewOrderStore(), 5)
+	o, err := f.svc.CreateOrder(context.Background(), f.order(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o.ID == 0 || o.TotalPrice != 25 || o.Status != "Pending" {
+		t.Errorf("order = %+v, want it saved as pending with a total of 25", o)
+	}
+	item := o.Items[0]
+	if item.Title != "Dune" || item.UnitPrice != 12.5 || item.EditionID == 0 {
+		t.Errorf("item = %+v, want the title, price and edition copied from the book", item)
+	}
+	if o.ShippingAddress.City != "Rabat" {
+		t.Errorf("shipping address = %+v, want the customer's", o.ShippingAddress)
+	}
+	if got := f.stock(t); got != 3 {
+		t.Errorf("stock = %d, want 3", got)
+	}
+	sales := f.sales(t)
+	if len(sales) != 1 || sales[0].Delta != -2 || sales[0].OrderID != o.ID {
+		t.Errorf("sales = %+v, want one sale of 2 for order %d", sales, o.ID)
+	}
+}
+
+func TestCreateOrderSaveFails(t *testing.T) {
+	f := newFixture(t, failingStore{NewOrderStore()}, 5)
+	if _, err := f.svc.CreateOrder(context.Background(), f.order(2)); !errors.Is(err, errStoreDown) {
+		t.Fatalf("CreateOrder = %v, want %v", err, errStoreDown)
+	}
+	if got := f.stock(t); got != 5 {
+		t.Errorf("stock = %d, want it put back to 5", got)
+	}
+	if sales := f.sales(t); len(sales) != 0 {
+		t.Errorf("sales = %+v, want none for an order that was not saved", sales)
+	}
+}
+
+func TestCreateOrderConcurrent(t *testing.T) {
+	const stock, buyers = 5, 12
+	f := newFixture(t, NewOrderStore(), stock)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	placed := 0
+	for range buyers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.svc.CreateOrder(context.Background(), f.order(1)); err == nil {
+				mu.Lock()
+				placed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if placed != stock {
+		t.Errorf("%d orders placed, want %d", placed, stock)
+	}
+	if got := f.stock(t); got != 0 {
+		t.Errorf("stock = %d, want 0", got)
+	}
+	orders, err := f.orders.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != placed || len(f.sales(t)) != placed {
+		t.Errorf("%d orders and %d sales saved, want %d of each", len(orders), len(f.sales(t)), placed)
+	}
+}
+
+func TestHasPurchased(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, NewOrderStore(), 5)
+	o, err := f.svc.CreateOrder(ctx, f.order(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		customerID, bookID int
+		want               bool
+	}{
+		{f.customerID, f.bookID, true},
+		{f.customerID, 99, false},
+		{99, f.bookID, false},
+	}
+	for _, tt := range tests {
+		got, err := f.orders.HasPurchased(ctx, tt.customerID, tt.bookID)
+		if err != nil || got != tt.want {
+			t.Errorf("HasPurchased(%d, %d) = %v, %v; want %v", tt.customerID, tt.bookID, got, err, tt.want)
+		}
+	}
+
+	o.Status = StatusCancelled
+	if err := f.svc.UpdateOrder(ctx, o.ID, o); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := f.orders.HasPurchased(ctx, f.customerID, f.bookID); got {
+		t.Error("HasPurchased counts a cancelled order")
+	}
+}
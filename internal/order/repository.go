@@ -2,32 +2,110 @@ package order
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"um6p.ma/final_project/internal/book"
 	"um6p.ma/final_project/internal/customer"
 )
 
+// OrderItem records what was bought: the book and edition references plus
+// the title, format and unit price as they were at purchase time. An item
+// placed without an edition ID gets the book's default edition. Deleting a
+// book keeps the items that bought it, with their BookID left as it was.
+// Book is only set when a response asks for it with ?expand=book.
 type OrderItem struct {
-	Book     book.Book `json:"book"`
-	Quantity int       `json:"quantity"`
+	BookID    int         `json:"book_id"`
+	EditionID int         `json:"edition_id,omitempty"`
+	Title     string      `json:"title"`
+	Format    book.Format `json:"format,omitempty"`
+	UnitPrice float64     `json:"unit_price"`
+	Quantity  int         `json:"quantity"`
+	Book      *book.Book  `json:"book,omitempty"`
 }
 
+// Order references its customer by ID and keeps a frozen copy of the
+// shipping address. Customer is only set with ?expand=customer.
 type Order struct {
-	ID         int               `json:"id"`
-	Customer   customer.Customer `json:"customer"`
-	Items      []OrderItem       `json:"items"`
-	TotalPrice float64           `json:"total_price"`
-	CreatedAt  time.Time         `json:"created_at"`
-	Status     string            `json:"status"`
+	ID              int                `json:"id"`
+	CustomerID      int                `json:"customer_id"`
+	ShippingAddress customer.Address   `json:"shipping_address"`
+	Customer        *customer.Customer `json:"customer,omitempty"`
+	Items           []OrderItem        `json:"items"`
+	TotalPrice      float64            `json:"total_price"`
+	CreatedAt       time.Time          `json:"created_at"`
+	Status          string             `json:"status"`
 }
 
+// StatusCancelled marks an order that was called off. A cancelled order no
+// longer counts as a purchase of its books.
+const StatusCancelled = "Cancelled"
+
+func (o Order) Cancelled() bool {
+	return strings.EqualFold(o.Status, StatusCancelled)
+}
+
+// Normalized converts orders written before items and customers were stored
+// by reference, where "customer" and "book" held full embedded copies, and
+// drops any expanded entities so only references are persisted.
+func (o Order) Normalized() Order {
+	if o.Customer != nil {
+		if o.CustomerID == 0 {
+			o.CustomerID = o.Customer.ID
+		}
+		if o.ShippingAddress == (customer.Address{}) {
+			o.ShippingAddress = o.Customer.Address
+		}
+		o.Customer = nil
+	}
+
+	items := make([]OrderItem, len(o.Items))
+	for i, item := range o.Items {
+		if item.Book != nil {
+			if item.BookID == 0 {
+				item.BookID = item.Book.ID
+			}
+			if item.Title == "" {
+				item.Title = item.Book.Title
+			}
+			if item.UnitPrice == 0 {
+				item.UnitPrice = item.Book.Price
+			}
+			item.Book = nil
+		}
+		items[i] = item
+	}
+	o.Items = items
+	return o
+}
+
+// Expand selects which referenced entities are embedded in order responses.
+type Expand struct {
+	Book     bool
+	Customer bool
+}
+
+// ErrNoOrders is returned by List, and GetOrdersInTimeRange, when there
+// are no orders.
+var ErrNoOrders = errors.New("no orders found")
+
 type OrderStore interface {
 	Create(ctx context.Context, order Order) (Order, error)
 	GetByID(ctx context.Context, id int) (Order, error)
 	Update(ctx context.Context, id int, order Order) (Order, error)
 	Delete(ctx context.Context, id int) error
 	List(ctx context.Context) ([]Order, error)
+	Restore(ctx context.Context, order Order) error
+	// HasPurchased reports whether any order of customerID that was not
+	// cancelled contains bookID.
+	HasPurchased(ctx context.Context, customerID, bookID int) (bool, error)
 
 	GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]Order, error)
 }
+
+// OrderPlacer is implemented by stores that can validate stock, decrement it
+// and record an order atomically.
+type OrderPlacer interface {
+	PlaceOrder(ctx context.Context, order Order) (Order, error)
+}
@@ -3,12 +3,14 @@ package order
 import (
 	"context"
 	"fmt"
-	"log"
+	"slices"
 	"sync"
 	"time"
 
 	"um6p.ma/final_project/internal/book"
 	"um6p.ma/final_project/internal/customer"
+	"um6p.ma/final_project/internal/inventory"
+	"um6p.ma/final_project/pkg/logging"
 )
 
 type InMemoryOrderStore struct {
@@ -29,7 +31,7 @@ func (store *InMemoryOrderStore) GetByID(ctx context.Context, id int) (Order, er
 
 	order, found := store.orders[id]
 	if !found {
-		log.Printf("order with ID %d not found", id)
+		logging.Printf(ctx, "order with ID %d not found", id)
 		return Order{}, fmt.Errorf("order with ID %d not found", id)
 	}
 
@@ -48,9 +50,10 @@ func (store *InMemoryOrderStore) Update(ctx context.Context, id int, order Order
 
 	_, found := store.orders[id]
 	if !found {
-		log.Printf("order with ID %d not found", id)
+		logging.Printf(ctx, "order with ID %d not found", id)
 		return Order{}, fmt.Errorf("order with ID %d not found", id)
 	}
+	order = order.Normalized()
 	order.ID = id
 	store.orders[id] = order
 
@@ -69,7 +72,7 @@ func (store *InMemoryOrderStore) Delete(ctx context.Context, id int) error {
 
 	_, found := store.orders[id]
 	if !found {
-		log.Printf("order with ID %d not found", id)
+		logging.Printf(ctx, "order with ID %d not found", id)
 		return fmt.Errorf("order with ID %d not found", id)
 	}
 
@@ -92,8 +95,8 @@ func (store *InMemoryOrderStore) List(ctx context.Context) ([]Order, error) {
 		all = append(all, order)
 	}
 	if len(all) == 0 {
-		log.Printf("no orders found")
-		return nil, fmt.Errorf("no orders found")
+		logging.Printf(ctx, "no orders found")
+		return nil, ErrNoOrders
 	}
 	return all, nil
 }
@@ -108,55 +111,127 @@ func (store *InMemoryOrderStore) Create(ctx context.Context, order Order) (Order
 	default:
 	}
 
+	order = order.Normalized()
 	order.ID = store.nextID
 	store.nextID++
 	store.orders[order.ID] = order
 
-	log.Printf("Order with ID %d created", order.ID)
+	logging.Printf(ctx, "Order with ID %d created", order.ID)
 	return order, nil
 }
 
+// Restore stores order under its own ID, as needed when importing a backup,
+// and moves nextID past it.
+func (store *InMemoryOrderStore) Restore(ctx context.Context, order Order) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if order.ID <= 0 {
+		return fmt.Errorf("invalid order ID %d", order.ID)
+	}
+	store.orders[order.ID] = order.Normalized()
+	store.nextID = max(store.nextID, order.ID+1)
+	return nil
+}
+
+func (store *InMemoryOrderStore) HasPurchased(ctx context.Context, customerID, bookID int) (bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	default:
+	}
+
+	for _, o := range store.orders {
+		if o.CustomerID != customerID || o.Cancelled() {
+			continue
+		}
+		if slices.ContainsFunc(o.Items, func(item OrderItem) bool { return item.BookID == bookID }) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 type Service interface {
 	CreateOrder(ctx context.Context, o Order) (Order, error)
 	GetOrderByID(ctx context.Context, id int) (Order, error)
 	UpdateOrder(ctx context.Context, id int, o Order) error
 	DeleteOrder(ctx context.Context, id int) error
 	ListOrders(ctx context.Context) ([]Order, error)
+	ExpandOrders(ctx context.Context, orders []Order, expand Expand) []Order
 }
 
 type service struct {
 	store         OrderStore
 	customerStore customer.CustomerStore
 	bookStore     book.BookStore
+	stock         inventory.StockTaker
 }
 
-func NewService(orderStore OrderStore, cStore customer.CustomerStore, bStore book.BookStore) Service {
+// NewService returns the order service. Stores that are not an OrderPlacer
+// take the stock of an order through stock.
+func NewService(orderStore OrderStore, cStore customer.CustomerStore, bStore book.BookStore, stock inventory.StockTaker) Service {
 	return &service{
 		store:         orderStore,
 		customerStore: cStore,
 		bookStore:     bStore,
+		stock:         stock,
 	}
 }
 func (s *service) CreateOrder(ctx context.Context, o Order) (Order, error) {
-	existingCustomer, err := s.customerStore.GetCustomerByID(ctx, o.Customer.ID)
+	o = o.Normalized()
+	if o.CustomerID <= 0 {
+		return Order{}, fmt.Errorf("customer_id is required")
+	}
+	if len(o.Items) == 0 {
+		return Order{}, fmt.Errorf("order must contain at least one item")
+	}
+	for _, item := range o.Items {
+		if item.Quantity <= 0 {
+			return Order{}, fmt.Errorf("invalid quantity %d for book with ID %d", item.Quantity, item.BookID)
+		}
+	}
+
+	if placer, ok := s.store.(OrderPlacer); ok {
+		return placer.PlaceOrder(ctx, o)
+	}
+
+	existingCustomer, err := s.customerStore.GetCustomerByID(ctx, o.CustomerID)
 	if err != nil {
-		return Order{}, fmt.Errorf("customer with ID %d not found: %w", o.Customer.ID, err)
+		return Order{}, fmt.Errorf("customer with ID %d not found: %w", o.CustomerID, err)
+	}
+	if o.ShippingAddress == (customer.Address{}) {
+		o.ShippingAddress = existingCustomer.Address
 	}
-	o.Customer = existingCustomer
 
+	sales := make([]inventory.Movement, len(o.Items))
 	for i, item := range o.Items {
-		b, err := s.bookStore.GetBook(ctx, item.Book.ID)
+		b, err := s.bookStore.GetBook(ctx, item.BookID)
 		if err != nil {
-			return Order{}, fmt.Errorf("book with ID %d not found: %w", item.Book.ID, err)
+			return Order{}, fmt.Errorf("book with ID %d not found: %w", item.BookID, err)
 		}
-		if b.Stock < item.Quantity {
-			return Order{}, fmt.Errorf("insufficient stock for book with ID %d (available=%d, needed=%d)", b.ID, b.Stock, item.Quantity)
+		j := b.EditionIndex(item.EditionID)
+		if j < 0 {
+			return Order{}, fmt.Errorf("edition %d not found for book with ID %d", item.EditionID, b.ID)
 		}
-		b.Stock -= item.Quantity
-		if _, err := s.bookStore.UpdateBook(ctx, b.ID, b); err != nil {
-			return Order{}, fmt.Errorf("failed to update book with ID %d: %w", b.ID, err)
+		edition := b.Editions[j]
+		if edition.Stock < item.Quantity {
+			return Order{}, fmt.Errorf("insufficient stock for edition %d of book with ID %d (available=%d, needed=%d)", edition.ID, b.ID, edition.Stock, item.Quantity)
+		}
+		o.Items[i].EditionID = edition.ID
+		o.Items[i].Title = b.Title
+		o.Items[i].Format = edition.Format
+		o.Items[i].UnitPrice = edition.Price
+		sales[i] = inventory.Movement{
+			BookID:    b.ID,
+			EditionID: edition.ID,
+			Reason:    inventory.ReasonSale,
+			Delta:     -item.Quantity,
+			Actor:     saleActor(o.CustomerID),
 		}
-		o.Items[i].Book = b
 	}
 	errChan := make(chan error, len(o.Items))
 	var wg sync.WaitGroup
@@ -173,7 +248,7 @@ func (s *service) CreateOrder(ctx context.Context, o Order) (Order, error) {
 				errChan <- ctx.Err()
 				return
 			default:
-				cost := it.Book.Price * float64(it.Quantity)
+				cost := it.UnitPrice * float64(it.Quantity)
 
 				mu.Lock()
 				totalPrice += cost
@@ -192,18 +267,36 @@ func (s *service) CreateOrder(ctx context.Context, o Order) (Order, error) {
 	o.TotalPrice = totalPrice
 	o.Status = "Pending"
 
-	newOrder, err := s.store.Create(ctx, o)
+	// The stock checked above may have been sold since. It is taken before
+	// the order is saved, so that the order is only saved if all of its
+	// stock could be taken and a failed save puts the stock back.
+	for i := range sales {
+		sales[i].CreatedAt = o.CreatedAt
+	}
+	var newOrder Order
+	_, err = s.stock.TakeStock(ctx, sales, func() (int, error) {
+		var err error
+		if newOrder, err = s.store.Create(ctx, o); err != nil {
+			return 0, fmt.Errorf("failed to create order: %w", err)
+		}
+		return newOrder.ID, nil
+	})
 	if err != nil {
-		return Order{}, fmt.Errorf("failed to create order: %w", err)
+		return Order{}, err
 	}
 	return newOrder, nil
 }
 
+// saleActor names the customer who placed an order in the stock ledger.
+func saleActor(customerID int) string {
+	return fmt.Sprintf("customer:%d", customerID)
+}
+
 func (s *service) GetOrderByID(ctx context.Context, id int) (Order, error) {
 	return s.store.GetByID(ctx, id)
 }
 func (s *service) UpdateOrder(ctx context.Context, id int, o Order) error {
-	_, err := s.store.Update(ctx, id, o)
+	_, err := s.store.Update(ctx, id, o.Normalized())
 	return err
 }
 func (s *service) DeleteOrder(ctx context.Context, id int) error {
@@ -212,6 +305,44 @@ func (s *service) DeleteOrder(ctx context.Context, id int) error {
 func (s *service) ListOrders(ctx context.Context) ([]Order, error) {
 	return s.store.List(ctx)
 }
+
+// ExpandOrders fills in the current customer and book records referenced by
+// orders. References whose entity has since been deleted are left unexpanded.
+func (s *service) ExpandOrders(ctx context.Context, orders []Order, expand Expand) []Order {
+	customers := make(map[int]*customer.Customer)
+	books := make(map[int]*book.Book)
+
+	out := make([]Order, len(orders))
+	for i, o := range orders {
+		if expand.Customer {
+			c, ok := customers[o.CustomerID]
+			if !ok {
+				if found, err := s.customerStore.GetCustomerByID(ctx, o.CustomerID); err == nil {
+					c = &found
+				}
+				customers[o.CustomerID] = c
+			}
+			o.Customer = c
+		}
+		if expand.Book {
+			items := make([]OrderItem, len(o.Items))
+			for j, item := range o.Items {
+				b, ok := books[item.BookID]
+				if !ok {
+					if found, err := s.bookStore.GetBook(ctx, item.BookID); err == nil {
+						b = &found
+					}
+					books[item.BookID] = b
+				}
+				item.Book = b
+				items[j] = item
+			}
+			o.Items = items
+		}
+		out[i] = o
+	}
+	return out
+}
 func (store *InMemoryOrderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]Order, error) {
 	store.mu.RLock()
 	defer store.mu.RUnlock()
@@ -224,7 +355,7 @@ func (store *InMemoryOrderStore) GetOrdersInTimeRange(ctx context.Context, start
 	}
 
 	if len(result) == 0 {
-		return nil, fmt.Errorf("no orders found between %s and %s", start, end)
+		return nil, fmt.Errorf("%w between %s and %s", ErrNoOrders, start, end)
 	}
 	return result, nil
 }
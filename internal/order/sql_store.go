@@ -0,0 +1,325 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/customer"
+	"um6p.ma/final_project/internal/inventory"
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+const orderSelect = `SELECT o.id, o.customer_id, o.total_price, o.created_at, o.status,
+	o.ship_street, o.ship_city, o.ship_state, o.ship_postal_code, o.ship_country
+	FROM orders o`
+
+const itemSelect = `SELECT oi.order_id, oi.book_id, COALESCE(oi.edition_id, 0), oi.title, oi.format, oi.unit_price, oi.quantity FROM order_items oi`
+
+type SQLOrderStore struct {
+	db *sql.DB
+}
+
+func NewSQLOrderStore(db *sql.DB) *SQLOrderStore {
+	return &SQLOrderStore{db: db}
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func scanOrder(rows *sql.Rows) (Order, error) {
+	var o Order
+	a := &o.ShippingAddress
+	err := rows.Scan(&o.ID, &o.CustomerID, &o.TotalPrice, &o.CreatedAt, &o.Status,
+		&a.Street, &a.City, &a.State, &a.PostalCode, &a.Country)
+	return o, err
+}
+
+// queryOrders loads the orders matching where together with their items.
+func queryOrders(ctx context.Context, q queryer, where string, args ...any) ([]Order, error) {
+	rows, err := q.QueryContext(ctx, orderSelect+" "+where+" ORDER BY o.id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	var orders []Order
+	index := make(map[int]int)
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[o.ID] = len(orders)
+		orders = append(orders, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return orders, nil
+	}
+
+	itemRows, err := q.QueryContext(ctx,
+		itemSelect+` WHERE oi.order_id IN (SELECT o.id FROM orders o `+where+`) ORDER BY oi.order_id, oi.position`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order items: %w", err)
+	}
+	defer itemRows.Close()
+	for itemRows.Next() {
+		var orderID int
+		var item OrderItem
+		if err := itemRows.Scan(&orderID, &item.BookID, &item.EditionID, &item.Title, &item.Format, &item.UnitPrice, &item.Quantity); err != nil {
+			return nil, err
+		}
+		if i, ok := index[orderID]; ok {
+			orders[i].Items = append(orders[i].Items, item)
+		}
+	}
+	return orders, itemRows.Err()
+}
+
+func insertItems(ctx context.Context, q queryer, orderID int, items []OrderItem) error {
+	for pos, item := range items {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO order_items (order_id, position, book_id, edition_id, title, format, quantity, unit_price)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			orderID, pos, item.BookID, sqlutil.NullID(item.EditionID), item.Title, string(item.Format), item.Quantity, item.UnitPrice)
+		if err != nil {
+			return fmt.Errorf("failed to insert item for book %d: %w", item.BookID, err)
+		}
+	}
+	return nil
+}
+
+func insertOrder(ctx context.Context, tx *sql.Tx, order Order) (Order, error) {
+	order = order.Normalized()
+	a := order.ShippingAddress
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO orders (customer_id, total_price, created_at, status,
+		 ship_street, ship_city, ship_state, ship_postal_code, ship_country)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		order.CustomerID, order.TotalPrice, order.CreatedAt, order.Status,
+		a.Street, a.City, a.State, a.PostalCode, a.Country,
+	).Scan(&order.ID)
+	if err != nil {
+		return Order{}, fmt.Errorf("failed to insert order: %w", err)
+	}
+	if err := insertItems(ctx, tx, order.ID, order.Items); err != nil {
+		return Order{}, err
+	}
+	return order, nil
+}
+
+func (store *SQLOrderStore) Create(ctx context.Context, order Order) (Order, error) {
+	var created Order
+	err := sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		var err error
+		created, err = insertOrder(ctx, tx, order)
+		return err
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	return created, nil
+}
+
+// PlaceOrder checks and decrements stock and records the order and its sale
+// movements in a single transaction, locking the affected edition rows until
+// it commits.
+func (store *SQLOrderStore) PlaceOrder(ctx context.Context, o Order) (Order, error) {
+	err := sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		var addr customer.Address
+		err := tx.QueryRowContext(ctx,
+			`SELECT street, city, state, postal_code, country FROM customers WHERE id = $1`,
+			o.CustomerID,
+		).Scan(&addr.Street, &addr.City, &addr.State, &addr.PostalCode, &addr.Country)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("customer with ID %d not found", o.CustomerID)
+		}
+		if err != nil {
+			return err
+		}
+		if o.ShippingAddress == (customer.Address{}) {
+			o.ShippingAddress = addr
+		}
+
+		var total float64
+		stockAfter := make([]int, len(o.Items))
+		for i, item := range o.Items {
+			var title string
+			var e book.Edition
+			err := tx.QueryRowContext(ctx,
+				`SELECT b.title, e.id, e.format, e.price, e.stock
+				 FROM editions e JOIN books b ON b.id = e.book_id
+				 WHERE e.book_id = $1 AND (e.id = $2 OR ($2 = 0 AND e.position = 0))
+				 FOR UPDATE OF e`,
+				item.BookID, item.EditionID,
+			).Scan(&title, &e.ID, &e.Format, &e.Price, &e.Stock)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("edition %d not found for book with ID %d", item.EditionID, item.BookID)
+			}
+			if err != nil {
+				return err
+			}
+			if e.Stock < item.Quantity {
+				return fmt.Errorf("insufficient stock for edition %d of book with ID %d (available=%d, needed=%d)", e.ID, item.BookID, e.Stock, item.Quantity)
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE editions SET stock = stock - $2 WHERE id = $1`, e.ID, item.Quantity); err != nil {
+				return fmt.Errorf("failed to update edition %d: %w", e.ID, err)
+			}
+			stockAfter[i] = e.Stock - item.Quantity
+			o.Items[i].EditionID = e.ID
+			o.Items[i].Title = title
+			o.Items[i].Format = e.Format
+			o.Items[i].UnitPrice = e.Price
+			total += e.Price * float64(item.Quantity)
+		}
+
+		o.CreatedAt = time.Now()
+		o.TotalPrice = total
+		o.Status = "Pending"
+		if o, err = insertOrder(ctx, tx, o); err != nil {
+			return err
+		}
+		for i, item := range o.Items {
+			_, err := inventory.InsertMovement(ctx, tx, inventory.Movement{
+				BookID:     item.BookID,
+				EditionID:  item.EditionID,
+				Reason:     inventory.ReasonSale,
+				Delta:      -item.Quantity,
+				StockAfter: stockAfter[i],
+				Actor:      saleActor(o.CustomerID),
+				OrderID:    o.ID,
+				CreatedAt:  o.CreatedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	return o, nil
+}
+
+func (store *SQLOrderStore) GetByID(ctx context.Context, id int) (Order, error) {
+	orders, err := queryOrders(ctx, store.db, "WHERE o.id = $1", id)
+	if err != nil {
+		return Order{}, err
+	}
+	if len(orders) == 0 {
+		return Order{}, fmt.Errorf("order with ID %d not found", id)
+	}
+	return orders[0], nil
+}
+
+func (store *SQLOrderStore) Update(ctx context.Context, id int, order Order) (Order, error) {
+	order = order.Normalized()
+	err := sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		a := order.ShippingAddress
+		res, err := tx.ExecContext(ctx,
+			`UPDATE orders SET customer_id = $2, total_price = $3, created_at = $4, status = $5,
+			 ship_street = $6, ship_city = $7, ship_state = $8, ship_postal_code = $9, ship_country = $10
+			 WHERE id = $1`,
+			id, order.CustomerID, order.TotalPrice, order.CreatedAt, order.Status,
+			a.Street, a.City, a.State, a.PostalCode, a.Country)
+		if err != nil {
+			return fmt.Errorf("failed to update order %d: %w", id, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("order with ID %d not found", id)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, id); err != nil {
+			return err
+		}
+		return insertItems(ctx, tx, id, order.Items)
+	})
+	if err != nil {
+		return Order{}, err
+	}
+	order.ID = id
+	return order, nil
+}
+
+func (store *SQLOrderStore) Delete(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM orders WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete order %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("order with ID %d not found", id)
+	}
+	return nil
+}
+
+func (store *SQLOrderStore) List(ctx context.Context) ([]Order, error) {
+	orders, err := queryOrders(ctx, store.db, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, ErrNoOrders
+	}
+	return orders, nil
+}
+
+func (store *SQLOrderStore) HasPurchased(ctx context.Context, customerID, bookID int) (bool, error) {
+	var found bool
+	err := store.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM orders o JOIN order_items oi ON oi.order_id = o.id
+		 WHERE o.customer_id = $1 AND oi.book_id = $2 AND lower(o.status) <> lower($3))`,
+		customerID, bookID, StatusCancelled,
+	).Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up orders of customer %d: %w", customerID, err)
+	}
+	return found, nil
+}
+
+func (store *SQLOrderStore) GetOrdersInTimeRange(ctx context.Context, start, end time.Time) ([]Order, error) {
+	orders, err := queryOrders(ctx, store.db, "WHERE o.created_at > $1 AND o.created_at < $2", start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, fmt.Errorf("%w between %s and %s", ErrNoOrders, start, end)
+	}
+	return orders, nil
+}
+
+func (store *SQLOrderStore) Restore(ctx context.Context, order Order) error {
+	order = order.Normalized()
+	return sqlutil.InTx(ctx, store.db, func(tx *sql.Tx) error {
+		a := order.ShippingAddress
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO orders (id, customer_id, total_price, created_at, status,
+			 ship_street, ship_city, ship_state, ship_postal_code, ship_country)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			 ON CONFLICT (id) DO UPDATE
+			 SET customer_id = EXCLUDED.customer_id, total_price = EXCLUDED.total_price,
+			     created_at = EXCLUDED.created_at, status = EXCLUDED.status,
+			     ship_street = EXCLUDED.ship_street, ship_city = EXCLUDED.ship_city, ship_state = EXCLUDED.ship_state,
+			     ship_postal_code = EXCLUDED.ship_postal_code, ship_country = EXCLUDED.ship_country`,
+			order.ID, order.CustomerID, order.TotalPrice, order.CreatedAt, order.Status,
+			a.Street, a.City, a.State, a.PostalCode, a.Country)
+		if err != nil {
+			return fmt.Errorf("failed to restore order %d: %w", order.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM order_items WHERE order_id = $1`, order.ID); err != nil {
+			return err
+		}
+		if err := insertItems(ctx, tx, order.ID, order.Items); err != nil {
+			return err
+		}
+		return sqlutil.ResetSequence(ctx, tx, "orders")
+	})
+}
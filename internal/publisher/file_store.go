@@ -0,0 +1,84 @@
+package publisher
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID     int         `json:"next_id"`
+	Publishers []Publisher `json:"publishers"`
+}
+
+func (store *InMemoryPublisherStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	snap := storeSnapshot{NextID: store.nextID, Publishers: make([]Publisher, 0, len(store.publishers))}
+	for _, p := range store.publishers {
+		snap.Publishers = append(snap.Publishers, p)
+	}
+	return snap
+}
+
+func (store *InMemoryPublisherStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.publishers = make(map[int]Publisher, len(snap.Publishers))
+	store.nextID = max(snap.NextID, 1)
+	for _, p := range snap.Publishers {
+		store.publishers[p.ID] = p
+		store.nextID = max(store.nextID, p.ID+1)
+	}
+}
+
+type FilePublisherStore struct {
+	*InMemoryPublisherStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileStore(dir string) (*FilePublisherStore, error) {
+	mem := NewStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "publishers.json"), "publishers", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisherStore{InMemoryPublisherStore: mem, file: file}, nil
+}
+
+func (store *FilePublisherStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FilePublisherStore) CreatePublisher(ctx context.Context, p Publisher) (Publisher, error) {
+	created, err := store.InMemoryPublisherStore.CreatePublisher(ctx, p)
+	if err != nil {
+		return Publisher{}, err
+	}
+	return created, store.file.Save()
+}
+
+func (store *FilePublisherStore) UpdatePublisher(ctx context.Context, id int, p Publisher) (Publisher, error) {
+	updated, err := store.InMemoryPublisherStore.UpdatePublisher(ctx, id, p)
+	if err != nil {
+		return Publisher{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FilePublisherStore) DeletePublisher(ctx context.Context, id int) error {
+	if err := store.InMemoryPublisherStore.DeletePublisher(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FilePublisherStore) RestorePublisher(ctx context.Context, p Publisher) error {
+	if err := store.InMemoryPublisherStore.RestorePublisher(ctx, p); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
@@ -0,0 +1,123 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+)
+
+func NewStore() *InMemoryPublisherStore {
+	return &InMemoryPublisherStore{
+		publishers: make(map[int]Publisher),
+		nextID:     1,
+	}
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/publishers", h.ListPublishers)
+	r.HandleFunc(http.MethodPost, "/publishers", h.CreatePublisher)
+	r.HandleFunc(http.MethodGet, "/publishers/{id}", h.GetPublisher)
+	r.HandleFunc(http.MethodPut, "/publishers/{id}", h.UpdatePublisher)
+	r.HandleFunc(http.MethodDelete, "/publishers/{id}", h.DeletePublisher)
+}
+
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInUse):
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+func (h *Handler) ListPublishers(w http.ResponseWriter, r *http.Request) {
+	publishers, err := h.svc.ListPublishers(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(publishers)
+}
+
+func (h *Handler) CreatePublisher(w http.ResponseWriter, r *http.Request) {
+	var p Publisher
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.svc.CreatePublisher(r.Context(), p)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) GetPublisher(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid publisher ID", http.StatusBadRequest)
+		return
+	}
+
+	p, err := h.svc.GetPublisher(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *Handler) UpdatePublisher(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid publisher ID", http.StatusBadRequest)
+		return
+	}
+
+	var p Publisher
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.svc.UpdatePublisher(r.Context(), id, p)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeletePublisher(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid publisher ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeletePublisher(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,93 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryPublisherStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var p Publisher
+		if err := json.Unmarshal(rec.Data, &p); err != nil {
+			return fmt.Errorf("invalid publisher record: %w", err)
+		}
+		store.publishers[p.ID] = p
+		store.nextID = max(store.nextID, p.ID+1)
+	case journal.OpDelete:
+		delete(store.publishers, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledPublisherStore struct {
+	*InMemoryPublisherStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledStore(dir string, opts journal.Options) (*JournaledPublisherStore, error) {
+	mem := NewStore()
+	j, err := journal.OpenStore(dir, "publishers", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledPublisherStore{InMemoryPublisherStore: mem, journal: j}, nil
+}
+
+func (store *JournaledPublisherStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledPublisherStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledPublisherStore) CreatePublisher(ctx context.Context, p Publisher) (Publisher, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	created, err := store.InMemoryPublisherStore.CreatePublisher(ctx, p)
+	if err != nil {
+		return Publisher{}, err
+	}
+	return created, store.journal.Record(journal.OpCreate, created.ID, created)
+}
+
+func (store *JournaledPublisherStore) UpdatePublisher(ctx context.Context, id int, p Publisher) (Publisher, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemoryPublisherStore.UpdatePublisher(ctx, id, p)
+	if err != nil {
+		return Publisher{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledPublisherStore) DeletePublisher(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryPublisherStore.DeletePublisher(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledPublisherStore) RestorePublisher(ctx context.Context, p Publisher) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryPublisherStore.RestorePublisher(ctx, p); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, p.ID, p)
+}
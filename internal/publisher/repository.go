@@ -0,0 +1,35 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNotFound = errors.New("publisher not found")
+	ErrInUse    = errors.New("publisher in use")
+)
+
+// Publisher is a publishing house books are bought from. Email and Website
+// are the contacts used for terms and returns.
+type Publisher struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Website string `json:"website,omitempty"`
+}
+
+type PublisherStore interface {
+	CreatePublisher(ctx context.Context, p Publisher) (Publisher, error)
+	GetPublisher(ctx context.Context, id int) (Publisher, error)
+	UpdatePublisher(ctx context.Context, id int, p Publisher) (Publisher, error)
+	DeletePublisher(ctx context.Context, id int) error
+	ListPublishers(ctx context.Context) ([]Publisher, error)
+	RestorePublisher(ctx context.Context, p Publisher) error
+}
+
+// BookCounter reports how many books reference a publisher, so publishers
+// that are still used cannot be deleted.
+type BookCounter interface {
+	CountBooksByPublisher(ctx context.Context, publisherID int) (int, error)
+}
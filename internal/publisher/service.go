@@ -0,0 +1,188 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+
+	"um6p.ma/final_project/pkg/logging"
+)
+
+type InMemoryPublisherStore struct {
+	mu         sync.RWMutex
+	publishers map[int]Publisher
+	nextID     int
+}
+
+// nameTaken reports whether a publisher other than id is already called
+// name. Callers hold the lock.
+func (store *InMemoryPublisherStore) nameTaken(id int, name string) bool {
+	for _, p := range store.publishers {
+		if p.ID != id && strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (store *InMemoryPublisherStore) CreatePublisher(ctx context.Context, p Publisher) (Publisher, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.nameTaken(0, p.Name) {
+		logging.Printf(ctx, "publisher %s already exists", p.Name)
+		return Publisher{}, fmt.Errorf("publisher %s already exists", p.Name)
+	}
+	p.ID = store.nextID
+	store.nextID++
+	store.publishers[p.ID] = p
+	return p, nil
+}
+
+func (store *InMemoryPublisherStore) GetPublisher(ctx context.Context, id int) (Publisher, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	p, found := store.publishers[id]
+	if !found {
+		logging.Printf(ctx, "publisher with ID %d not found", id)
+		return Publisher{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return p, nil
+}
+
+func (store *InMemoryPublisherStore) UpdatePublisher(ctx context.Context, id int, p Publisher) (Publisher, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.publishers[id]; !found {
+		logging.Printf(ctx, "publisher with ID %d not found", id)
+		return Publisher{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if store.nameTaken(id, p.Name) {
+		return Publisher{}, fmt.Errorf("publisher %s already exists", p.Name)
+	}
+	p.ID = id
+	store.publishers[id] = p
+	return p, nil
+}
+
+func (store *InMemoryPublisherStore) DeletePublisher(ctx context.Context, id int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.publishers[id]; !found {
+		logging.Printf(ctx, "publisher with ID %d not found", id)
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	delete(store.publishers, id)
+	return nil
+}
+
+func (store *InMemoryPublisherStore) ListPublishers(ctx context.Context) ([]Publisher, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	all := make([]Publisher, 0, len(store.publishers))
+	for _, p := range store.publishers {
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all, nil
+}
+
+// RestorePublisher stores p under its own ID and moves nextID past it.
+func (store *InMemoryPublisherStore) RestorePublisher(ctx context.Context, p Publisher) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if p.ID <= 0 {
+		return fmt.Errorf("invalid publisher ID %d", p.ID)
+	}
+	store.publishers[p.ID] = p
+	store.nextID = max(store.nextID, p.ID+1)
+	return nil
+}
+
+type Service interface {
+	CreatePublisher(ctx context.Context, p Publisher) (Publisher, error)
+	GetPublisher(ctx context.Context, id int) (Publisher, error)
+	UpdatePublisher(ctx context.Context, id int, p Publisher) (Publisher, error)
+	DeletePublisher(ctx context.Context, id int) error
+	ListPublishers(ctx context.Context) ([]Publisher, error)
+}
+
+type service struct {
+	store PublisherStore
+	books BookCounter
+}
+
+func NewService(store PublisherStore, books BookCounter) Service {
+	return &service{store: store, books: books}
+}
+
+func validate(p Publisher) (Publisher, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Email = strings.TrimSpace(p.Email)
+	p.Website = strings.TrimSpace(p.Website)
+	if p.Name == "" {
+		return Publisher{}, fmt.Errorf("publisher name is required")
+	}
+	if p.Email != "" {
+		if _, err := mail.ParseAddress(p.Email); err != nil {
+			return Publisher{}, fmt.Errorf("invalid email %q", p.Email)
+		}
+	}
+	return p, nil
+}
+
+func (s *service) CreatePublisher(ctx context.Context, p Publisher) (Publisher, error) {
+	p, err := validate(p)
+	if err != nil {
+		return Publisher{}, err
+	}
+	return s.store.CreatePublisher(ctx, p)
+}
+
+func (s *service) GetPublisher(ctx context.Context, id int) (Publisher, error) {
+	return s.store.GetPublisher(ctx, id)
+}
+
+func (s *service) UpdatePublisher(ctx context.Context, id int, p Publisher) (Publisher, error) {
+	if _, err := s.store.GetPublisher(ctx, id); err != nil {
+		return Publisher{}, err
+	}
+	p, err := validate(p)
+	if err != nil {
+		return Publisher{}, err
+	}
+	return s.store.UpdatePublisher(ctx, id, p)
+}
+
+// DeletePublisher refuses to delete a publisher that books still reference.
+func (s *service) DeletePublisher(ctx context.Context, id int) error {
+	if _, err := s.store.GetPublisher(ctx, id); err != nil {
+		return err
+	}
+	n, err := s.books.CountBooksByPublisher(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: publisher %d has %d book(s)", ErrInUse, id, n)
+	}
+	return s.store.DeletePublisher(ctx, id)
+}
+
+func (s *service) ListPublishers(ctx context.Context) ([]Publisher, error) {
+	return s.store.ListPublishers(ctx)
+}
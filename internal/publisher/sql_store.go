@@ -0,0 +1,103 @@
+package publisher
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+type SQLPublisherStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLPublisherStore {
+	return &SQLPublisherStore{db: db}
+}
+
+func (store *SQLPublisherStore) CreatePublisher(ctx context.Context, p Publisher) (Publisher, error) {
+	err := store.db.QueryRowContext(ctx,
+		`INSERT INTO publishers (name, email, website) VALUES ($1, $2, $3)
+		 ON CONFLICT DO NOTHING
+		 RETURNING id`,
+		p.Name, p.Email, p.Website,
+	).Scan(&p.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Publisher{}, fmt.Errorf("publisher %s already exists", p.Name)
+	}
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed to create publisher: %w", err)
+	}
+	return p, nil
+}
+
+func (store *SQLPublisherStore) GetPublisher(ctx context.Context, id int) (Publisher, error) {
+	var p Publisher
+	err := store.db.QueryRowContext(ctx,
+		`SELECT id, name, email, website FROM publishers WHERE id = $1`, id,
+	).Scan(&p.ID, &p.Name, &p.Email, &p.Website)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Publisher{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed to get publisher %d: %w", id, err)
+	}
+	return p, nil
+}
+
+func (store *SQLPublisherStore) UpdatePublisher(ctx context.Context, id int, p Publisher) (Publisher, error) {
+	res, err := store.db.ExecContext(ctx,
+		`UPDATE publishers SET name = $2, email = $3, website = $4 WHERE id = $1`,
+		id, p.Name, p.Email, p.Website,
+	)
+	if err != nil {
+		return Publisher{}, fmt.Errorf("failed to update publisher %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Publisher{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	p.ID = id
+	return p, nil
+}
+
+func (store *SQLPublisherStore) DeletePublisher(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM publishers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete publisher %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+func (store *SQLPublisherStore) ListPublishers(ctx context.Context) ([]Publisher, error) {
+	rows, err := store.db.QueryContext(ctx, `SELECT id, name, email, website FROM publishers ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list publishers: %w", err)
+	}
+	defer rows.Close()
+
+	publishers := make([]Publisher, 0)
+	for rows.Next() {
+		var p Publisher
+		if err := rows.Scan(&p.ID, &p.Name, &p.Email, &p.Website); err != nil {
+			return nil, err
+		}
+		publishers = append(publishers, p)
+	}
+	return publishers, rows.Err()
+}
+
+func (store *SQLPublisherStore) RestorePublisher(ctx context.Context, p Publisher) error {
+	err := sqlutil.RestoreRow(ctx, store.db, "publishers",
+		`INSERT INTO publishers (id, name, email, website) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, email = EXCLUDED.email, website = EXCLUDED.website`,
+		p.ID, p.Name, p.Email, p.Website)
+	if err != nil {
+		return fmt.Errorf("failed to restore publisher %d: %w", p.ID, err)
+	}
+	return nil
+}
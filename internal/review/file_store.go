@@ -0,0 +1,91 @@
+package review
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID  int      `json:"next_id"`
+	Reviews []Review `json:"reviews"`
+}
+
+func (store *InMemoryReviewStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	snap := storeSnapshot{NextID: store.nextID, Reviews: make([]Review, 0, len(store.reviews))}
+	for _, r := range store.reviews {
+		snap.Reviews = append(snap.Reviews, r)
+	}
+	return snap
+}
+
+func (store *InMemoryReviewStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.reviews = make(map[int]Review, len(snap.Reviews))
+	store.nextID = max(snap.NextID, 1)
+	for _, r := range snap.Reviews {
+		store.reviews[r.ID] = r
+		store.nextID = max(store.nextID, r.ID+1)
+	}
+}
+
+type FileReviewStore struct {
+	*InMemoryReviewStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileStore(dir string) (*FileReviewStore, error) {
+	mem := NewStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "reviews.json"), "reviews", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileReviewStore{InMemoryReviewStore: mem, file: file}, nil
+}
+
+func (store *FileReviewStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileReviewStore) CreateReview(ctx context.Context, r Review) (Review, error) {
+	created, err := store.InMemoryReviewStore.CreateReview(ctx, r)
+	if err != nil {
+		return Review{}, err
+	}
+	return created, store.file.Save()
+}
+
+func (store *FileReviewStore) UpdateReview(ctx context.Context, id int, r Review) (Review, error) {
+	updated, err := store.InMemoryReviewStore.UpdateReview(ctx, id, r)
+	if err != nil {
+		return Review{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FileReviewStore) DeleteReview(ctx context.Context, id int) error {
+	if err := store.InMemoryReviewStore.DeleteReview(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileReviewStore) DeleteBookReviews(ctx context.Context, bookID int) error {
+	if err := store.InMemoryReviewStore.DeleteBookReviews(ctx, bookID); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileReviewStore) RestoreReview(ctx context.Context, r Review) error {
+	if err := store.InMemoryReviewStore.RestoreReview(ctx, r); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
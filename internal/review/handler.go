@@ -0,0 +1,220 @@
+package review
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+)
+
+func NewStore() *InMemoryReviewStore {
+	return &InMemoryReviewStore{
+		reviews: make(map[int]Review),
+		nextID:  1,
+	}
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/books/{id}/reviews", h.ListBookReviews)
+	r.HandleFunc(http.MethodPost, "/books/{id}/reviews", h.CreateReview)
+	r.HandleFunc(http.MethodGet, "/reviews", h.ListReviews)
+	r.HandleFunc(http.MethodGet, "/reviews/{id}", h.GetReview)
+	r.HandleFunc(http.MethodPut, "/reviews/{id}/status", h.ModerateReview)
+	r.HandleFunc(http.MethodDelete, "/reviews/{id}", h.DeleteReview)
+}
+
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrBookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, ErrNotPurchased):
+		return http.StatusForbidden
+	}
+	return fallback
+}
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parseFilter reads the status, customer_id, page and limit query
+// parameters; pages are numbered from 1.
+func parseFilter(q url.Values) (Filter, int, error) {
+	f := Filter{Limit: defaultPageLimit}
+	page := 1
+
+	var err error
+	if v := q.Get("status"); v != "" {
+		if f.Status, err = ParseStatus(v); err != nil {
+			return Filter{}, 0, err
+		}
+	}
+	if v := q.Get("customer_id"); v != "" {
+		if f.CustomerID, err = strconv.Atoi(v); err != nil || f.CustomerID <= 0 {
+			return Filter{}, 0, fmt.Errorf("invalid customer_id %q", v)
+		}
+	}
+	if v := q.Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page <= 0 {
+			return Filter{}, 0, fmt.Errorf("invalid page %q", v)
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit <= 0 || f.Limit > maxPageLimit {
+			return Filter{}, 0, fmt.Errorf("invalid limit %q: must be between 1 and %d", v, maxPageLimit)
+		}
+	}
+	f.Offset = (page - 1) * f.Limit
+	return f, page, nil
+}
+
+func writePage(w http.ResponseWriter, reviews []Review, total, page, limit int) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Page{Reviews: reviews, Page: page, Limit: limit, Total: total})
+}
+
+// ListBookReviews pages through the approved reviews of a book, newest
+// first; status selects reviews in another moderation state.
+func (h *Handler) ListBookReviews(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+	f, page, err := parseFilter(r.URL.Query())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reviews, total, err := h.svc.BookReviews(r.Context(), id, f)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	writePage(w, reviews, total, page, f.Limit)
+}
+
+// CreateReview takes the customer_id, rating and text of a review. The
+// review waits for moderation before it is shown.
+func (h *Handler) CreateReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid book ID", http.StatusBadRequest)
+		return
+	}
+	var rv Review
+	if err := json.NewDecoder(r.Body).Decode(&rv); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+	rv.BookID = id
+
+	created, err := h.svc.CreateReview(r.Context(), rv)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// ListReviews pages through reviews of any book, e.g. with status=pending
+// as a moderation queue.
+func (h *Handler) ListReviews(w http.ResponseWriter, r *http.Request) {
+	f, page, err := parseFilter(r.URL.Query())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := r.URL.Query().Get("book_id"); v != "" {
+		if f.BookID, err = strconv.Atoi(v); err != nil || f.BookID <= 0 {
+			pkgError.WriteJSONError(w, fmt.Sprintf("invalid book_id %q", v), http.StatusBadRequest)
+			return
+		}
+	}
+
+	reviews, total, err := h.svc.ListReviews(r.Context(), f)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writePage(w, reviews, total, page, f.Limit)
+}
+
+func (h *Handler) GetReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	rv, err := h.svc.GetReview(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rv)
+}
+
+// ModerateReview sets the status of a review from a {"status": ...} body.
+func (h *Handler) ModerateReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid review ID", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+	status, err := ParseStatus(body.Status)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.svc.Moderate(r.Context(), id, status)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid review ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteReview(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
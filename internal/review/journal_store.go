@@ -0,0 +1,108 @@
+package review
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemoryReviewStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var r Review
+		if err := json.Unmarshal(rec.Data, &r); err != nil {
+			return fmt.Errorf("invalid review record: %w", err)
+		}
+		store.reviews[r.ID] = r
+		store.nextID = max(store.nextID, r.ID+1)
+	case journal.OpDelete:
+		delete(store.reviews, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledReviewStore struct {
+	*InMemoryReviewStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledStore(dir string, opts journal.Options) (*JournaledReviewStore, error) {
+	mem := NewStore()
+	j, err := journal.OpenStore(dir, "reviews", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledReviewStore{InMemoryReviewStore: mem, journal: j}, nil
+}
+
+func (store *JournaledReviewStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledReviewStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledReviewStore) CreateReview(ctx context.Context, r Review) (Review, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	created, err := store.InMemoryReviewStore.CreateReview(ctx, r)
+	if err != nil {
+		return Review{}, err
+	}
+	return created, store.journal.Record(journal.OpCreate, created.ID, created)
+}
+
+func (store *JournaledReviewStore) UpdateReview(ctx context.Context, id int, r Review) (Review, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemoryReviewStore.UpdateReview(ctx, id, r)
+	if err != nil {
+		return Review{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledReviewStore) DeleteReview(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryReviewStore.DeleteReview(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledReviewStore) DeleteBookReviews(ctx context.Context, bookID int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	store.InMemoryReviewStore.mu.Lock()
+	ids := store.deleteBookReviews(bookID)
+	store.InMemoryReviewStore.mu.Unlock()
+	for _, id := range ids {
+		if err := store.journal.Record(journal.OpDelete, id, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (store *JournaledReviewStore) RestoreReview(ctx context.Context, r Review) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemoryReviewStore.RestoreReview(ctx, r); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, r.ID, r)
+}
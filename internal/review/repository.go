@@ -0,0 +1,88 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"um6p.ma/final_project/internal/book"
+)
+
+var (
+	ErrNotFound     = errors.New("review not found")
+	ErrDuplicate    = errors.New("review already exists")
+	ErrNotPurchased = errors.New("book not purchased")
+	ErrBookNotFound = errors.New("book not found")
+)
+
+// Status is where a review is in moderation. Only approved reviews are
+// shown on books and count towards their rating.
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+func ParseStatus(s string) (Status, error) {
+	switch st := Status(s); st {
+	case StatusPending, StatusApproved, StatusRejected:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown review status %q (want pending, approved or rejected)", s)
+	}
+}
+
+// Review is a customer's 1-5 star rating of a book they bought, with an
+// optional text. A customer reviews a book at most once.
+type Review struct {
+	ID         int       `json:"id"`
+	BookID     int       `json:"book_id"`
+	CustomerID int       `json:"customer_id"`
+	Rating     int       `json:"rating"`
+	Text       string    `json:"text"`
+	Status     Status    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Filter selects reviews. Zero values leave the corresponding filter off,
+// and a zero Limit returns all matches after Offset.
+type Filter struct {
+	BookID     int
+	CustomerID int
+	Status     Status
+	Limit      int
+	Offset     int
+}
+
+func (f Filter) Matches(r Review) bool {
+	return (f.BookID == 0 || r.BookID == f.BookID) &&
+		(f.CustomerID == 0 || r.CustomerID == f.CustomerID) &&
+		(f.Status == "" || r.Status == f.Status)
+}
+
+// Page is one page of reviews, newest first. Total counts all matches.
+type Page struct {
+	Reviews []Review `json:"reviews"`
+	Page    int      `json:"page"`
+	Limit   int      `json:"limit"`
+	Total   int      `json:"total"`
+}
+
+type ReviewStore interface {
+	// CreateReview fails with ErrDuplicate if the customer already reviewed
+	// the book.
+	CreateReview(ctx context.Context, r Review) (Review, error)
+	GetReview(ctx context.Context, id int) (Review, error)
+	UpdateReview(ctx context.Context, id int, r Review) (Review, error)
+	DeleteReview(ctx context.Context, id int) error
+	DeleteBookReviews(ctx context.Context, bookID int) error
+	// ListReviews returns the reviews matching f, newest first, and how
+	// many match in total regardless of f.Limit and f.Offset.
+	ListReviews(ctx context.Context, f Filter) ([]Review, int, error)
+	RestoreReview(ctx context.Context, r Review) error
+	// BookRatings averages the approved reviews of each of bookIDs.
+	BookRatings(ctx context.Context, bookIDs []int) (map[int]book.Rating, error)
+}
@@ -0,0 +1,192 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/customer"
+	"um6p.ma/final_project/internal/order"
+)
+
+func TestRoundRating(t *testing.T) {
+	tests := []struct {
+		in, want float64
+	}{
+		{4, 4},
+		{11.0 / 3, 3.67},
+		{13.0 / 3, 4.33},
+		{4.125, 4.13},
+		{1.004, 1},
+	}
+	for _, tt := range tests {
+		if got := roundRating(tt.in); got != tt.want {
+			t.Errorf("roundRating(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBookRatings(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	reviews := []Review{
+		{BookID: 1, CustomerID: 1, Rating: 5, Status: StatusApproved},
+		{BookID: 1, CustomerID: 2, Rating: 4, Status: StatusApproved},
+		{BookID: 1, CustomerID: 3, Rating: 2, Status: StatusApproved},
+		{BookID: 1, CustomerID: 4, Rating: 1, Status: StatusPending},
+		{BookID: 1, CustomerID: 5, Rating: 1, Status: StatusRejected},
+		{BookID: 2, CustomerID: 1, Rating: 3, Status: StatusApproved},
+		{BookID: 3, CustomerID: 1, Rating: 5, Status: StatusPending},
+		{BookID: 4, CustomerID: 1, Rating: 1, Status: StatusApproved},
+	}
+	for _, r := range reviews {
+		if _, err := store.CreateReview(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := store.BookRatings(ctx, []int{1, 2, 3, 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]book.Rating{
+		1: {Average: 3.67, Count: 3},
+		2: {Average: 3, Count: 1},
+	}
+	if len(got) != len(want) {
+		t.Errorf("BookRatings = %v, want only books with approved reviews among those asked for: %v", got, want)
+	}
+	for id, rating := range want {
+		if got[id] != rating {
+			t.Errorf("rating of book %d = %+v, want %+v", id, got[id], rating)
+		}
+	}
+
+	if got, err := store.BookRatings(ctx, nil); err != nil || len(got) != 0 {
+		t.Errorf("BookRatings(nil) = %v, %v; want no ratings", got, err)
+	}
+}
+
+type fixture struct {
+	svc        Service
+	books      book.BookStore
+	orders     *order.InMemoryOrderStore
+	customerID int
+	bookID     int
+}
+
+// newFixture returns a review service over in-memory stores holding one
+// book and one customer who ordered it.
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	ctx := context.Background()
+	books := book.NewStore()
+	b, err := books.CreateBook(ctx, book.Book{Title: "Dune", Price: 10, Stock: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	customers := customer.NewCustomerStore()
+	c, err := customers.CreateCustomer(ctx, &customer.Customer{Name: "Reader", Email: "reader@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := order.NewOrderStore()
+	if _, err := orders.Create(ctx, order.Order{CustomerID: c.ID, Items: []order.OrderItem{{BookID: b.ID, Quantity: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	return fixture{
+		svc:        NewService(NewStore(), books, customers, orders),
+		books:      books,
+		orders:     orders,
+		customerID: c.ID,
+		bookID:     b.ID,
+	}
+}
+
+func TestCreateReview(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	tests := []struct {
+		name    string
+		r       Review
+		wantErr error
+	}{
+		{"rating too low", Review{BookID: f.bookID, CustomerID: f.customerID, Rating: 0}, nil},
+		{"rating too high", Review{BookID: f.bookID, CustomerID: f.customerID, Rating: 6}, nil},
+		{"no customer", Review{BookID: f.bookID, Rating: 4}, nil},
+		{"unknown book", Review{BookID: 99, CustomerID: f.customerID, Rating: 4}, ErrBookNotFound},
+		{"unknown customer", Review{BookID: f.bookID, CustomerID: 99, Rating: 4}, nil},
+	}
+	for _, tt := range tests {
+		_, err := f.svc.CreateReview(ctx, tt.r)
+		if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+			t.Errorf("%s: CreateReview = %v, want an error %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	r, err := f.svc.CreateReview(ctx, Review{BookID: f.bookID, CustomerID: f.customerID, Rating: 4, Text: "  Spice!  "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != StatusPending || r.Text != "Spice!" {
+		t.Errorf("review = %+v, want it pending with its text trimmed", r)
+	}
+	if _, err := f.svc.CreateReview(ctx, r); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("second review = %v, want %v", err, ErrDuplicate)
+	}
+}
+
+func TestCreateReviewNeedsOrder(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	other, err := f.books.CreateBook(ctx, book.Book{Title: "Dune Messiah", Price: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.svc.CreateReview(ctx, Review{BookID: other.ID, CustomerID: f.customerID, Rating: 4}); !errors.Is(err, ErrNotPurchased) {
+		t.Errorf("review of a book not ordered = %v, want %v", err, ErrNotPurchased)
+	}
+
+	orders, err := f.orders.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := orders[0]
+	o.Status = order.StatusCancelled
+	if _, err := f.orders.Update(ctx, o.ID, o); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.svc.CreateReview(ctx, Review{BookID: f.bookID, CustomerID: f.customerID, Rating: 4}); !errors.Is(err, ErrNotPurchased) {
+		t.Errorf("review of a cancelled order = %v, want %v", err, ErrNotPurchased)
+	}
+}
+
+func TestModerationUpdatesRating(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	r, err := f.svc.CreateReview(ctx, Review{BookID: f.bookID, CustomerID: f.customerID, Rating: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		status Status
+		want   book.Rating
+	}{
+		{StatusPending, book.Rating{}},
+		{StatusApproved, book.Rating{Average: 4, Count: 1}},
+		{StatusRejected, book.Rating{}},
+	} {
+		if _, err := f.svc.Moderate(ctx, r.ID, step.status); err != nil {
+			t.Fatal(err)
+		}
+		ratings, err := f.svc.BookRatings(ctx, []int{f.bookID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ratings[f.bookID]; got != step.want {
+			t.Errorf("rating while %s = %+v, want %+v", step.status, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package review
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/internal/customer"
+	"um6p.ma/final_project/internal/order"
+	"um6p.ma/final_project/pkg/logging"
+)
+
+type InMemoryReviewStore struct {
+	mu      sync.RWMutex
+	reviews map[int]Review
+	nextID  int
+}
+
+// duplicate reports whether a review other than id has the same book and
+// customer as r. Callers hold the lock.
+func (store *InMemoryReviewStore) duplicate(id int, r Review) bool {
+	for _, other := range store.reviews {
+		if other.ID != id && other.BookID == r.BookID && other.CustomerID == r.CustomerID {
+			return true
+		}
+	}
+	return false
+}
+
+func (store *InMemoryReviewStore) CreateReview(ctx context.Context, r Review) (Review, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.duplicate(0, r) {
+		return Review{}, fmt.Errorf("%w: customer %d has already reviewed book %d", ErrDuplicate, r.CustomerID, r.BookID)
+	}
+	r.ID = store.nextID
+	store.nextID++
+	store.reviews[r.ID] = r
+	return r, nil
+}
+
+func (store *InMemoryReviewStore) GetReview(ctx context.Context, id int) (Review, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	r, found := store.reviews[id]
+	if !found {
+		logging.Printf(ctx, "review with ID %d not found", id)
+		return Review{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return r, nil
+}
+
+func (store *InMemoryReviewStore) UpdateReview(ctx context.Context, id int, r Review) (Review, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.reviews[id]; !found {
+		logging.Printf(ctx, "review with ID %d not found", id)
+		return Review{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if store.duplicate(id, r) {
+		return Review{}, fmt.Errorf("%w: customer %d has already reviewed book %d", ErrDuplicate, r.CustomerID, r.BookID)
+	}
+	r.ID = id
+	store.reviews[id] = r
+	return r, nil
+}
+
+func (store *InMemoryReviewStore) DeleteReview(ctx context.Context, id int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.reviews[id]; !found {
+		logging.Printf(ctx, "review with ID %d not found", id)
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	delete(store.reviews, id)
+	return nil
+}
+
+func (store *InMemoryReviewStore) ListReviews(ctx context.Context, f Filter) ([]Review, int, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	default:
+	}
+
+	matches := make([]Review, 0)
+	for _, r := range store.reviews {
+		if f.Matches(r) {
+			matches = append(matches, r)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].ID > matches[j].ID
+	})
+
+	total := len(matches)
+	matches = matches[min(f.Offset, total):]
+	if f.Limit > 0 && len(matches) > f.Limit {
+		matches = matches[:f.Limit]
+	}
+	return matches, total, nil
+}
+
+func (store *InMemoryReviewStore) DeleteBookReviews(ctx context.Context, bookID int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.deleteBookReviews(bookID)
+	return nil
+}
+
+// deleteBookReviews deletes the reviews of bookID and returns their IDs.
+// Callers hold the lock.
+func (store *InMemoryReviewStore) deleteBookReviews(bookID int) []int {
+	var ids []int
+	for id, r := range store.reviews {
+		if r.BookID == bookID {
+			ids = append(ids, id)
+			delete(store.reviews, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// RestoreReview stores r under its own ID and moves nextID past it.
+func (store *InMemoryReviewStore) RestoreReview(ctx context.Context, r Review) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if r.ID <= 0 {
+		return fmt.Errorf("invalid review ID %d", r.ID)
+	}
+	store.reviews[r.ID] = r
+	store.nextID = max(store.nextID, r.ID+1)
+	return nil
+}
+
+func (store *InMemoryReviewStore) BookRatings(ctx context.Context, bookIDs []int) (map[int]book.Rating, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	wanted := make(map[int]struct{}, len(bookIDs))
+	for _, id := range bookIDs {
+		wanted[id] = struct{}{}
+	}
+	sums := make(map[int]int)
+	ratings := make(map[int]book.Rating)
+	for _, r := range store.reviews {
+		if _, ok := wanted[r.BookID]; r.Status != StatusApproved || !ok {
+			continue
+		}
+		sums[r.BookID] += r.Rating
+		rating := ratings[r.BookID]
+		rating.Count++
+		ratings[r.BookID] = rating
+	}
+	for id, rating := range ratings {
+		rating.Average = roundRating(float64(sums[id]) / float64(rating.Count))
+		ratings[id] = rating
+	}
+	return ratings, nil
+}
+
+// roundRating rounds an average rating to two decimals.
+func roundRating(avg float64) float64 {
+	return math.Round(avg*100) / 100
+}
+
+const (
+	MinRating = 1
+	MaxRating = 5
+
+	// MaxTextLength is the longest review text accepted, in characters.
+	MaxTextLength = 5000
+)
+
+type Service interface {
+	CreateReview(ctx context.Context, r Review) (Review, error)
+	GetReview(ctx context.Context, id int) (Review, error)
+	ListReviews(ctx context.Context, f Filter) ([]Review, int, error)
+	BookReviews(ctx context.Context, bookID int, f Filter) ([]Review, int, error)
+	Moderate(ctx context.Context, id int, status Status) (Review, error)
+	DeleteReview(ctx context.Context, id int) error
+	DeleteBookReviews(ctx context.Context, bookID int) error
+	BookRatings(ctx context.Context, bookIDs []int) (map[int]book.Rating, error)
+}
+
+type service struct {
+	store     ReviewStore
+	books     book.BookStore
+	customers customer.CustomerStore
+	orders    order.OrderStore
+}
+
+// NewService returns the review service. Reviewers must have an order for
+// the book that was not cancelled.
+func NewService(store ReviewStore, books book.BookStore, customers customer.CustomerStore, orders order.OrderStore) Service {
+	return &service{store: store, books: books, customers: customers, orders: orders}
+}
+
+// CreateReview records a pending review of a book the customer has ordered
+// and not cancelled.
+func (s *service) CreateReview(ctx context.Context, r Review) (Review, error) {
+	r.Text = strings.TrimSpace(r.Text)
+	if r.Rating < MinRating || r.Rating > MaxRating {
+		return Review{}, fmt.Errorf("rating must be between %d and %d", MinRating, MaxRating)
+	}
+	if utf8.RuneCountInString(r.Text) > MaxTextLength {
+		return Review{}, fmt.Errorf("review text must be at most %d characters", MaxTextLength)
+	}
+	if r.CustomerID <= 0 {
+		return Review{}, fmt.Errorf("customer_id is required")
+	}
+	if _, err := s.books.GetBook(ctx, r.BookID); err != nil {
+		return Review{}, fmt.Errorf("%w: ID %d", ErrBookNotFound, r.BookID)
+	}
+	if _, err := s.customers.GetCustomerByID(ctx, r.CustomerID); err != nil {
+		return Review{}, fmt.Errorf("customer with ID %d not found: %w", r.CustomerID, err)
+	}
+	ok, err := s.orders.HasPurchased(ctx, r.CustomerID, r.BookID)
+	if err != nil {
+		return Review{}, err
+	}
+	if !ok {
+		return Review{}, fmt.Errorf("%w: customer %d has no order for book %d", ErrNotPurchased, r.CustomerID, r.BookID)
+	}
+
+	r.Status = StatusPending
+	r.CreatedAt = time.Now().UTC()
+	return s.store.CreateReview(ctx, r)
+}
+
+func (s *service) GetReview(ctx context.Context, id int) (Review, error) {
+	return s.store.GetReview(ctx, id)
+}
+
+func (s *service) ListReviews(ctx context.Context, f Filter) ([]Review, int, error) {
+	return s.store.ListReviews(ctx, f)
+}
+
+// BookReviews lists the reviews of a book, only approved ones unless
+// f.Status asks otherwise.
+func (s *service) BookReviews(ctx context.Context, bookID int, f Filter) ([]Review, int, error) {
+	if _, err := s.books.GetBook(ctx, bookID); err != nil {
+		return nil, 0, fmt.Errorf("%w: ID %d", ErrBookNotFound, bookID)
+	}
+	f.BookID = bookID
+	if f.Status == "" {
+		f.Status = StatusApproved
+	}
+	return s.store.ListReviews(ctx, f)
+}
+
+func (s *service) Moderate(ctx context.Context, id int, status Status) (Review, error) {
+	r, err := s.store.GetReview(ctx, id)
+	if err != nil {
+		return Review{}, err
+	}
+	r.Status = status
+	return s.store.UpdateReview(ctx, id, r)
+}
+
+func (s *service) DeleteReview(ctx context.Context, id int) error {
+	return s.store.DeleteReview(ctx, id)
+}
+
+func (s *service) DeleteBookReviews(ctx context.Context, bookID int) error {
+	return s.store.DeleteBookReviews(ctx, bookID)
+}
+
+func (s *service) BookRatings(ctx context.Context, bookIDs []int) (map[int]book.Rating, error) {
+	return s.store.BookRatings(ctx, bookIDs)
+}
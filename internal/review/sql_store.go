@@ -0,0 +1,188 @@
+package review
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"um6p.ma/final_project/internal/book"
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+type SQLReviewStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLReviewStore {
+	return &SQLReviewStore{db: db}
+}
+
+const reviewColumns = `id, book_id, customer_id, rating, text, status, created_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReview(row rowScanner) (Review, error) {
+	var r Review
+	err := row.Scan(&r.ID, &r.BookID, &r.CustomerID, &r.Rating, &r.Text, &r.Status, &r.CreatedAt)
+	return r, err
+}
+
+func (store *SQLReviewStore) CreateReview(ctx context.Context, r Review) (Review, error) {
+	err := store.db.QueryRowContext(ctx,
+		`INSERT INTO reviews (book_id, customer_id, rating, text, status, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (book_id, customer_id) DO NOTHING
+		 RETURNING id`,
+		r.BookID, r.CustomerID, r.Rating, r.Text, r.Status, r.CreatedAt,
+	).Scan(&r.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Review{}, fmt.Errorf("%w: customer %d has already reviewed book %d", ErrDuplicate, r.CustomerID, r.BookID)
+	}
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to create review: %w", err)
+	}
+	return r, nil
+}
+
+func (store *SQLReviewStore) GetReview(ctx context.Context, id int) (Review, error) {
+	r, err := scanReview(store.db.QueryRowContext(ctx, `SELECT `+reviewColumns+` FROM reviews WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Review{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to get review %d: %w", id, err)
+	}
+	return r, nil
+}
+
+func (store *SQLReviewStore) UpdateReview(ctx context.Context, id int, r Review) (Review, error) {
+	res, err := store.db.ExecContext(ctx,
+		`UPDATE reviews SET book_id = $2, customer_id = $3, rating = $4, text = $5, status = $6, created_at = $7
+		 WHERE id = $1`,
+		id, r.BookID, r.CustomerID, r.Rating, r.Text, r.Status, r.CreatedAt,
+	)
+	if err != nil {
+		return Review{}, fmt.Errorf("failed to update review %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Review{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	r.ID = id
+	return r, nil
+}
+
+func (store *SQLReviewStore) DeleteReview(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM reviews WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete review %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// DeleteBookReviews is a no-op when the book row is already gone, since
+// reviews cascade with their book.
+func (store *SQLReviewStore) DeleteBookReviews(ctx context.Context, bookID int) error {
+	if _, err := store.db.ExecContext(ctx, `DELETE FROM reviews WHERE book_id = $1`, bookID); err != nil {
+		return fmt.Errorf("failed to delete reviews of book %d: %w", bookID, err)
+	}
+	return nil
+}
+
+func (store *SQLReviewStore) ListReviews(ctx context.Context, f Filter) ([]Review, int, error) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.BookID != 0 {
+		add("book_id = $%d", f.BookID)
+	}
+	if f.CustomerID != 0 {
+		add("customer_id = $%d", f.CustomerID)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM reviews`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	query := `SELECT ` + reviewColumns + ` FROM reviews` + where + ` ORDER BY created_at DESC, id DESC`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if f.Offset > 0 {
+		args = append(args, f.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	rows, err := store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := make([]Review, 0)
+	for rows.Next() {
+		r, err := scanReview(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, total, rows.Err()
+}
+
+func (store *SQLReviewStore) RestoreReview(ctx context.Context, r Review) error {
+	err := sqlutil.RestoreRow(ctx, store.db, "reviews",
+		`INSERT INTO reviews (id, book_id, customer_id, rating, text, status, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (id) DO UPDATE
+		 SET book_id = EXCLUDED.book_id, customer_id = EXCLUDED.customer_id, rating = EXCLUDED.rating,
+		     text = EXCLUDED.text, status = EXCLUDED.status, created_at = EXCLUDED.created_at`,
+		r.ID, r.BookID, r.CustomerID, r.Rating, r.Text, r.Status, r.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to restore review %d: %w", r.ID, err)
+	}
+	return nil
+}
+
+func (store *SQLReviewStore) BookRatings(ctx context.Context, bookIDs []int) (map[int]book.Rating, error) {
+	ratings := make(map[int]book.Rating)
+	if len(bookIDs) == 0 {
+		return ratings, nil
+	}
+	rows, err := store.db.QueryContext(ctx,
+		`SELECT book_id, ROUND(AVG(rating), 2)::float8, COUNT(*) FROM reviews
+		 WHERE status = $1 AND book_id = ANY($2)
+		 GROUP BY book_id`,
+		StatusApproved, bookIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get book ratings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var r book.Rating
+		if err := rows.Scan(&id, &r.Average, &r.Count); err != nil {
+			return nil, err
+		}
+		ratings[id] = r
+	}
+	return ratings, rows.Err()
+}
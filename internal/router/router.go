@@ -0,0 +1,72 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pkgError "um6p.ma/final_project/pkg/error"
+)
+
+// Router registers method-qualified ServeMux patterns under a versioned
+// prefix and, during the migration, under the legacy unversioned path too.
+// Requests that match no pattern get the mux's 404, or 405 with an Allow
+// header, as a JSON error.
+type Router struct {
+	mux    *http.ServeMux
+	prefix string
+	legacy bool
+}
+
+func New(prefix string, legacy bool) *Router {
+	return &Router{
+		mux:    http.NewServeMux(),
+		prefix: strings.TrimSuffix(prefix, "/"),
+		legacy: legacy,
+	}
+}
+
+func (r *Router) HandleFunc(method, path string, h http.HandlerFunc) {
+	r.mux.Handle(method+" "+r.prefix+path, h)
+	if r.legacy && r.prefix != "" {
+		r.mux.Handle(method+" "+path, deprecated(r.prefix, h))
+	}
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if _, pattern := r.mux.Handler(req); pattern == "" {
+		w = errorWriter{w}
+	}
+	r.mux.ServeHTTP(w, req)
+}
+
+// errorWriter replaces the plain-text error ServeMux writes for a request
+// that matches no pattern with a JSON one, keeping its status and headers.
+type errorWriter struct {
+	http.ResponseWriter
+}
+
+func (w errorWriter) WriteHeader(code int) {
+	pkgError.WriteJSONError(w.ResponseWriter, strings.ToLower(http.StatusText(code)), code)
+}
+
+func (w errorWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func deprecated(prefix string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", prefix, r.URL.Path))
+		h.ServeHTTP(w, r)
+	})
+}
+
+func IntParam(r *http.Request, name string) (int, bool) {
+	v, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
@@ -3,73 +3,137 @@ package sales
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
-	"um6p.ma/final_project/internal/order"
-
+	"um6p.ma/final_project/internal/publisher"
+	"um6p.ma/final_project/internal/router"
 	pkgError "um6p.ma/final_project/pkg/error"
 )
 
+type recordedSale struct {
+	sale       BookSales
+	recordedAt time.Time
+}
+
 type InMemorySalesStore struct {
-	orders map[int]order.Order
-	nextID int
+	mu    sync.RWMutex
+	sales []recordedSale
 }
 
 func NewSalesStore() *InMemorySalesStore {
-	return &InMemorySalesStore{
-		orders: make(map[int]order.Order),
-		nextID: 1,
-	}
+	return &InMemorySalesStore{}
 }
 
-func (s *InMemorySalesStore) generateSalesReport(ctx context.Context, start, end time.Time) (interface{}, error) {
-	placeholder := map[string]interface{}{
-		"message": "generateSalesReport placeholder",
-		"start":   start.Format(time.RFC3339),
-		"end":     end.Format(time.RFC3339),
+func (s *InMemorySalesStore) RecordSale(ctx context.Context, sale BookSales) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
 	}
-	return placeholder, nil
+
+	s.sales = append(s.sales, recordedSale{sale: sale, recordedAt: time.Now()})
+	return nil
 }
-func SalesReportHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		q := r.URL.Query()
-		startStr := q.Get("start")
-		endStr := q.Get("end")
-
-		var start, end time.Time
-		var err error
-
-		salesService := NewSalesStore()
-
-		if startStr == "" || endStr == "" {
-			end = time.Now()
-			start = end.Add(-24 * time.Hour)
-		} else {
-			start, err = time.Parse(time.RFC3339, startStr)
-			if err != nil {
-				pkgError.WriteJSONError(w, "invalid 'start' time (use RFC3339)", http.StatusBadRequest)
-				return
-			}
-			end, err = time.Parse(time.RFC3339, endStr)
-			if err != nil {
-				pkgError.WriteJSONError(w, "invalid 'end' time (use RFC3339)", http.StatusBadRequest)
-				return
-			}
+
+func (s *InMemorySalesStore) generateSalesReport(ctx context.Context, start, end time.Time) (SalesReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report := SalesReport{Timestamp: time.Now()}
+	for _, rec := range s.sales {
+		if rec.recordedAt.Before(start) || rec.recordedAt.After(end) {
+			continue
 		}
+		report.TotalRevenue += rec.sale.Book.Price * float64(rec.sale.Quantity)
+		report.TopSellingBooks = append(report.TopSellingBooks, rec.sale)
+	}
+	return report, nil
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/sales/report", h.GetSalesReport)
+	r.HandleFunc(http.MethodGet, "/sales/publishers", h.GetPublisherReport)
+}
 
-		ctx := r.Context()
-		report, err := salesService.generateSalesReport(ctx, start, end)
-		if err != nil {
-			pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+// parseRange reads the start and end query parameters, defaulting to the
+// last 24 hours when either is missing.
+func parseRange(q url.Values) (time.Time, time.Time, error) {
+	startStr := q.Get("start")
+	endStr := q.Get("end")
+	if startStr == "" || endStr == "" {
+		end := time.Now()
+		return end.Add(-24 * time.Hour), end, nil
+	}
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid 'start' time (use RFC3339)")
+	}
+	end, err := time.Parse(time.RFC3339, endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid 'end' time (use RFC3339)")
+	}
+	return start, end, nil
+}
+
+func (h *Handler) GetSalesReport(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseRange(r.URL.Query())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.svc.GenerateSalesReport(r.Context(), start, end)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *Handler) GetPublisherReport(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, end, err := parseRange(q)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var publisherID int
+	if v := q.Get("publisher_id"); v != "" {
+		if publisherID, err = strconv.Atoi(v); err != nil || publisherID <= 0 {
+			pkgError.WriteJSONError(w, fmt.Sprintf("invalid publisher_id %q", v), http.StatusBadRequest)
 			return
 		}
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(report)
-
-	default:
-		pkgError.WriteJSONError(w, "method not allowed", http.StatusMethodNotAllowed)
+	report, err := h.svc.PublisherReport(r.Context(), start, end, publisherID)
+	if errors.Is(err, publisher.ErrNotFound) {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusNotFound)
+		return
 	}
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
 }
@@ -19,6 +19,34 @@ type BookSales struct {
 	Quantity int       `json:"quantity_sold"`
 }
 
+// PublisherReport breaks the sales of a period down by publisher. Books
+// without a publisher, or deleted since they were sold, are counted under
+// publisher ID 0.
+type PublisherReport struct {
+	Timestamp    time.Time        `json:"timestamp"`
+	Start        time.Time        `json:"start"`
+	End          time.Time        `json:"end"`
+	TotalRevenue float64          `json:"total_revenue"`
+	Publishers   []PublisherSales `json:"publishers"`
+}
+
+type PublisherSales struct {
+	PublisherID int             `json:"publisher_id"`
+	Name        string          `json:"name,omitempty"`
+	Orders      int             `json:"orders"`
+	Quantity    int             `json:"quantity_sold"`
+	Revenue     float64         `json:"revenue"`
+	Books       []BookLineSales `json:"books"`
+}
+
+// BookLineSales is what was sold of one book, at the prices it was sold at.
+type BookLineSales struct {
+	BookID   int     `json:"book_id"`
+	Title    string  `json:"title"`
+	Quantity int     `json:"quantity_sold"`
+	Revenue  float64 `json:"revenue"`
+}
+
 type SalesStore interface {
 	RecordSale(ctx context.Context, sale BookSales) error
 	generateSalesReport(ctx context.Context, start, end time.Time) (SalesReport, error)
@@ -2,33 +2,46 @@ package sales
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
+	"um6p.ma/final_project/internal/book"
 	"um6p.ma/final_project/internal/order"
+	"um6p.ma/final_project/internal/publisher"
 )
 
 type Service interface {
 	StartPeriodicReportGeneration(ctx context.Context)
 	Stop()
+	GenerateSalesReport(ctx context.Context, start, end time.Time) (SalesReport, error)
+	PublisherReport(ctx context.Context, start, end time.Time, publisherID int) (PublisherReport, error)
 }
 
 type service struct {
 	orderStore order.OrderStore
 	salesStore SalesStore
-
-	ticker  *time.Ticker
-	stopCh  chan struct{}
-	wg      sync.WaitGroup
-	running bool
+	books      book.BookStore
+	publishers publisher.PublisherStore
+
+	interval time.Duration
+	ticker   *time.Ticker
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	running  bool
 }
 
-func NewService(oStore order.OrderStore, sStore SalesStore) Service {
+func NewService(oStore order.OrderStore, sStore SalesStore, books book.BookStore, publishers publisher.PublisherStore,
+	interval time.Duration) Service {
 	return &service{
 		orderStore: oStore,
 		salesStore: sStore,
+		books:      books,
+		publishers: publishers,
+		interval:   interval,
 		stopCh:     make(chan struct{}),
 	}
 }
@@ -39,7 +52,7 @@ func (s *service) StartPeriodicReportGeneration(ctx context.Context) {
 	}
 	s.running = true
 
-	s.ticker = time.NewTicker(24 * time.Hour)
+	s.ticker = time.NewTicker(s.interval)
 
 	s.wg.Add(1)
 	go func() {
@@ -47,11 +60,17 @@ func (s *service) StartPeriodicReportGeneration(ctx context.Context) {
 		for {
 			select {
 			case <-s.ticker.C:
-				if _, err := s.generateSalesReport(ctx, time.Now().Add(-24*time.Hour), time.Now()); err != nil {
+				report, err := s.GenerateSalesReport(ctx, time.Now().Add(-s.interval), time.Now())
+				if err != nil {
 					log.Printf("SalesService Failed to generate report: %v\n", err)
-				} else {
-					log.Println("SalesService Sales report generated successfully.")
+					continue
+				}
+				for _, sale := range report.TopSellingBooks {
+					if err := s.salesStore.RecordSale(ctx, sale); err != nil {
+						log.Printf("SalesService Failed to record sale of book %d: %v", sale.Book.ID, err)
+					}
 				}
+				log.Println("SalesService Sales report generated successfully.")
 
 			case <-s.stopCh:
 				s.ticker.Stop()
@@ -59,7 +78,7 @@ func (s *service) StartPeriodicReportGeneration(ctx context.Context) {
 
 			case <-ctx.Done():
 				log.Println("SalesService context cancelled")
-				s.Stop()
+				s.ticker.Stop()
 				return
 			}
 		}
@@ -75,7 +94,7 @@ func (s *service) Stop() {
 	s.running = false
 }
 
-func (s *service) generateSalesReport(ctx context.Context, start, end time.Time) (SalesReport, error) {
+func (s *service) GenerateSalesReport(ctx context.Context, start, end time.Time) (SalesReport, error) {
 	orders, err := s.orderStore.GetOrdersInTimeRange(ctx, start, end)
 	if err != nil {
 		return SalesReport{}, fmt.Errorf("getOrdersInTimeRange: %w", err)
@@ -92,21 +111,28 @@ func (s *service) generateSalesReport(ctx context.Context, start, end time.Time)
 		default:
 			totalRevenue += o.TotalPrice
 			for _, item := range o.Items {
-				bookSalesMap[item.Book.ID] += item.Quantity
+				bookSalesMap[item.BookID] += item.Quantity
 			}
 		}
 	}
 
 	var topSelling []BookSales
+	seen := make(map[int]bool)
 	for _, o := range orders {
 		for _, item := range o.Items {
-			qty := bookSalesMap[item.Book.ID]
+			if seen[item.BookID] {
+				continue
+			}
+			seen[item.BookID] = true
 			topSelling = append(topSelling, BookSales{
-				Book:     item.Book,
-				Quantity: qty,
+				Book:     book.Book{ID: item.BookID, Title: item.Title, Price: item.UnitPrice},
+				Quantity: bookSalesMap[item.BookID],
 			})
 		}
 	}
+	sort.SliceStable(topSelling, func(i, j int) bool {
+		return topSelling[i].Quantity > topSelling[j].Quantity
+	})
 
 	report := SalesReport{
 		Timestamp:       time.Now(),
@@ -114,9 +140,86 @@ func (s *service) generateSalesReport(ctx context.Context, start, end time.Time)
 		TotalOrders:     totalOrders,
 		TopSellingBooks: topSelling,
 	}
-	if err := s.salesStore.RecordSale(ctx, BookSales{}); err != nil {
-		log.Printf("RecordSale stub. Replace with real logic. err=%v", err)
+	return report, nil
+}
+
+// PublisherReport attributes each order item to the current publisher of
+// the edition sold, pricing it at what was paid. A non-zero publisherID keeps only
+// that publisher.
+func (s *service) PublisherReport(ctx context.Context, start, end time.Time, publisherID int) (PublisherReport, error) {
+	if publisherID != 0 {
+		if _, err := s.publishers.GetPublisher(ctx, publisherID); err != nil {
+			return PublisherReport{}, err
+		}
+	}
+	// The order store reports an empty range as an error.
+	orders, err := s.orderStore.GetOrdersInTimeRange(ctx, start, end)
+	if err != nil && !errors.Is(err, order.ErrNoOrders) {
+		return PublisherReport{}, err
 	}
 
+	books := make(map[int]book.Book)
+	publisherOf := func(item order.OrderItem) int {
+		b, ok := books[item.BookID]
+		if !ok {
+			// A book deleted since it was sold stays under publisher 0.
+			b, _ = s.books.GetBook(ctx, item.BookID)
+			books[item.BookID] = b
+		}
+		return b.EditionPublisher(item.EditionID)
+	}
+
+	byPublisher := make(map[int]*PublisherSales)
+	bookLines := make(map[int]map[int]*BookLineSales)
+	for _, o := range orders {
+		if err := ctx.Err(); err != nil {
+			return PublisherReport{}, err
+		}
+		counted := make(map[int]bool)
+		for _, item := range o.Items {
+			pid := publisherOf(item)
+			if publisherID != 0 && pid != publisherID {
+				continue
+			}
+			ps, ok := byPublisher[pid]
+			if !ok {
+				ps = &PublisherSales{PublisherID: pid}
+				byPublisher[pid] = ps
+				bookLines[pid] = make(map[int]*BookLineSales)
+			}
+			if !counted[pid] {
+				ps.Orders++
+				counted[pid] = true
+			}
+			line, ok := bookLines[pid][item.BookID]
+			if !ok {
+				line = &BookLineSales{BookID: item.BookID, Title: item.Title}
+				bookLines[pid][item.BookID] = line
+			}
+			revenue := item.UnitPrice * float64(item.Quantity)
+			line.Quantity += item.Quantity
+			line.Revenue += revenue
+			ps.Quantity += item.Quantity
+			ps.Revenue += revenue
+		}
+	}
+
+	report := PublisherReport{Timestamp: time.Now(), Start: start, End: end, Publishers: make([]PublisherSales, 0, len(byPublisher))}
+	for pid, ps := range byPublisher {
+		if pid != 0 {
+			if p, err := s.publishers.GetPublisher(ctx, pid); err == nil {
+				ps.Name = p.Name
+			}
+		}
+		for _, line := range bookLines[pid] {
+			ps.Books = append(ps.Books, *line)
+		}
+		sort.Slice(ps.Books, func(i, j int) bool { return ps.Books[i].Revenue > ps.Books[j].Revenue })
+		report.TotalRevenue += ps.Revenue
+		report.Publishers = append(report.Publishers, *ps)
+	}
+	sort.Slice(report.Publishers, func(i, j int) bool {
+		return report.Publishers[i].Revenue > report.Publishers[j].Revenue
+	})
 	return report, nil
 }
@@ -0,0 +1,53 @@
+package sales
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+type SQLSalesStore struct {
+	db *sql.DB
+}
+
+func NewSQLSalesStore(db *sql.DB) *SQLSalesStore {
+	return &SQLSalesStore{db: db}
+}
+
+func (s *SQLSalesStore) RecordSale(ctx context.Context, sale BookSales) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO sales (book_id, title, unit_price, quantity, recorded_at) VALUES ($1, $2, $3, $4, $5)`,
+		sqlutil.NullID(sale.Book.ID), sale.Book.Title, sale.Book.Price, sale.Quantity, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record sale: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLSalesStore) generateSalesReport(ctx context.Context, start, end time.Time) (SalesReport, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT COALESCE(book_id, 0), title, unit_price, SUM(quantity)
+		 FROM sales
+		 WHERE recorded_at >= $1 AND recorded_at <= $2
+		 GROUP BY book_id, title, unit_price
+		 ORDER BY SUM(quantity) DESC`,
+		start, end)
+	if err != nil {
+		return SalesReport{}, fmt.Errorf("failed to query sales: %w", err)
+	}
+	defer rows.Close()
+
+	report := SalesReport{Timestamp: time.Now()}
+	for rows.Next() {
+		var sale BookSales
+		if err := rows.Scan(&sale.Book.ID, &sale.Book.Title, &sale.Book.Price, &sale.Quantity); err != nil {
+			return SalesReport{}, err
+		}
+		report.TotalRevenue += sale.Book.Price * float64(sale.Quantity)
+		report.TopSellingBooks = append(report.TopSellingBooks, sale)
+	}
+	return report, rows.Err()
+}
@@ -0,0 +1,84 @@
+package series
+
+import (
+	"context"
+	"path/filepath"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+type storeSnapshot struct {
+	NextID int      `json:"next_id"`
+	Series []Series `json:"series"`
+}
+
+func (store *InMemorySeriesStore) snapshot() storeSnapshot {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	snap := storeSnapshot{NextID: store.nextID, Series: make([]Series, 0, len(store.series))}
+	for _, s := range store.series {
+		snap.Series = append(snap.Series, s)
+	}
+	return snap
+}
+
+func (store *InMemorySeriesStore) restore(snap storeSnapshot) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.series = make(map[int]Series, len(snap.Series))
+	store.nextID = max(snap.NextID, 1)
+	for _, s := range snap.Series {
+		store.series[s.ID] = s
+		store.nextID = max(store.nextID, s.ID+1)
+	}
+}
+
+type FileSeriesStore struct {
+	*InMemorySeriesStore
+	file *persist.SnapshotFile[storeSnapshot]
+}
+
+func NewFileStore(dir string) (*FileSeriesStore, error) {
+	mem := NewStore()
+	file, err := persist.OpenSnapshotFile(filepath.Join(dir, "series.json"), "series", mem.snapshot, mem.restore)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSeriesStore{InMemorySeriesStore: mem, file: file}, nil
+}
+
+func (store *FileSeriesStore) Flush() error {
+	return store.file.Save()
+}
+
+func (store *FileSeriesStore) CreateSeries(ctx context.Context, s Series) (Series, error) {
+	created, err := store.InMemorySeriesStore.CreateSeries(ctx, s)
+	if err != nil {
+		return Series{}, err
+	}
+	return created, store.file.Save()
+}
+
+func (store *FileSeriesStore) UpdateSeries(ctx context.Context, id int, s Series) (Series, error) {
+	updated, err := store.InMemorySeriesStore.UpdateSeries(ctx, id, s)
+	if err != nil {
+		return Series{}, err
+	}
+	return updated, store.file.Save()
+}
+
+func (store *FileSeriesStore) DeleteSeries(ctx context.Context, id int) error {
+	if err := store.InMemorySeriesStore.DeleteSeries(ctx, id); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
+
+func (store *FileSeriesStore) RestoreSeries(ctx context.Context, s Series) error {
+	if err := store.InMemorySeriesStore.RestoreSeries(ctx, s); err != nil {
+		return err
+	}
+	return store.file.Save()
+}
@@ -0,0 +1,123 @@
+package series
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"um6p.ma/final_project/internal/router"
+	pkgError "um6p.ma/final_project/pkg/error"
+)
+
+func NewStore() *InMemorySeriesStore {
+	return &InMemorySeriesStore{
+		series: make(map[int]Series),
+		nextID: 1,
+	}
+}
+
+type Handler struct {
+	svc Service
+}
+
+func NewHandler(svc Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func (h *Handler) RegisterRoutes(r *router.Router) {
+	r.HandleFunc(http.MethodGet, "/series", h.ListSeries)
+	r.HandleFunc(http.MethodPost, "/series", h.CreateSeries)
+	r.HandleFunc(http.MethodGet, "/series/{id}", h.GetSeries)
+	r.HandleFunc(http.MethodPut, "/series/{id}", h.UpdateSeries)
+	r.HandleFunc(http.MethodDelete, "/series/{id}", h.DeleteSeries)
+}
+
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInUse):
+		return http.StatusConflict
+	}
+	return fallback
+}
+
+func (h *Handler) ListSeries(w http.ResponseWriter, r *http.Request) {
+	series, err := h.svc.ListSeries(r.Context())
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+func (h *Handler) CreateSeries(w http.ResponseWriter, r *http.Request) {
+	var s Series
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.svc.CreateSeries(r.Context(), s)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.svc.GetSeries(r.Context(), id)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
+
+func (h *Handler) UpdateSeries(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	var s Series
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		pkgError.WriteJSONError(w, "bad request: invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.svc.UpdateSeries(r.Context(), id, s)
+	if err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusBadRequest))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteSeries(w http.ResponseWriter, r *http.Request) {
+	id, ok := router.IntParam(r, "id")
+	if !ok {
+		pkgError.WriteJSONError(w, "invalid series ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteSeries(r.Context(), id); err != nil {
+		pkgError.WriteJSONError(w, err.Error(), errorStatus(err, http.StatusInternalServerError))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,93 @@
+package series
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/journal"
+)
+
+func (store *InMemorySeriesStore) apply(rec journal.Record) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	switch rec.Op {
+	case journal.OpCreate, journal.OpUpdate:
+		var s Series
+		if err := json.Unmarshal(rec.Data, &s); err != nil {
+			return fmt.Errorf("invalid series record: %w", err)
+		}
+		store.series[s.ID] = s
+		store.nextID = max(store.nextID, s.ID+1)
+	case journal.OpDelete:
+		delete(store.series, rec.ID)
+	default:
+		return fmt.Errorf("unknown journal op %q", rec.Op)
+	}
+	return nil
+}
+
+type JournaledSeriesStore struct {
+	*InMemorySeriesStore
+	journal *journal.Store[storeSnapshot]
+}
+
+func NewJournaledStore(dir string, opts journal.Options) (*JournaledSeriesStore, error) {
+	mem := NewStore()
+	j, err := journal.OpenStore(dir, "series", opts, mem.snapshot, mem.restore, mem.apply)
+	if err != nil {
+		return nil, err
+	}
+	return &JournaledSeriesStore{InMemorySeriesStore: mem, journal: j}, nil
+}
+
+func (store *JournaledSeriesStore) Flush() error {
+	return store.journal.Flush()
+}
+
+func (store *JournaledSeriesStore) Close() error {
+	return store.journal.Close()
+}
+
+func (store *JournaledSeriesStore) CreateSeries(ctx context.Context, s Series) (Series, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	created, err := store.InMemorySeriesStore.CreateSeries(ctx, s)
+	if err != nil {
+		return Series{}, err
+	}
+	return created, store.journal.Record(journal.OpCreate, created.ID, created)
+}
+
+func (store *JournaledSeriesStore) UpdateSeries(ctx context.Context, id int, s Series) (Series, error) {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	updated, err := store.InMemorySeriesStore.UpdateSeries(ctx, id, s)
+	if err != nil {
+		return Series{}, err
+	}
+	return updated, store.journal.Record(journal.OpUpdate, id, updated)
+}
+
+func (store *JournaledSeriesStore) DeleteSeries(ctx context.Context, id int) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemorySeriesStore.DeleteSeries(ctx, id); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpDelete, id, nil)
+}
+
+func (store *JournaledSeriesStore) RestoreSeries(ctx context.Context, s Series) error {
+	store.journal.Lock()
+	defer store.journal.Unlock()
+
+	if err := store.InMemorySeriesStore.RestoreSeries(ctx, s); err != nil {
+		return err
+	}
+	return store.journal.Record(journal.OpCreate, s.ID, s)
+}
@@ -0,0 +1,52 @@
+package series
+
+import (
+	"context"
+	"errors"
+
+	"um6p.ma/final_project/internal/author"
+)
+
+var (
+	ErrNotFound = errors.New("series not found")
+	ErrInUse    = errors.New("series in use")
+)
+
+// Series groups books meant to be read in order. Books join a series with a
+// volume number; AuthorID, if set, is the author the series is credited to.
+type Series struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AuthorID    int    `json:"author_id,omitempty"`
+}
+
+// Volume is a book in a series, as listed by GET /series/{id}.
+type Volume struct {
+	Volume int    `json:"volume"`
+	BookID int    `json:"book_id"`
+	Title  string `json:"title"`
+	Stock  int    `json:"stock"`
+}
+
+// Detail is a series with its author and its volumes in reading order.
+type Detail struct {
+	Series
+	Author  *author.Author `json:"author,omitempty"`
+	Volumes []Volume       `json:"volumes"`
+}
+
+type SeriesStore interface {
+	CreateSeries(ctx context.Context, s Series) (Series, error)
+	GetSeries(ctx context.Context, id int) (Series, error)
+	UpdateSeries(ctx context.Context, id int, s Series) (Series, error)
+	DeleteSeries(ctx context.Context, id int) error
+	ListSeries(ctx context.Context) ([]Series, error)
+	RestoreSeries(ctx context.Context, s Series) error
+}
+
+// VolumeLister lists the books in a series ordered by volume, so series can
+// show their volumes and those that still have books cannot be deleted.
+type VolumeLister interface {
+	SeriesVolumes(ctx context.Context, seriesID int) ([]Volume, error)
+}
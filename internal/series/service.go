@@ -0,0 +1,241 @@
+package series
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"um6p.ma/final_project/internal/author"
+	"um6p.ma/final_project/pkg/logging"
+)
+
+type InMemorySeriesStore struct {
+	mu     sync.RWMutex
+	series map[int]Series
+	nextID int
+}
+
+// nameTaken reports whether a series other than id is already called name.
+// Callers hold the lock.
+func (store *InMemorySeriesStore) nameTaken(id int, name string) bool {
+	for _, s := range store.series {
+		if s.ID != id && strings.EqualFold(s.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (store *InMemorySeriesStore) CreateSeries(ctx context.Context, s Series) (Series, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if store.nameTaken(0, s.Name) {
+		logging.Printf(ctx, "series %s already exists", s.Name)
+		return Series{}, fmt.Errorf("series %s already exists", s.Name)
+	}
+	s.ID = store.nextID
+	store.nextID++
+	store.series[s.ID] = s
+	return s, nil
+}
+
+func (store *InMemorySeriesStore) GetSeries(ctx context.Context, id int) (Series, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	s, found := store.series[id]
+	if !found {
+		logging.Printf(ctx, "series with ID %d not found", id)
+		return Series{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return s, nil
+}
+
+func (store *InMemorySeriesStore) UpdateSeries(ctx context.Context, id int, s Series) (Series, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.series[id]; !found {
+		logging.Printf(ctx, "series with ID %d not found", id)
+		return Series{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if store.nameTaken(id, s.Name) {
+		return Series{}, fmt.Errorf("series %s already exists", s.Name)
+	}
+	s.ID = id
+	store.series[id] = s
+	return s, nil
+}
+
+func (store *InMemorySeriesStore) DeleteSeries(ctx context.Context, id int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, found := store.series[id]; !found {
+		logging.Printf(ctx, "series with ID %d not found", id)
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	delete(store.series, id)
+	return nil
+}
+
+func (store *InMemorySeriesStore) ListSeries(ctx context.Context) ([]Series, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	all := make([]Series, 0, len(store.series))
+	for _, s := range store.series {
+		all = append(all, s)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	return all, nil
+}
+
+// RestoreSeries stores s under its own ID and moves nextID past it.
+func (store *InMemorySeriesStore) RestoreSeries(ctx context.Context, s Series) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if s.ID <= 0 {
+		return fmt.Errorf("invalid series ID %d", s.ID)
+	}
+	store.series[s.ID] = s
+	store.nextID = max(store.nextID, s.ID+1)
+	return nil
+}
+
+type Service interface {
+	CreateSeries(ctx context.Context, s Series) (Series, error)
+	GetSeries(ctx context.Context, id int) (Detail, error)
+	UpdateSeries(ctx context.Context, id int, s Series) (Series, error)
+	DeleteSeries(ctx context.Context, id int) error
+	ListSeries(ctx context.Context) ([]Series, error)
+
+	SeriesRefsByAuthor(ctx context.Context, authorID int) ([]author.SeriesRef, error)
+	ReassignSeries(ctx context.Context, fromAuthorID, toAuthorID int) error
+}
+
+type service struct {
+	store   SeriesStore
+	authors author.AuthorStore
+	volumes VolumeLister
+}
+
+func NewService(store SeriesStore, authors author.AuthorStore, volumes VolumeLister) Service {
+	return &service{store: store, authors: authors, volumes: volumes}
+}
+
+func (s *service) validate(ctx context.Context, sr Series) (Series, error) {
+	sr.Name = strings.TrimSpace(sr.Name)
+	if sr.Name == "" {
+		return Series{}, fmt.Errorf("series name is required")
+	}
+	if sr.AuthorID < 0 {
+		return Series{}, fmt.Errorf("invalid author ID %d", sr.AuthorID)
+	}
+	if sr.AuthorID != 0 {
+		if _, err := s.authors.GetAuthorByID(ctx, sr.AuthorID); err != nil {
+			return Series{}, err
+		}
+	}
+	return sr, nil
+}
+
+func (s *service) CreateSeries(ctx context.Context, sr Series) (Series, error) {
+	sr, err := s.validate(ctx, sr)
+	if err != nil {
+		return Series{}, err
+	}
+	return s.store.CreateSeries(ctx, sr)
+}
+
+// GetSeries returns series id with its volumes. An author that has since
+// been deleted is left out.
+func (s *service) GetSeries(ctx context.Context, id int) (Detail, error) {
+	sr, err := s.store.GetSeries(ctx, id)
+	if err != nil {
+		return Detail{}, err
+	}
+	d := Detail{Series: sr}
+	if sr.AuthorID != 0 {
+		if a, err := s.authors.GetAuthorByID(ctx, sr.AuthorID); err == nil {
+			d.Author = &a
+		}
+	}
+	if d.Volumes, err = s.volumes.SeriesVolumes(ctx, id); err != nil {
+		return Detail{}, err
+	}
+	return d, nil
+}
+
+func (s *service) UpdateSeries(ctx context.Context, id int, sr Series) (Series, error) {
+	if _, err := s.store.GetSeries(ctx, id); err != nil {
+		return Series{}, err
+	}
+	sr, err := s.validate(ctx, sr)
+	if err != nil {
+		return Series{}, err
+	}
+	return s.store.UpdateSeries(ctx, id, sr)
+}
+
+// DeleteSeries refuses to delete a series that still has books.
+func (s *service) DeleteSeries(ctx context.Context, id int) error {
+	if _, err := s.store.GetSeries(ctx, id); err != nil {
+		return err
+	}
+	volumes, err := s.volumes.SeriesVolumes(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(volumes) > 0 {
+		return fmt.Errorf("%w: series %d has %d book(s)", ErrInUse, id, len(volumes))
+	}
+	return s.store.DeleteSeries(ctx, id)
+}
+
+func (s *service) ListSeries(ctx context.Context) ([]Series, error) {
+	return s.store.ListSeries(ctx)
+}
+
+func (s *service) SeriesRefsByAuthor(ctx context.Context, authorID int) ([]author.SeriesRef, error) {
+	all, err := s.store.ListSeries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var refs []author.SeriesRef
+	for _, sr := range all {
+		if sr.AuthorID == authorID {
+			refs = append(refs, author.SeriesRef{ID: sr.ID, Name: sr.Name})
+		}
+	}
+	return refs, nil
+}
+
+// ReassignSeries credits every series of fromAuthorID to toAuthorID, or
+// to no one if toAuthorID is 0.
+func (s *service) ReassignSeries(ctx context.Context, fromAuthorID, toAuthorID int) error {
+	all, err := s.store.ListSeries(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sr := range all {
+		if sr.AuthorID != fromAuthorID {
+			continue
+		}
+		sr.AuthorID = toAuthorID
+		if _, err := s.store.UpdateSeries(ctx, sr.ID, sr); err != nil {
+			return fmt.Errorf("failed to reassign series %d: %w", sr.ID, err)
+		}
+	}
+	return nil
+}
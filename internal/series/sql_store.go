@@ -0,0 +1,105 @@
+package series
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"um6p.ma/final_project/pkg/sqlutil"
+)
+
+type SQLSeriesStore struct {
+	db *sql.DB
+}
+
+func NewSQLStore(db *sql.DB) *SQLSeriesStore {
+	return &SQLSeriesStore{db: db}
+}
+
+const seriesColumns = `id, name, description, COALESCE(author_id, 0)`
+
+func (store *SQLSeriesStore) CreateSeries(ctx context.Context, s Series) (Series, error) {
+	err := store.db.QueryRowContext(ctx,
+		`INSERT INTO series (name, description, author_id) VALUES ($1, $2, $3)
+		 ON CONFLICT DO NOTHING
+		 RETURNING id`,
+		s.Name, s.Description, sqlutil.NullID(s.AuthorID),
+	).Scan(&s.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Series{}, fmt.Errorf("series %s already exists", s.Name)
+	}
+	if err != nil {
+		return Series{}, fmt.Errorf("failed to create series: %w", err)
+	}
+	return s, nil
+}
+
+func (store *SQLSeriesStore) GetSeries(ctx context.Context, id int) (Series, error) {
+	var s Series
+	err := store.db.QueryRowContext(ctx,
+		`SELECT `+seriesColumns+` FROM series WHERE id = $1`, id,
+	).Scan(&s.ID, &s.Name, &s.Description, &s.AuthorID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Series{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	if err != nil {
+		return Series{}, fmt.Errorf("failed to get series %d: %w", id, err)
+	}
+	return s, nil
+}
+
+func (store *SQLSeriesStore) UpdateSeries(ctx context.Context, id int, s Series) (Series, error) {
+	res, err := store.db.ExecContext(ctx,
+		`UPDATE series SET name = $2, description = $3, author_id = $4 WHERE id = $1`,
+		id, s.Name, s.Description, sqlutil.NullID(s.AuthorID),
+	)
+	if err != nil {
+		return Series{}, fmt.Errorf("failed to update series %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Series{}, fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	s.ID = id
+	return s, nil
+}
+
+func (store *SQLSeriesStore) DeleteSeries(ctx context.Context, id int) error {
+	res, err := store.db.ExecContext(ctx, `DELETE FROM series WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete series %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: ID %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+func (store *SQLSeriesStore) ListSeries(ctx context.Context) ([]Series, error) {
+	rows, err := store.db.QueryContext(ctx, `SELECT `+seriesColumns+` FROM series ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series: %w", err)
+	}
+	defer rows.Close()
+
+	series := make([]Series, 0)
+	for rows.Next() {
+		var s Series
+		if err := rows.Scan(&s.ID, &s.Name, &s.Description, &s.AuthorID); err != nil {
+			return nil, err
+		}
+		series = append(series, s)
+	}
+	return series, rows.Err()
+}
+
+func (store *SQLSeriesStore) RestoreSeries(ctx context.Context, s Series) error {
+	err := sqlutil.RestoreRow(ctx, store.db, "series",
+		`INSERT INTO series (id, name, description, author_id) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, author_id = EXCLUDED.author_id`,
+		s.ID, s.Name, s.Description, sqlutil.NullID(s.AuthorID))
+	if err != nil {
+		return fmt.Errorf("failed to restore series %d: %w", s.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package isbn validates ISBN-10 and ISBN-13 numbers and converts between
+// the two forms.
+package isbn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalid is wrapped by every error Normalize returns.
+var ErrInvalid = errors.New("invalid ISBN")
+
+// Normalize checks s and returns it in compact form: ten characters for an
+// ISBN-10 (the last may be 'X') or thirteen digits for an ISBN-13. Parts may
+// be separated by hyphens or by spaces, but not both; a hyphenated ISBN-10
+// has four parts, an ISBN-13 five, and the check digit is always its own
+// part.
+func Normalize(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("%w: empty", ErrInvalid)
+	}
+
+	sep := ""
+	switch {
+	case strings.Contains(s, "-") && strings.Contains(s, " "):
+		return "", fmt.Errorf("%w %q: mixes hyphens and spaces", ErrInvalid, s)
+	case strings.Contains(s, "-"):
+		sep = "-"
+	case strings.Contains(s, " "):
+		sep = " "
+	}
+
+	compact := strings.ToUpper(s)
+	var parts []string
+	if sep != "" {
+		parts = strings.Split(compact, sep)
+		for _, p := range parts {
+			if p == "" {
+				return "", fmt.Errorf("%w %q: misplaced separator", ErrInvalid, s)
+			}
+		}
+		compact = strings.Join(parts, "")
+	}
+
+	switch len(compact) {
+	case 10:
+		if parts != nil && (len(parts) != 4 || len(parts[3]) != 1) {
+			return "", fmt.Errorf("%w %q: a hyphenated ISBN-10 has four parts ending in the check digit", ErrInvalid, s)
+		}
+		if !Valid10(compact) {
+			return "", fmt.Errorf("%w %q: wrong characters or ISBN-10 check digit", ErrInvalid, s)
+		}
+	case 13:
+		if parts != nil && (len(parts) != 5 || len(parts[0]) != 3 || len(parts[4]) != 1) {
+			return "", fmt.Errorf("%w %q: a hyphenated ISBN-13 has five parts starting with the prefix and ending in the check digit", ErrInvalid, s)
+		}
+		if !Valid13(compact) {
+			return "", fmt.Errorf("%w %q: wrong characters, prefix or ISBN-13 check digit", ErrInvalid, s)
+		}
+	default:
+		return "", fmt.Errorf("%w %q: must have 10 or 13 digits", ErrInvalid, s)
+	}
+	return compact, nil
+}
+
+// Canonical returns s as a compact ISBN-13, converting ISBN-10s.
+func Canonical(s string) (string, error) {
+	n, err := Normalize(s)
+	if err != nil {
+		return "", err
+	}
+	if len(n) == 10 {
+		return To13(n), nil
+	}
+	return n, nil
+}
+
+// Valid10 reports whether s is a compact ISBN-10 with a correct check digit.
+func Valid10(s string) bool {
+	if len(s) != 10 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var d int
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case (c == 'X' || c == 'x') && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += (10 - i) * d
+	}
+	return sum%11 == 0
+}
+
+// Valid13 reports whether s is a compact ISBN-13 with a 978 or 979 prefix
+// and a correct check digit.
+func Valid13(s string) bool {
+	if len(s) != 13 || !allDigits(s) {
+		return false
+	}
+	if !strings.HasPrefix(s, "978") && !strings.HasPrefix(s, "979") {
+		return false
+	}
+	return checkDigit13(s[:12]) == s[12]
+}
+
+// To13 converts a valid compact ISBN-10 to its ISBN-13.
+func To13(isbn10 string) string {
+	body := "978" + isbn10[:9]
+	return body + string(checkDigit13(body))
+}
+
+// To10 converts a valid compact ISBN-13 to its ISBN-10. Only 978-prefixed
+// numbers have one.
+func To10(isbn13 string) (string, bool) {
+	if !strings.HasPrefix(isbn13, "978") || len(isbn13) != 13 {
+		return "", false
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(body[i]-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", true
+	}
+	return body + string(rune('0'+check)), true
+}
+
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(body[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValid10(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"0306406152", true},
+		{"080442957X", true},
+		{"080442957x", true},
+		{"043942089X", true},
+		{"0306406153", false},
+		{"0306406150", false},
+		{"X306406152", false},
+		{"030640615", false},
+		{"03064061522", false},
+		{"03064O6152", false},
+	}
+	for _, tt := range tests {
+		if got := Valid10(tt.in); got != tt.want {
+			t.Errorf("Valid10(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValid13(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"9780306406157", true},
+		{"9780804429573", true},
+		{"9791090636071", true},
+		{"9780306406158", false},
+		{"9770306406157", false},
+		{"978030640615", false},
+		{"978030640615X", false},
+	}
+	for _, tt := range tests {
+		if got := Valid13(tt.in); got != tt.want {
+			t.Errorf("Valid13(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"0306406152", "0306406152", false},
+		{" 0-306-40615-2 ", "0306406152", false},
+		{"0 306 40615 2", "0306406152", false},
+		{"0-8044-2957-x", "080442957X", false},
+		{"978-0-306-40615-7", "9780306406157", false},
+		{"978 0 306 40615 7", "9780306406157", false},
+		{"", "", true},
+		{"0-306 40615-2", "", true},
+		{"0--306-40615-2", "", true},
+		{"030-640615-2", "", true},
+		{"0-306-4061-52", "", true},
+		{"97-80-306-40615-7", "", true},
+		{"978-0-306-4061-57", "", true},
+		{"0-306-40615-3", "", true},
+		{"978-0-306-40615-8", "", true},
+		{"12345", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if tt.wantErr {
+			if err == nil || !errors.Is(err, ErrInvalid) {
+				t.Errorf("Normalize(%q) = %q, %v; want an ErrInvalid error", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		isbn10, isbn13 string
+	}{
+		{"0306406152", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"043942089X", "9780439420891"},
+	}
+	for _, tt := range tests {
+		if got := To13(tt.isbn10); got != tt.isbn13 {
+			t.Errorf("To13(%q) = %q, want %q", tt.isbn10, got, tt.isbn13)
+		}
+		if got, ok := To10(tt.isbn13); !ok || got != tt.isbn10 {
+			t.Errorf("To10(%q) = %q, %v; want %q", tt.isbn13, got, ok, tt.isbn10)
+		}
+		if got, err := Canonical(tt.isbn10); err != nil || got != tt.isbn13 {
+			t.Errorf("Canonical(%q) = %q, %v; want %q", tt.isbn10, got, err, tt.isbn13)
+		}
+	}
+	if got, ok := To10("9791090636071"); ok {
+		t.Errorf("To10 of a 979 ISBN = %q, want none", got)
+	}
+}
@@ -0,0 +1,299 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"um6p.ma/final_project/pkg/persist"
+)
+
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+type SyncPolicy string
+
+const (
+	SyncAlways  SyncPolicy = "always"
+	SyncBatched SyncPolicy = "batched"
+	SyncNever   SyncPolicy = "never"
+)
+
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch p := SyncPolicy(s); p {
+	case SyncAlways, SyncBatched, SyncNever:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown journal sync policy %q (want always, batched or never)", s)
+	}
+}
+
+type Options struct {
+	Sync         SyncPolicy
+	SyncInterval time.Duration
+	CompactEvery int
+}
+
+type Record struct {
+	Op   string          `json:"op"`
+	ID   int             `json:"id"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+const (
+	headerSize    = 8
+	maxRecordSize = 64 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Journal is an append-only log of store mutations. Each record is framed as
+// a 4-byte length, a 4-byte CRC32-C of the payload and the JSON payload, so a
+// torn final write is detected on replay and cut off.
+type Journal struct {
+	mu           sync.Mutex
+	path         string
+	snapshotPath string
+	opts         Options
+	f            *os.File
+	w            *bufio.Writer
+	appended     int
+	dirty        bool
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func Open(dir, name string, opts Options) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory %s: %w", dir, err)
+	}
+	if opts.Sync == "" {
+		opts.Sync = SyncBatched
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = time.Second
+	}
+
+	path := filepath.Join(dir, name+".journal")
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+
+	return &Journal{
+		path:         path,
+		snapshotPath: filepath.Join(dir, name+".snapshot.json"),
+		opts:         opts,
+		f:            f,
+		w:            bufio.NewWriter(f),
+		stopCh:       make(chan struct{}),
+	}, nil
+}
+
+// Load reads the latest snapshot into snapshot (left untouched if there is
+// none yet) and returns the journal records written after it. A corrupt or
+// incomplete tail is truncated so later appends start on a clean boundary.
+// Batched syncing starts once Load has run.
+func (j *Journal) Load(snapshot any) ([]Record, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := persist.ReadJSON(j.snapshotPath, snapshot); err != nil {
+		return nil, err
+	}
+
+	if _, err := j.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(j.f)
+	var records []Record
+	var offset int64
+	for {
+		rec, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("journal %s: discarding tail at offset %d: %v", j.path, offset, err)
+			if err := j.f.Truncate(offset); err != nil {
+				return nil, fmt.Errorf("failed to truncate journal %s: %w", j.path, err)
+			}
+			break
+		}
+		records = append(records, rec)
+		offset += n
+	}
+
+	if _, err := j.f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	j.w.Reset(j.f)
+	j.appended = len(records)
+
+	if j.opts.Sync == SyncBatched {
+		j.wg.Add(1)
+		go j.syncLoop()
+	}
+	return records, nil
+}
+
+func readRecord(r *bufio.Reader) (Record, int64, error) {
+	var header [headerSize]byte
+	n, err := io.ReadFull(r, header[:])
+	if err == io.EOF {
+		return Record{}, 0, io.EOF
+	}
+	if err != nil {
+		return Record{}, 0, fmt.Errorf("short header (%d bytes)", n)
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if size > maxRecordSize {
+		return Record{}, 0, fmt.Errorf("record size %d exceeds limit", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return Record{}, 0, fmt.Errorf("short record payload")
+	}
+	if crc32.Checksum(payload, crcTable) != sum {
+		return Record{}, 0, fmt.Errorf("checksum mismatch")
+	}
+
+	var rec Record
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return Record{}, 0, fmt.Errorf("invalid record: %w", err)
+	}
+	return rec, int64(headerSize + len(payload)), nil
+}
+
+func (j *Journal) Append(op string, id int, data any) error {
+	rec := Record{Op: op, ID: id}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to encode journal record: %w", err)
+		}
+		rec.Data = raw
+	}
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal record: %w", err)
+	}
+
+	var header [headerSize]byte
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(payload, crcTable))
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.w.Write(header[:]); err != nil {
+		return fmt.Errorf("failed to append to journal %s: %w", j.path, err)
+	}
+	if _, err := j.w.Write(payload); err != nil {
+		return fmt.Errorf("failed to append to journal %s: %w", j.path, err)
+	}
+	if err := j.w.Flush(); err != nil {
+		return fmt.Errorf("failed to append to journal %s: %w", j.path, err)
+	}
+	j.appended++
+	j.dirty = true
+
+	if j.opts.Sync == SyncAlways {
+		return j.syncLocked()
+	}
+	return nil
+}
+
+func (j *Journal) ShouldCompact() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.opts.CompactEvery > 0 && j.appended >= j.opts.CompactEvery
+}
+
+// Compact writes snapshot as the new base state and empties the journal.
+// Callers must ensure no Append runs concurrently so that snapshot covers
+// every record written so far.
+func (j *Journal) Compact(snapshot any) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := persist.WriteJSONAtomic(j.snapshotPath, snapshot); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := j.f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate journal %s: %w", j.path, err)
+	}
+	if _, err := j.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	j.w.Reset(j.f)
+	j.appended = 0
+	j.dirty = true
+	return j.syncLocked()
+}
+
+func (j *Journal) Sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.syncLocked()
+}
+
+func (j *Journal) syncLocked() error {
+	if !j.dirty {
+		return nil
+	}
+	if err := j.f.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal %s: %w", j.path, err)
+	}
+	j.dirty = false
+	return nil
+}
+
+func (j *Journal) syncLoop() {
+	defer j.wg.Done()
+	ticker := time.NewTicker(j.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.Sync(); err != nil {
+				log.Printf("journal %s: %v", j.path, err)
+			}
+		case <-j.stopCh:
+			return
+		}
+	}
+}
+
+func (j *Journal) Close() error {
+	select {
+	case <-j.stopCh:
+		return nil
+	default:
+		close(j.stopCh)
+	}
+	j.wg.Wait()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var err error
+	if j.opts.Sync != SyncNever {
+		err = j.syncLocked()
+	}
+	return errors.Join(err, j.f.Close())
+}
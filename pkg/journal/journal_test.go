@@ -0,0 +1,228 @@
+package journal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+type item struct {
+	Name string `json:"name"`
+}
+
+// writeJournal appends n create records to a new journal in dir and returns
+// the size of the journal file after each record.
+func writeJournal(t *testing.T, dir string, n int) []int64 {
+	t.Helper()
+	j, err := Open(dir, "items", Options{Sync: SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.Load(&[]item{}); err != nil {
+		t.Fatal(err)
+	}
+	var sizes []int64
+	for i := 1; i <= n; i++ {
+		if err := j.Append(OpCreate, i, item{Name: "item"}); err != nil {
+			t.Fatal(err)
+		}
+		fi, err := os.Stat(j.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, fi.Size())
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return sizes
+}
+
+// frame encodes payload the way Append does, with the given checksum.
+func frame(payload []byte, sum uint32) []byte {
+	out := make([]byte, headerSize, headerSize+len(payload))
+	binary.BigEndian.PutUint32(out[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(out[4:8], sum)
+	return append(out, payload...)
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SyncPolicy
+		wantErr bool
+	}{
+		{"always", SyncAlways, false},
+		{"batched", SyncBatched, false},
+		{"never", SyncNever, false},
+		{"", "", true},
+		{"sometimes", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSyncPolicy(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseSyncPolicy(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFraming(t *testing.T) {
+	dir := t.TempDir()
+	writeJournal(t, dir, 1)
+
+	raw, err := os.ReadFile(filepath.Join(dir, "items.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := binary.BigEndian.Uint32(raw[0:4])
+	sum := binary.BigEndian.Uint32(raw[4:8])
+	payload := raw[headerSize:]
+	if int(size) != len(payload) {
+		t.Fatalf("length header = %d, payload is %d bytes", size, len(payload))
+	}
+	if want := crc32.Checksum(payload, crc32.MakeTable(crc32.Castagnoli)); sum != want {
+		t.Errorf("checksum header = %#x, want CRC32-C %#x", sum, want)
+	}
+	var rec Record
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Op != OpCreate || rec.ID != 1 || string(rec.Data) != `{"name":"item"}` {
+		t.Errorf("record = %+v", rec)
+	}
+}
+
+func TestLoadDiscardsCorruptTail(t *testing.T) {
+	validJSON := []byte(`{"op":"create","id":9}`)
+	tests := []struct {
+		name string
+		// tail is appended after two valid records.
+		tail func(last []byte) []byte
+	}{
+		{"short header", func([]byte) []byte { return []byte{0, 0, 0} }},
+		{"short payload", func(last []byte) []byte { return last[:len(last)-2] }},
+		{"checksum mismatch", func([]byte) []byte {
+			return frame(validJSON, crc32.Checksum(validJSON, crcTable)+1)
+		}},
+		{"oversized record", func([]byte) []byte {
+			out := make([]byte, headerSize)
+			binary.BigEndian.PutUint32(out[0:4], maxRecordSize+1)
+			return out
+		}},
+		{"invalid payload", func([]byte) []byte {
+			bad := []byte("not json")
+			return frame(bad, crc32.Checksum(bad, crcTable))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sizes := writeJournal(t, dir, 3)
+			path := filepath.Join(dir, "items.journal")
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			good := slices.Clone(raw[:sizes[1]])
+			last := raw[sizes[1]:]
+			if err := os.WriteFile(path, append(good, tt.tail(last)...), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			j, err := Open(dir, "items", Options{Sync: SyncNever})
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, err := j.Load(&[]item{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 2 || records[0].ID != 1 || records[1].ID != 2 {
+				t.Fatalf("Load returned %+v, want records 1 and 2", records)
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fi.Size() != sizes[1] {
+				t.Errorf("journal is %d bytes after Load, want it truncated to %d", fi.Size(), sizes[1])
+			}
+
+			// Appends continue on a clean boundary.
+			if err := j.Append(OpDelete, 1, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := j.Close(); err != nil {
+				t.Fatal(err)
+			}
+			j, err = Open(dir, "items", Options{Sync: SyncNever})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.Close()
+			records, err = j.Load(&[]item{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 3 || records[2].Op != OpDelete {
+				t.Errorf("after append, Load returned %+v", records)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, "items", Options{Sync: SyncAlways, CompactEvery: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state []item
+	if _, err := j.Load(&state); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 2; i++ {
+		if j.ShouldCompact() {
+			t.Fatalf("ShouldCompact before %d appends", i)
+		}
+		if err := j.Append(OpCreate, i, item{Name: "item"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !j.ShouldCompact() {
+		t.Fatal("ShouldCompact = false after CompactEvery appends")
+	}
+	if err := j.Compact([]item{{Name: "a"}, {Name: "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if j.ShouldCompact() {
+		t.Error("ShouldCompact = true right after Compact")
+	}
+	if err := j.Append(OpUpdate, 2, item{Name: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err = Open(dir, "items", Options{Sync: SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	state = nil
+	records, err := j.Load(&state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state) != 2 || state[0].Name != "a" || state[1].Name != "b" {
+		t.Errorf("snapshot = %+v, want the compacted state", state)
+	}
+	if len(records) != 1 || records[0].Op != OpUpdate || records[0].ID != 2 {
+		t.Errorf("records after snapshot = %+v, want the one update", records)
+	}
+}
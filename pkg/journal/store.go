@@ -0,0 +1,59 @@
+package journal
+
+import "sync"
+
+// Store journals the changes to an in-memory store whose state is S. Writers
+// hold its lock from changing the store until the change is recorded, so a
+// compaction's snapshot covers every record appended before it.
+type Store[S any] struct {
+	sync.Mutex
+	journal  *Journal
+	snapshot func() S
+}
+
+// OpenStore opens journal name in dir, passes its snapshot to restore and
+// every record written since to apply.
+func OpenStore[S any](dir, name string, opts Options, snapshot func() S, restore func(S), apply func(Record) error) (*Store[S], error) {
+	j, err := Open(dir, name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var snap S
+	records, err := j.Load(&snap)
+	if err != nil {
+		j.Close()
+		return nil, err
+	}
+	restore(snap)
+	for _, rec := range records {
+		if err := apply(rec); err != nil {
+			j.Close()
+			return nil, err
+		}
+	}
+	return &Store[S]{journal: j, snapshot: snapshot}, nil
+}
+
+// Record appends a change and compacts the journal once enough have built
+// up. Callers hold the lock.
+func (s *Store[S]) Record(op string, id int, data any) error {
+	if err := s.journal.Append(op, id, data); err != nil {
+		return err
+	}
+	if s.journal.ShouldCompact() {
+		return s.journal.Compact(s.snapshot())
+	}
+	return nil
+}
+
+// Flush compacts the journal into a snapshot of the current state.
+func (s *Store[S]) Flush() error {
+	s.Lock()
+	defer s.Unlock()
+	return s.journal.Compact(s.snapshot())
+}
+
+func (s *Store[S]) Close() error {
+	return s.journal.Close()
+}
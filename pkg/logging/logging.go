@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Printf logs through the default slog logger so the line carries the
+// request ID found in ctx, if any.
+func Printf(ctx context.Context, format string, args ...any) {
+	slog.Default().Log(ctx, slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+// NewHandler wraps h so that every record logged with a request-scoped
+// context gets a request_id attribute.
+func NewHandler(h slog.Handler) slog.Handler {
+	return contextHandler{Handler: h}
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,40 @@
+package persist
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SnapshotFile keeps a store's whole state in one JSON file, rewritten
+// atomically on every Save.
+type SnapshotFile[S any] struct {
+	path     string
+	name     string
+	snapshot func() S
+	mu       sync.Mutex
+}
+
+// OpenSnapshotFile passes the state saved at path, if there is one, to
+// restore and returns the file later states from snapshot are saved to.
+// name says what the file holds in errors.
+func OpenSnapshotFile[S any](path, name string, snapshot func() S, restore func(S)) (*SnapshotFile[S], error) {
+	var snap S
+	found, err := ReadJSON(path, &snap)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		restore(snap)
+	}
+	return &SnapshotFile[S]{path: path, name: name, snapshot: snapshot}, nil
+}
+
+func (f *SnapshotFile[S]) Save() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := WriteJSONAtomic(f.path, f.snapshot()); err != nil {
+		return fmt.Errorf("failed to persist %s: %w", f.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package sqlutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// InTx runs fn inside a transaction, committing if it returns nil and
+// rolling back otherwise.
+func InTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// NullID maps the zero ID used for "no reference" to SQL NULL.
+func NullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+// NullString maps the empty string used for "not set" to SQL NULL, so
+// unique indexes ignore it.
+func NullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// RowQueryer is satisfied by both *sql.DB and *sql.Tx.
+type RowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ResetSequence moves the SERIAL sequence of table past its highest ID, which
+// is needed after rows were inserted with explicit IDs.
+func ResetSequence(ctx context.Context, db Execer, table string) error {
+	_, err := db.ExecContext(ctx,
+		`SELECT setval(pg_get_serial_sequence('`+table+`', 'id'), COALESCE((SELECT MAX(id) FROM `+table+`), 0) + 1, false)`)
+	if err != nil {
+		return fmt.Errorf("failed to reset %s id sequence: %w", table, err)
+	}
+	return nil
+}
+
+// RestoreRow runs query, which stores a row of table under an ID it was
+// given, and moves the table's ID sequence past it in the same transaction.
+func RestoreRow(ctx context.Context, db *sql.DB, table, query string, args ...any) error {
+	return InTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+		return ResetSequence(ctx, tx, table)
+	})
+}
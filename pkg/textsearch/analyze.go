@@ -0,0 +1,143 @@
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Language int
+
+const (
+	English Language = iota
+	French
+)
+
+// token is a normalized word and the byte range it came from in the input.
+type token struct {
+	text       string
+	start, end int
+}
+
+var folds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'œ': "oe",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'ÿ': "y", 'ß': "ss",
+}
+
+// fold lowercases r and strips Latin diacritics.
+func fold(r rune) string {
+	r = unicode.ToLower(r)
+	if f, ok := folds[r]; ok {
+		return f
+	}
+	return string(r)
+}
+
+// tokenize splits text on anything that is not a letter or digit, so
+// "l'été" yields "l" and "ete". Tokens are lowercased and accent-folded.
+func tokenize(text string) []token {
+	var tokens []token
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, token{text: b.String(), start: start, end: end})
+			b.Reset()
+			start = -1
+		}
+	}
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			b.WriteString(fold(r))
+			continue
+		}
+		flush(i)
+	}
+	flush(len(text))
+	return tokens
+}
+
+var stopwords = map[Language]map[string]bool{
+	English: setOf("a an and are as at be but by for from has have he her his i in is it its of on or she that the their they this to was were will with you"),
+	French:  setOf("au aux avec ce ces dans de des du elle en et est il ils je la le les leur lui ma mais me mes mon ne nous on ou par pas pour qu que qui sa se ses son sur ta te tes ton tu un une vous"),
+}
+
+func setOf(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+func isStopword(w string) bool {
+	return stopwords[English][w] || stopwords[French][w]
+}
+
+// DetectLanguage guesses whether text is French or English by counting
+// stopwords; ties and text without stopwords count as English.
+func DetectLanguage(text string) Language {
+	var en, fr int
+	for _, t := range tokenize(text) {
+		if stopwords[English][t.text] {
+			en++
+		}
+		if stopwords[French][t.text] {
+			fr++
+		}
+	}
+	if fr > en {
+		return French
+	}
+	return English
+}
+
+func stem(word string, lang Language) string {
+	if lang == French {
+		return stemFrench(word)
+	}
+	return stemEnglish(word)
+}
+
+// indexable reports whether a token is worth indexing: stopwords and single
+// letters are dropped, single digits are kept.
+func indexable(t string) bool {
+	if isStopword(t) {
+		return false
+	}
+	return len(t) > 1 || unicode.IsDigit(rune(t[0]))
+}
+
+// analyze turns text into index terms using the stemmer for lang.
+func analyze(text string, lang Language) []string {
+	var terms []string
+	for _, t := range tokenize(text) {
+		if indexable(t.text) {
+			terms = append(terms, stem(t.text, lang))
+		}
+	}
+	return terms
+}
+
+// queryTerms analyzes a query without knowing its language: each word
+// becomes the set of its English and French stems.
+func queryTerms(q string) [][]string {
+	var out [][]string
+	for _, t := range tokenize(q) {
+		if !indexable(t.text) {
+			continue
+		}
+		en, fr := stemEnglish(t.text), stemFrench(t.text)
+		if en == fr {
+			out = append(out, []string{en})
+		} else {
+			out = append(out, []string{en, fr})
+		}
+	}
+	return out
+}
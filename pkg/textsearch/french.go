@@ -0,0 +1,40 @@
+package textsearch
+
+import "strings"
+
+// frenchSuffixes are tried longest first, after plurals have been removed;
+// the remaining stem must keep at least three letters.
+var frenchSuffixes = []string{
+	"issement", "issant", "atrice",
+	"ation", "ateur", "ement",
+	"euse", "ment", "ique", "isme", "iste", "able", "ible",
+	"ite", "ive",
+	"eu", "ee", "er", "ez", "if",
+	"e",
+}
+
+// stemFrench is a light French stemmer in the spirit of Savoy's: it folds
+// plurals, strips one common derivational or inflectional suffix and
+// collapses a trailing double consonant. Input is expected to be lowercased
+// and accent-folded already.
+func stemFrench(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "aux") && len(word) > 5:
+		word = strings.TrimSuffix(word, "aux") + "al"
+	case strings.HasSuffix(word, "s") || strings.HasSuffix(word, "x"):
+		word = word[:len(word)-1]
+	}
+	for _, suffix := range frenchSuffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			word = strings.TrimSuffix(word, suffix)
+			break
+		}
+	}
+	if n := len(word); n > 3 && word[n-1] == word[n-2] && !strings.ContainsRune("aeiouy", rune(word[n-1])) {
+		word = word[:n-1]
+	}
+	return word
+}
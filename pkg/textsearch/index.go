@@ -0,0 +1,278 @@
+// Package textsearch is a small in-memory inverted index with English and
+// French stemming, BM25 ranking and highlighted snippets.
+package textsearch
+
+import (
+	"html"
+	"maps"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// snippetWords is how many words of context a snippet keeps on each
+	// side of the first match in a long field.
+	snippetWords  = 8
+	maxFieldWords = 2*snippetWords + 1
+
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// Hit is one ranked search result. Snippets holds, for each field that
+// matched, the field text (shortened if long) with matches wrapped in
+// HighlightStart/HighlightEnd and the rest HTML-escaped.
+type Hit struct {
+	ID       int               `json:"id"`
+	Score    float64           `json:"score"`
+	Snippets map[string]string `json:"snippets,omitempty"`
+}
+
+type document struct {
+	fields  map[string]string
+	lang    Language
+	lengths map[string]int
+	terms   map[string]map[string]int // term -> field -> frequency
+}
+
+// Index maps terms to the documents and fields containing them. Fields are
+// weighted by the boosts given to New; fields without a boost weigh 1.
+type Index struct {
+	mu       sync.RWMutex
+	boosts   map[string]float64
+	docs     map[int]*document
+	postings map[string]map[int]bool
+	totalLen map[string]int
+}
+
+func New(boosts map[string]float64) *Index {
+	return &Index{
+		boosts:   maps.Clone(boosts),
+		docs:     make(map[int]*document),
+		postings: make(map[string]map[int]bool),
+		totalLen: make(map[string]int),
+	}
+}
+
+// Add indexes fields under id, replacing any previous document with that
+// ID. The stemming language is detected from the combined field text.
+func (ix *Index) Add(id int, fields map[string]string) {
+	var all strings.Builder
+	for _, text := range fields {
+		all.WriteString(text)
+		all.WriteByte(' ')
+	}
+	doc := &document{
+		fields:  maps.Clone(fields),
+		lang:    DetectLanguage(all.String()),
+		lengths: make(map[string]int),
+		terms:   make(map[string]map[string]int),
+	}
+	for field, text := range fields {
+		terms := analyze(text, doc.lang)
+		doc.lengths[field] = len(terms)
+		for _, t := range terms {
+			if doc.terms[t] == nil {
+				doc.terms[t] = make(map[string]int)
+			}
+			doc.terms[t][field]++
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+	ix.docs[id] = doc
+	for field, n := range doc.lengths {
+		ix.totalLen[field] += n
+	}
+	for t := range doc.terms {
+		if ix.postings[t] == nil {
+			ix.postings[t] = make(map[int]bool)
+		}
+		ix.postings[t][id] = true
+	}
+}
+
+// Fields returns the text currently indexed under id.
+func (ix *Index) Fields(id int) (map[string]string, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	doc, ok := ix.docs[id]
+	if !ok {
+		return nil, false
+	}
+	return maps.Clone(doc.fields), true
+}
+
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id int) {
+	doc, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for field, n := range doc.lengths {
+		ix.totalLen[field] -= n
+	}
+	for t := range doc.terms {
+		delete(ix.postings[t], id)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+		}
+	}
+	delete(ix.docs, id)
+}
+
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+func (ix *Index) boost(field string) float64 {
+	if b, ok := ix.boosts[field]; ok {
+		return b
+	}
+	return 1
+}
+
+// Search returns every document matching at least one query word, best
+// first. Each query word is scored by whichever of its English or French
+// stems scores higher, so queries work without knowing their language.
+func (ix *Index) Search(query string) []Hit {
+	words := queryTerms(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	n := float64(len(ix.docs))
+	scores := make(map[int]float64)
+	for _, variants := range words {
+		best := make(map[int]float64)
+		for _, term := range variants {
+			docs := ix.postings[term]
+			if len(docs) == 0 {
+				continue
+			}
+			df := float64(len(docs))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			for id := range docs {
+				if s := idf * ix.termWeight(ix.docs[id], term); s > best[id] {
+					best[id] = s
+				}
+			}
+		}
+		for id, s := range best {
+			scores[id] += s
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score, Snippets: ix.snippets(ix.docs[id], words)})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// termWeight is the boosted sum over fields of the BM25 term-frequency
+// component for term in doc.
+func (ix *Index) termWeight(doc *document, term string) float64 {
+	var w float64
+	for field, tf := range doc.terms[term] {
+		avg := float64(ix.totalLen[field]) / float64(len(ix.docs))
+		if avg == 0 {
+			avg = 1
+		}
+		norm := 1 - bm25B + bm25B*float64(doc.lengths[field])/avg
+		f := float64(tf)
+		w += ix.boost(field) * f * (bm25K1 + 1) / (f + bm25K1*norm)
+	}
+	return w
+}
+
+func (ix *Index) snippets(doc *document, words [][]string) map[string]string {
+	want := make(map[string]bool)
+	for _, variants := range words {
+		for _, t := range variants {
+			want[t] = true
+		}
+	}
+	out := make(map[string]string)
+	for field, text := range doc.fields {
+		if s, ok := highlight(text, doc.lang, want); ok {
+			out[field] = s
+		}
+	}
+	return out
+}
+
+// highlight marks the words of text whose stem is in want. Long texts are
+// cut down to a window around the first match. The text between the markers
+// is HTML-escaped, so the snippet is safe to render as HTML.
+func highlight(text string, lang Language, want map[string]bool) (string, bool) {
+	tokens := tokenize(text)
+	first := -1
+	var matched []bool
+	for i, t := range tokens {
+		hit := indexable(t.text) && want[stem(t.text, lang)]
+		matched = append(matched, hit)
+		if hit && first < 0 {
+			first = i
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from, to := 0, len(tokens)
+	if len(tokens) > maxFieldWords {
+		from = max(first-snippetWords, 0)
+		to = min(from+maxFieldWords, len(tokens))
+	}
+
+	var b strings.Builder
+	start := 0
+	if from > 0 {
+		b.WriteString("…")
+		start = tokens[from].start
+	}
+	end := len(text)
+	if to < len(tokens) {
+		end = tokens[to-1].end
+	}
+	pos := start
+	for i := from; i < to; i++ {
+		if !matched[i] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:tokens[i].start]))
+		b.WriteString(HighlightStart)
+		b.WriteString(html.EscapeString(text[tokens[i].start:tokens[i].end]))
+		b.WriteString(HighlightEnd)
+		pos = tokens[i].end
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
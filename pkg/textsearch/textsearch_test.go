@@ -0,0 +1,275 @@
+package textsearch
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestStemEnglish(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "ti"},
+		{"cats", "cat"},
+		{"feed", "feed"},
+		{"agreed", "agre"},
+		{"plastered", "plaster"},
+		{"motoring", "motor"},
+		{"sing", "sing"},
+		{"conflated", "conflat"},
+		{"troubled", "troubl"},
+		{"sized", "size"},
+		{"hopping", "hop"},
+		{"falling", "fall"},
+		{"hissing", "hiss"},
+		{"filing", "file"},
+		{"happy", "happi"},
+		{"relational", "relat"},
+		{"conditional", "condit"},
+		{"generalization", "gener"},
+		{"electrical", "electr"},
+		{"adjustable", "adjust"},
+		{"hopefulness", "hope"},
+	}
+	for _, tt := range tests {
+		if got := stemEnglish(tt.word); got != tt.want {
+			t.Errorf("stemEnglish(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestStemFrench(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"ete", "ete"},
+		{"chevaux", "cheval"},
+		{"maisons", "maison"},
+		{"nationale", "national"},
+		{"nationales", "national"},
+		{"continuellement", "continuel"},
+		{"rapidement", "rapid"},
+		{"generation", "gener"},
+		{"generations", "gener"},
+		{"voyageurs", "voyageur"},
+		{"aimer", "aim"},
+		{"livres", "livr"},
+	}
+	for _, tt := range tests {
+		if got := stemFrench(tt.word); got != tt.want {
+			t.Errorf("stemFrench(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"Hello, World!", []string{"hello", "world"}},
+		{"l'été", []string{"l", "ete"}},
+		{"Œuvre Straße", []string{"oeuvre", "strasse"}},
+		{"Catch-22", []string{"catch", "22"}},
+	}
+	for _, tt := range tests {
+		tokens := tokenize(tt.text)
+		var got []string
+		for _, tok := range tokens {
+			got = append(got, tok.text)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want Language
+	}{
+		{"The history of the world", English},
+		{"Le rouge et le noir", French},
+		{"Dune", English},
+		{"", English},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSearchBM25Score(t *testing.T) {
+	ix := New(nil)
+	ix.Add(1, map[string]string{"title": "apple banana"})
+	ix.Add(2, map[string]string{"title": "cherry"})
+
+	hits := ix.Search("apple")
+	if len(hits) != 1 || hits[0].ID != 1 {
+		t.Fatalf("Search(apple) = %+v, want only document 1", hits)
+	}
+	// One of two documents matches, and document 1 is longer than the
+	// average title of 1.5 terms.
+	idf := math.Log(1 + (2-1+0.5)/(1+0.5))
+	norm := 1 - bm25B + bm25B*2/1.5
+	want := idf * (bm25K1 + 1) / (1 + bm25K1*norm)
+	if math.Abs(hits[0].Score-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", hits[0].Score, want)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name   string
+		boosts map[string]float64
+		docs   map[int]map[string]string
+		query  string
+		want   []int
+	}{
+		{
+			name: "term frequency",
+			docs: map[int]map[string]string{
+				1: {"body": "dragon castle knight"},
+				2: {"body": "dragon dragon dragon"},
+			},
+			query: "dragon",
+			want:  []int{2, 1},
+		},
+		{
+			name: "shorter field",
+			docs: map[int]map[string]string{
+				1: {"body": "dragon castle knight tower moat"},
+				2: {"body": "dragon castle"},
+			},
+			query: "dragon",
+			want:  []int{2, 1},
+		},
+		{
+			name: "rare term",
+			docs: map[int]map[string]string{
+				1: {"body": "castle moat"},
+				2: {"body": "castle dragon"},
+				3: {"body": "castle knight"},
+			},
+			query: "castle dragon",
+			want:  []int{2, 1, 3},
+		},
+		{
+			name:   "boosted field",
+			boosts: map[string]float64{"title": 3},
+			docs: map[int]map[string]string{
+				1: {"title": "castle", "body": "dragon"},
+				2: {"title": "dragon", "body": "castle"},
+			},
+			query: "dragon",
+			want:  []int{2, 1},
+		},
+		{
+			name: "stemmed query",
+			docs: map[int]map[string]string{
+				1: {"body": "running knights"},
+				2: {"body": "a quiet castle"},
+			},
+			query: "knight runs",
+			want:  []int{1},
+		},
+		{
+			name: "French stems",
+			docs: map[int]map[string]string{
+				1: {"body": "les chevaux de la nuit"},
+				2: {"body": "the night horses"},
+			},
+			query: "cheval",
+			want:  []int{1},
+		},
+		{
+			name: "stopwords only",
+			docs: map[int]map[string]string{
+				1: {"body": "the castle"},
+			},
+			query: "the of and",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix := New(tt.boosts)
+			for id, fields := range tt.docs {
+				ix.Add(id, fields)
+			}
+			var got []int
+			for _, h := range ix.Search(tt.query) {
+				got = append(got, h.ID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddReplacesAndRemove(t *testing.T) {
+	ix := New(nil)
+	ix.Add(1, map[string]string{"title": "dragon"})
+	ix.Add(1, map[string]string{"title": "castle"})
+	if hits := ix.Search("dragon"); len(hits) != 0 {
+		t.Errorf("Search(dragon) after replace = %+v, want none", hits)
+	}
+	if hits := ix.Search("castle"); len(hits) != 1 {
+		t.Errorf("Search(castle) = %+v, want document 1", hits)
+	}
+	ix.Remove(1)
+	if ix.Len() != 0 || len(ix.Search("castle")) != 0 {
+		t.Errorf("index not empty after Remove")
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	long := "one two three four five six seven eight nine ten dragon eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty"
+	tests := []struct {
+		name, text, query, want string
+	}{
+		{
+			name:  "marks matches",
+			text:  "The Dragon Reborn",
+			query: "dragon",
+			want:  "The <mark>Dragon</mark> Reborn",
+		},
+		{
+			name:  "escapes HTML",
+			text:  `<script>alert("x")</script> & dragons`,
+			query: "dragon",
+			want:  `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &amp; <mark>dragons</mark>`,
+		},
+		{
+			name:  "shortens long fields",
+			text:  long,
+			query: "dragon",
+			want:  "…three four five six seven eight nine ten <mark>dragon</mark> eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen…",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix := New(nil)
+			ix.Add(1, map[string]string{"title": tt.text})
+			hits := ix.Search(tt.query)
+			if len(hits) != 1 {
+				t.Fatalf("Search(%q) = %+v, want one hit", tt.query, hits)
+			}
+			if got := hits[0].Snippets["title"]; got != tt.want {
+				t.Errorf("snippet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
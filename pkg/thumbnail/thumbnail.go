@@ -0,0 +1,53 @@
+// Package thumbnail scales images down using only the standard library.
+package thumbnail
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Fit scales img down to fit within a size×size box, keeping its aspect ratio.
+// Each output pixel averages the block of source pixels it covers. Images
+// that already fit are returned unchanged.
+func Fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw <= size && sh <= size || sw == 0 || sh == 0 {
+		return img
+	}
+	dw, dh := size, size
+	if sw > sh {
+		dh = max((sh*size+sw/2)/sw, 1)
+	} else {
+		dw = max((sw*size+sh/2)/sh, 1)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, sw, sh))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*sh/dh, (y+1)*sh/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*sw/dw, (x+1)*sw/dw
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					bl += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
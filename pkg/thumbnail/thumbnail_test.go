@@ -0,0 +1,108 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func filled(r image.Rectangle, c color.Color) *image.RGBA {
+	img := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		w, h, size    int
+		wantW, wantH  int
+		wantUnchanged bool
+	}{
+		{"already fits", 50, 40, 100, 50, 40, true},
+		{"exact fit", 100, 100, 100, 100, 100, true},
+		{"empty", 0, 0, 10, 0, 0, true},
+		{"square", 400, 400, 100, 100, 100, false},
+		{"landscape", 400, 200, 100, 100, 50, false},
+		{"portrait", 300, 600, 100, 50, 100, false},
+		{"rounds", 300, 200, 100, 100, 67, false},
+		{"thin", 1000, 2, 100, 100, 1, false},
+		{"narrow", 3, 900, 100, 1, 100, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, tt.w, tt.h))
+			got := Fit(img, tt.size)
+			if b := got.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+				t.Errorf("Fit(%dx%d, %d) is %dx%d, want %dx%d", tt.w, tt.h, tt.size, b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+			if unchanged := got == image.Image(img); unchanged != tt.wantUnchanged {
+				t.Errorf("Fit returned the input image: %v, want %v", unchanged, tt.wantUnchanged)
+			}
+		})
+	}
+}
+
+func TestFitAveragesBlocks(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	blue := color.RGBA{0, 0, 255, 255}
+
+	// Left half red, right half blue, drawn off the origin.
+	src := filled(image.Rect(10, 10, 18, 14), red)
+	for y := 10; y < 14; y++ {
+		for x := 14; x < 18; x++ {
+			src.Set(x, y, blue)
+		}
+	}
+	// A black and white checkerboard.
+	checker := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if (x+y)%2 == 0 {
+				checker.Set(x, y, color.White)
+			} else {
+				checker.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		size int
+		want map[image.Point]color.RGBA
+	}{
+		{
+			name: "solid blocks",
+			img:  src,
+			size: 2,
+			want: map[image.Point]color.RGBA{{0, 0}: red, {1, 0}: blue},
+		},
+		{
+			name: "mixed block",
+			img:  checker,
+			size: 1,
+			want: map[image.Point]color.RGBA{{0, 0}: {127, 127, 127, 255}},
+		},
+		{
+			name: "transparency",
+			img:  filled(image.Rect(0, 0, 4, 4), color.RGBA{0, 0, 0, 0}),
+			size: 2,
+			want: map[image.Point]color.RGBA{{0, 0}: {0, 0, 0, 0}, {1, 1}: {0, 0, 0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fit(tt.img, tt.size)
+			for p, want := range tt.want {
+				if c := color.RGBAModel.Convert(got.At(p.X, p.Y)).(color.RGBA); c != want {
+					t.Errorf("pixel %v = %v, want %v", p, c, want)
+				}
+			}
+		})
+	}
+}